@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"errors"
+	"net/url"
 	"os/signal"
 	"syscall"
 
@@ -14,10 +15,14 @@ import (
 
 func init() {
 	modules.Register("open", modules.ModuleFunc(openFile))
-	js.Loader().SetFileLoader(fileLoader)
 
 	// alias module from cdn
-	source("node_modules/echarts", `export { default } from "https://unpkg.com/echarts@5/dist/echarts.js";`)
+	im, err := modules.ParseImportMap([]byte(`{"imports":{"echarts":"https://unpkg.com/echarts@5/dist/echarts.js"}}`),
+		&url.URL{Scheme: "file", Path: "/"})
+	if err != nil {
+		panic(err)
+	}
+	js.SetLoader(modules.NewLoader(modules.WithFileLoader(fileLoader), modules.WithImportMap(im)))
 
 	source("index.html", `
 <html>
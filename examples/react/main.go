@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"errors"
+	"net/url"
 	"os/signal"
 	"syscall"
 
@@ -13,15 +14,23 @@ import (
 	_ "github.com/shiroyk/ski/modules/http"
 )
 
+// importMap alias module from cdn, shared by the server and the browser
+const importMap = `{"imports":{
+  "react":"https://esm.sh/react@18",
+  "react-dom":"https://esm.sh/react-dom@18",
+  "react-dom/":"https://esm.sh/react-dom@18/",
+  "canvas-confetti":"https://esm.sh/canvas-confetti@1.6.0"
+}}`
+
 func init() {
 	modules.Register("open", modules.ModuleFunc(openFile))
 	modules.Register("now", modules.ModuleFunc(now))
-	js.Loader().SetFileLoader(fileLoader)
 
-	// alias module from cdn
-	source("node_modules/react", `export { default } from "https://esm.sh/react@18";`)
-	source("node_modules/react-dom/server", `export * from "https://esm.sh/react-dom@18/server";`)
-	source("node_modules/canvas-confetti", `export { default } from "https://esm.sh/canvas-confetti@1.6.0";`)
+	im, err := modules.ParseImportMap([]byte(importMap), &url.URL{Scheme: "file", Path: "/"})
+	if err != nil {
+		panic(err)
+	}
+	js.SetLoader(modules.NewLoader(modules.WithFileLoader(fileLoader), modules.WithImportMap(im)))
 
 	source("index.html", `
 <html>
@@ -35,13 +44,7 @@ func init() {
             display: flex; align-items: center; justify-content: center; height: 100%; text-align: center;
         }
     </style>
-    <script type="importmap">
-        {"imports":{
-          "react":"https://esm.sh/react@18",
-          "react-dom":"https://esm.sh/react-dom@18",
-          "canvas-confetti":"https://esm.sh/canvas-confetti@1.6.0"
-      }}
-    </script>
+    <script type="importmap">`+importMap+`</script>
     <script>window.__COMPILE__ = __TIME__;</script>
     <script type="module" src="/client.js" ></script>
 </head>
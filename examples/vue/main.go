@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"errors"
+	"net/url"
 	"os/signal"
 	"syscall"
 
@@ -15,12 +16,17 @@ import (
 func init() {
 	modules.Register("open", modules.ModuleFunc(openFile))
 	modules.Register("now", modules.ModuleFunc(now))
-	js.Loader().SetFileLoader(fileLoader)
 
 	// alias module from cdn
-	source("node_modules/vue", `export * from "https://esm.sh/vue@3.5.14";`)
-	source("node_modules/vue/server-renderer", `export * from "https://esm.sh/@vue/server-renderer@3.5.14";`)
-	source("node_modules/canvas-confetti", `export { default } from "https://esm.sh/canvas-confetti@1.6.0";`)
+	im, err := modules.ParseImportMap([]byte(`{"imports":{
+  "vue":"https://esm.sh/vue@3.5.14",
+  "vue/server-renderer":"https://esm.sh/@vue/server-renderer@3.5.14",
+  "canvas-confetti":"https://esm.sh/canvas-confetti@1.6.0"
+}}`), &url.URL{Scheme: "file", Path: "/"})
+	if err != nil {
+		panic(err)
+	}
+	js.SetLoader(modules.NewLoader(modules.WithFileLoader(fileLoader), modules.WithImportMap(im)))
 
 	source("index.html", `
 <html>
@@ -0,0 +1,129 @@
+package js
+
+import (
+	"sync"
+	"time"
+
+	"github.com/grafana/sobek"
+)
+
+// Clock the time source of the VM. The EventLoop timers, Date.now
+// and performance.now read the current time from it.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+// FakeClock the Clock which only moves when advanced, for deterministic tests.
+// The timers of the VMs using the FakeClock fire when the clock is advanced
+// past their deadlines, instead of by the wall clock.
+//
+//	clock := js.NewFakeClock(time.Unix(0, 0))
+//	vm := modulestest.New(t, js.WithClock(clock))
+type FakeClock struct {
+	mu    sync.Mutex
+	now   time.Time
+	loops []*EventLoop
+}
+
+// NewFakeClock returns a new FakeClock starts at the given time.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now returns the current time of the clock.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by the duration, the timers due are
+// queued to their EventLoop in the deadline order. A repeating timer is
+// queued for every interval elapsed.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	now, loops := c.now, c.loops
+	c.mu.Unlock()
+	for _, loop := range loops {
+		loop.cond.L.Lock()
+		loop.fireTimers(now)
+		loop.cond.L.Unlock()
+	}
+}
+
+// RunAllTimers advances the clock to the latest deadline of the scheduled
+// timers, so all the timers scheduled are queued. The timers scheduled by
+// the callbacks are not run, advance again to run them.
+func (c *FakeClock) RunAllTimers() {
+	c.mu.Lock()
+	now, loops := c.now, c.loops
+	c.mu.Unlock()
+	var latest time.Time
+	for _, loop := range loops {
+		loop.cond.L.Lock()
+		for _, t := range loop.timers {
+			if t.when.After(latest) {
+				latest = t.when
+			}
+		}
+		loop.cond.L.Unlock()
+	}
+	if latest.After(now) {
+		c.Advance(latest.Sub(now))
+	} else {
+		c.Advance(0)
+	}
+}
+
+// Next returns the duration until the earliest deadline of the scheduled
+// timers, false if no timer is scheduled.
+func (c *FakeClock) Next() (time.Duration, bool) {
+	c.mu.Lock()
+	now, loops := c.now, c.loops
+	c.mu.Unlock()
+	var (
+		earliest time.Time
+		ok       bool
+	)
+	for _, loop := range loops {
+		loop.cond.L.Lock()
+		if len(loop.timers) > 0 && (!ok || loop.timers[0].when.Before(earliest)) {
+			earliest, ok = loop.timers[0].when, true
+		}
+		loop.cond.L.Unlock()
+	}
+	if !ok {
+		return 0, false
+	}
+	return max(earliest.Sub(now), 0), true
+}
+
+// attach makes the clock fire the timers of the EventLoop.
+func (c *FakeClock) attach(e *EventLoop) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loops = append(c.loops, e)
+}
+
+// WithClock sets the Clock of the VM, it is used by the EventLoop timers and Date.
+func WithClock(clock Clock) Option {
+	return func(vm *vmImpl) {
+		vm.eventloop.clock = clock
+		if fake, ok := clock.(*FakeClock); ok {
+			fake.attach(vm.eventloop)
+		}
+		vm.runtime.SetTimeSource(clock.Now)
+	}
+}
+
+// GetClock returns the Clock of the VM.
+func GetClock(rt *sobek.Runtime) Clock { return self(rt).eventloop.clock }
+
+// Now returns the current time of the VM Clock.
+func Now(rt *sobek.Runtime) time.Time { return GetClock(rt).Now() }
@@ -0,0 +1,86 @@
+package js
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeClock(t *testing.T) {
+	t.Parallel()
+
+	t.Run("advance", func(t *testing.T) {
+		clock := NewFakeClock(time.Unix(0, 0))
+		loop := NewEventLoop()
+		loop.clock = clock
+		clock.attach(loop)
+
+		var order []string
+		err := loop.Start(func() error {
+			loop.AddTimer(20*time.Millisecond, false, func() error { order = append(order, "b"); return nil })
+			loop.AddTimer(10*time.Millisecond, false, func() error { order = append(order, "a"); return nil })
+			loop.AddTimer(20*time.Millisecond, false, func() error { order = append(order, "c"); return nil })
+			loop.AddTimer(time.Hour, false, func() error { order = append(order, "d"); return nil }).Unref()
+			clock.Advance(20 * time.Millisecond)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a", "b", "c"}, order)
+		assert.Equal(t, time.Unix(0, 0).Add(20*time.Millisecond), clock.Now())
+	})
+
+	t.Run("repeat", func(t *testing.T) {
+		clock := NewFakeClock(time.Unix(0, 0))
+		loop := NewEventLoop()
+		loop.clock = clock
+		clock.attach(loop)
+
+		count := 0
+		err := loop.Start(func() error {
+			timer := loop.AddTimer(10*time.Millisecond, true, func() error { count++; return nil })
+			clock.Advance(35 * time.Millisecond)
+			d, ok := clock.Next()
+			assert.True(t, ok)
+			assert.Equal(t, 5*time.Millisecond, d)
+			timer.Stop()
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 3, count)
+	})
+
+	t.Run("run all timers", func(t *testing.T) {
+		clock := NewFakeClock(time.Unix(0, 0))
+		loop := NewEventLoop()
+		loop.clock = clock
+		clock.attach(loop)
+
+		count := 0
+		err := loop.Start(func() error {
+			for i := range 5 {
+				loop.AddTimer(time.Duration(i)*time.Hour, false, func() error { count++; return nil })
+			}
+			clock.RunAllTimers()
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 5, count)
+		_, ok := clock.Next()
+		assert.False(t, ok)
+	})
+
+	t.Run("date", func(t *testing.T) {
+		clock := NewFakeClock(time.UnixMilli(1000))
+		vm := NewVM(WithClock(clock))
+		v, err := vm.RunString(context.Background(), `Date.now()`)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1000), v.ToInteger())
+		clock.Advance(time.Second)
+		v, err = vm.RunString(context.Background(), `new Date().getTime()`)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2000), v.ToInteger())
+	})
+}
@@ -2,7 +2,14 @@ package js
 
 import (
 	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
 	"unsafe"
+
+	"github.com/grafana/sobek"
 )
 
 type joinError []error
@@ -25,4 +32,210 @@ func (e joinError) Error() string {
 
 func (e joinError) Unwrap() []error { return e }
 
-var errCallableDefault = errors.New("module default export is not a function")
+var (
+	errCallableDefault = errors.New("module default export is not a function")
+	errUnsettled       = errors.New("promise is never settled, the EventLoop finished with nothing to do")
+	errModulePending   = errors.New("module evaluation is pending, the top-level await is not finished")
+)
+
+// Error the JavaScript exception thrown in the VM or the rejection reason of a promise.
+// The errors returned from VM.Run are converted to Error, use errors.As to get it.
+type Error struct {
+	// Name the error name, such as TypeError, empty if the thrown value is not an object
+	Name string
+	// Message the error message, or the string of the thrown value
+	Message string
+	// Cause the error cause, the JavaScript cause property or the Go error thrown by js.Throw
+	Cause error
+	// Frames the stack frames where the error was thrown, the innermost first
+	Frames []Frame
+	// Props the custom own enumerable properties of the error object
+	Props map[string]any
+	// Value the thrown JavaScript value, only use it on the VM goroutine
+	Value sobek.Value
+
+	exception *sobek.Exception
+}
+
+// Frame the stack frame of the Error.
+type Frame struct {
+	File     string
+	Line     int
+	Column   int
+	Function string
+}
+
+func (f Frame) String() string {
+	if f.Function == "" {
+		return fmt.Sprintf("%s:%d:%d", f.File, f.Line, f.Column)
+	}
+	return fmt.Sprintf("%s (%s:%d:%d)", f.Function, f.File, f.Line, f.Column)
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.Name == "":
+		return e.Message
+	case e.Message == "":
+		return e.Name
+	default:
+		return e.Name + ": " + e.Message
+	}
+}
+
+// Stack returns the error string followed by the stack frames.
+func (e *Error) Stack() string {
+	var b strings.Builder
+	b.WriteString(e.Error())
+	for _, f := range e.Frames {
+		b.WriteString("\n\tat ")
+		b.WriteString(f.String())
+	}
+	return b.String()
+}
+
+// Format implements fmt.Formatter, the %+v prints the Stack.
+func (e *Error) Format(s fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && s.Flag('+'):
+		_, _ = io.WriteString(s, e.Stack())
+	case verb == 'q':
+		_, _ = fmt.Fprintf(s, "%q", e.Error())
+	default:
+		_, _ = io.WriteString(s, e.Error())
+	}
+}
+
+// Unwrap returns the original *sobek.Exception and the Cause.
+func (e *Error) Unwrap() []error {
+	errs := make([]error, 0, 2)
+	if e.exception != nil {
+		errs = append(errs, e.exception)
+	}
+	if e.Cause != nil {
+		errs = append(errs, e.Cause)
+	}
+	return errs
+}
+
+// maxCauseDepth the max depth of the cause chain, to stop the cyclic causes.
+const maxCauseDepth = 16
+
+// NewError returns the Error of the JavaScript value, the frames are parsed from the stack property.
+// Must be called on the VM goroutine.
+func NewError(rt *sobek.Runtime, value sobek.Value) *Error {
+	return newError(rt, value, 0)
+}
+
+func newError(rt *sobek.Runtime, value sobek.Value, depth int) *Error {
+	e := &Error{Value: value}
+	if value == nil {
+		e.Message = "undefined"
+		return e
+	}
+	obj, ok := value.(*sobek.Object)
+	if !ok || obj.ClassName() != "Error" {
+		e.Message = value.String()
+		if ok {
+			e.Props = props(obj)
+		}
+		return e
+	}
+	if v := obj.Get("name"); v != nil && !sobek.IsUndefined(v) {
+		e.Name = v.String()
+	}
+	if v := obj.Get("message"); v != nil && !sobek.IsUndefined(v) {
+		e.Message = v.String()
+	}
+	if v := obj.Get("stack"); v != nil && sobek.IsString(v) {
+		e.Frames = parseStack(v.String())
+	}
+	if v := obj.Get("cause"); v != nil && !sobek.IsUndefined(v) && depth < maxCauseDepth {
+		if goErr, ok := v.Export().(error); ok {
+			e.Cause = goErr
+		} else {
+			e.Cause = newError(rt, v, depth+1)
+		}
+	}
+	e.Props = props(obj)
+	return e
+}
+
+// fromException returns the Error of the exception, the frames are the exception stack.
+func fromException(rt *sobek.Runtime, ex *sobek.Exception) *Error {
+	e := newError(rt, ex.Value(), 0)
+	e.exception = ex
+	if cause := ex.Unwrap(); cause != nil {
+		// the Go error thrown by js.Throw
+		e.Cause = cause
+	}
+	if stack := ex.Stack(); len(stack) > 0 {
+		e.Frames = make([]Frame, 0, len(stack))
+		for _, frame := range stack {
+			pos := frame.Position()
+			e.Frames = append(e.Frames, Frame{
+				File:     frame.SrcName(),
+				Line:     pos.Line,
+				Column:   pos.Column,
+				Function: frame.FuncName(),
+			})
+		}
+	}
+	return e
+}
+
+// props returns the own enumerable properties of the object except the standard ones.
+func props(obj *sobek.Object) map[string]any {
+	var ret map[string]any
+	for _, key := range obj.Keys() {
+		switch key {
+		case "name", "message", "stack", "cause":
+			continue
+		}
+		if ret == nil {
+			ret = make(map[string]any)
+		}
+		ret[key] = obj.Get(key).Export()
+	}
+	return ret
+}
+
+// stackFrame matches the frame line of the JavaScript stack, like
+// "at fn (file.js:1:2(3))" or "at file.js:1:2(3)".
+var stackFrame = regexp.MustCompile(`^\s*at (?:(.+?) \()?(.+?):(\d+):(\d+)(?:\(\d+\))?\)?$`)
+
+func parseStack(stack string) []Frame {
+	var frames []Frame
+	for _, line := range strings.Split(stack, "\n") {
+		m := stackFrame.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		l, _ := strconv.Atoi(m[3])
+		c, _ := strconv.Atoi(m[4])
+		frames = append(frames, Frame{File: m[2], Line: l, Column: c, Function: m[1]})
+	}
+	return frames
+}
+
+// wrapError converts the *sobek.Exception in the error to Error,
+// the joinError of a single error is unwrapped.
+func wrapError(rt *sobek.Runtime, err error) error {
+	switch e := err.(type) { //nolint:errorlint
+	case nil:
+		return nil
+	case joinError:
+		if len(e) == 1 {
+			return wrapError(rt, e[0])
+		}
+		ret := make(joinError, len(e))
+		for i, err := range e {
+			ret[i] = wrapError(rt, err)
+		}
+		return ret
+	case *sobek.Exception:
+		return fromException(rt, e)
+	default:
+		return err
+	}
+}
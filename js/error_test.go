@@ -0,0 +1,134 @@
+package js
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/grafana/sobek"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestError(t *testing.T) {
+	t.Parallel()
+
+	t.Run("exception", func(t *testing.T) {
+		vm := NewVM()
+		_, err := vm.RunString(context.Background(), `
+			function fail() {
+				const err = new RangeError("out of range", { cause: new Error("inner") });
+				err.code = "E_RANGE";
+				throw err;
+			}
+			fail();
+		`)
+		var jsErr *Error
+		require.ErrorAs(t, err, &jsErr)
+		assert.Equal(t, "RangeError", jsErr.Name)
+		assert.Equal(t, "out of range", jsErr.Message)
+		assert.Equal(t, map[string]any{"code": "E_RANGE"}, jsErr.Props)
+		require.NotEmpty(t, jsErr.Frames)
+		assert.Equal(t, "fail", jsErr.Frames[0].Function)
+		assert.Equal(t, 4, jsErr.Frames[0].Line)
+
+		var cause *Error
+		require.ErrorAs(t, jsErr.Cause, &cause)
+		assert.Equal(t, "inner", cause.Message)
+
+		var ex *sobek.Exception
+		assert.ErrorAs(t, err, &ex)
+		assert.Equal(t, "RangeError: out of range", jsErr.Error())
+		assert.Contains(t, jsErr.Stack(), "RangeError: out of range\n\tat fail (")
+		assert.Equal(t, jsErr.Stack(), fmt.Sprintf("%+v", jsErr))
+		assert.Equal(t, jsErr.Error(), fmt.Sprintf("%v", jsErr))
+	})
+
+	t.Run("non error value", func(t *testing.T) {
+		vm := NewVM()
+		_, err := vm.RunString(context.Background(), `throw "plain"`)
+		var jsErr *Error
+		require.ErrorAs(t, err, &jsErr)
+		assert.Empty(t, jsErr.Name)
+		assert.Equal(t, "plain", jsErr.Message)
+	})
+
+	t.Run("go error", func(t *testing.T) {
+		vm := NewVM()
+		rt := vm.Runtime()
+		_ = rt.Set("fail", func() { Throw(rt, assert.AnError) })
+		_, err := vm.RunString(context.Background(), `fail()`)
+		var jsErr *Error
+		require.ErrorAs(t, err, &jsErr)
+		assert.ErrorIs(t, err, assert.AnError)
+	})
+
+	t.Run("join", func(t *testing.T) {
+		vm := NewVM()
+		err := vm.Run(context.Background(), func() error {
+			enqueue := EnqueueJob(vm.Runtime())
+			enqueue(func() error {
+				_, err := vm.Runtime().RunString(`throw new TypeError("first")`)
+				return err
+			})
+			_, err := vm.Runtime().RunString(`throw new Error("second")`)
+			return err
+		})
+		var errs interface{ Unwrap() []error }
+		require.ErrorAs(t, err, &errs)
+		for _, e := range errs.Unwrap() {
+			var jsErr *Error
+			assert.ErrorAs(t, e, &jsErr)
+		}
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		vm := NewVM()
+		err := vm.Run(context.Background(), func() error { panic("panicked") })
+		var jsErr *Error
+		require.ErrorAs(t, err, &jsErr)
+		assert.Equal(t, "Panic", jsErr.Name)
+		assert.Equal(t, "panicked", jsErr.Message)
+
+		err = vm.Run(context.Background(), func() error { panic(assert.AnError) })
+		assert.ErrorIs(t, err, assert.AnError)
+	})
+
+	t.Run("throw", func(t *testing.T) {
+		vm := NewVM()
+		rt := vm.Runtime()
+		err := vm.Run(context.Background(), func() error {
+			_, err := rt.RunString(`function fail() { throw new RangeError("out of range", { cause: "reason" }) }`)
+			require.NoError(t, err)
+			fail, _ := sobek.AssertFunction(rt.Get("fail"))
+			_, err = fail(sobek.Undefined())
+			Throw(rt, err)
+			return nil
+		})
+		var jsErr *Error
+		require.ErrorAs(t, err, &jsErr)
+		assert.Equal(t, "RangeError", jsErr.Name)
+		assert.Equal(t, "out of range", jsErr.Message)
+		assert.EqualError(t, jsErr.Cause, "reason")
+		require.NotEmpty(t, jsErr.Frames)
+		assert.Equal(t, "fail", jsErr.Frames[0].Function)
+
+		err = vm.Run(context.Background(), func() error { Throw(rt, assert.AnError); return nil })
+		require.ErrorAs(t, err, &jsErr)
+		assert.Equal(t, "GoError", jsErr.Name)
+		assert.ErrorIs(t, err, assert.AnError)
+
+		err = vm.Run(context.Background(), func() error { panic(rt.NewTypeError("invalid")) })
+		require.ErrorAs(t, err, &jsErr)
+		assert.Equal(t, "TypeError", jsErr.Name)
+		assert.Equal(t, "invalid", jsErr.Message)
+	})
+
+	t.Run("parse stack", func(t *testing.T) {
+		frames := parseStack("Error: msg\n\tat fn (file.js:1:2(3))\n\tat file.js:4:5(6)\n\tat native")
+		assert.Equal(t, []Frame{
+			{File: "file.js", Line: 1, Column: 2, Function: "fn"},
+			{File: "file.js", Line: 4, Column: 5},
+		}, frames)
+	})
+}
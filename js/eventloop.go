@@ -2,16 +2,28 @@ package js
 
 import (
 	"sync"
+	"sync/atomic"
 
 	"github.com/grafana/sobek"
 )
 
 // EventLoop implements an eventloop.
 type EventLoop struct {
-	queue   []func() error // queue to store the job to be executed
-	cleanup []func()       // job of cleanup
-	enqueue uint           // Count of job in the event loop
-	cond    *sync.Cond     // Condition variable for synchronization
+	queue    []func() error // queue to store the job to be executed
+	cleanup  []func()       // job of cleanup
+	enqueue  uint           // Count of job in the event loop
+	maxQueue int            // max length of the queue since started
+	cond     *sync.Cond     // Condition variable for synchronization
+
+	timers       timerHeap     // scheduled timers
+	timerSeq     uint64        // the schedule order of timers
+	refTimers    int           // count of the scheduled timers which keep the loop alive
+	timerRunning bool          // the timer wakeup goroutine is running
+	wakeup       chan struct{} // notifies the wakeup goroutine
+	clock        Clock         // the time source of timers
+
+	busy    atomic.Bool // the jobs are executing
+	running bool        // the loop is started
 }
 
 // NewEventLoop create a new EventLoop instance
@@ -19,14 +31,29 @@ func NewEventLoop() *EventLoop {
 	return &EventLoop{
 		cond:    sync.NewCond(new(sync.Mutex)),
 		cleanup: make([]func(), 0),
+		wakeup:  make(chan struct{}, 1),
+		clock:   realClock{},
 	}
 }
 
-// Start the event loop and execute the provided function
+// Start the event loop and execute the provided function.
+// The jobs enqueued while the loop is not running are executed before the function.
 func (e *EventLoop) Start(task func() error) (err error) {
 	e.cond.L.Lock()
-	e.queue = []func() error{task}
+	e.running = true
+	e.queue = append(e.queue, task)
+	e.maxQueue = len(e.queue)
 	e.cond.L.Unlock()
+	defer func() {
+		e.cond.L.Lock()
+		if e.running {
+			// the job panics out of the loop, drop the rest jobs
+			e.running = false
+			e.queue = e.queue[:0]
+		}
+		e.cond.L.Unlock()
+		e.busy.Store(false)
+	}()
 	for {
 		e.cond.L.Lock()
 
@@ -35,6 +62,7 @@ func (e *EventLoop) Start(task func() error) (err error) {
 			e.queue = make([]func() error, 0, len(queue))
 			e.cond.L.Unlock()
 
+			e.busy.Store(true)
 			for _, job := range queue {
 				if err2 := job(); err2 != nil {
 					if err != nil {
@@ -44,15 +72,19 @@ func (e *EventLoop) Start(task func() error) (err error) {
 					}
 				}
 			}
+			e.busy.Store(false)
 			continue
 		}
 
-		if e.enqueue > 0 {
+		if e.enqueue > 0 || e.refTimers > 0 {
 			e.cond.Wait()
 			e.cond.L.Unlock()
 			continue
 		}
 
+		e.clearTimers()
+		e.running = false
+
 		if len(e.cleanup) > 0 {
 			cleanup := e.cleanup
 			e.cleanup = e.cleanup[:0]
@@ -152,22 +184,34 @@ func (e *EventLoop) EnqueueJob() Enqueue {
 			return // Eventloop stopped
 		}
 		e.queue = append(e.queue, job) // Add the job to the queue
+		e.maxQueue = max(e.maxQueue, len(e.queue))
 		called = true
 		e.enqueue--
 		e.cond.Signal() // Signal the condition variable
 	}
 }
 
-// Stop the eventloop with the provided error
+// Stop the eventloop with the provided error, it does nothing if the loop is not running.
 func (e *EventLoop) Stop(err error) {
 	e.cond.L.Lock()
 	defer e.cond.L.Unlock()
+	if !e.running {
+		return
+	}
 	// clean the queue
 	e.queue = append(e.queue[:0], func() error { return err })
 	e.enqueue = 0
+	e.clearTimers()
 	e.cond.Signal()
 }
 
+// MaxQueueLen returns the max length of the job queue since the last start.
+func (e *EventLoop) MaxQueueLen() int {
+	e.cond.L.Lock()
+	defer e.cond.L.Unlock()
+	return e.maxQueue
+}
+
 // Cleanup add a function to execute when run finish.
 func (e *EventLoop) Cleanup(job ...func()) {
 	e.cond.L.Lock()
@@ -182,3 +226,7 @@ func EnqueueJob(rt *sobek.Runtime) Enqueue { return self(rt).eventloop.EnqueueJo
 // Cleanup add a function to execute when the VM has finished running.
 // eg: close resources...
 func Cleanup(rt *sobek.Runtime, job ...func()) { self(rt).eventloop.Cleanup(job...) }
+
+// GetEventLoop returns the EventLoop of the VM. Unlike EnqueueJob,
+// the returned EventLoop can be used from other goroutines.
+func GetEventLoop(rt *sobek.Runtime) *EventLoop { return self(rt).eventloop }
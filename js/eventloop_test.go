@@ -188,6 +188,20 @@ func TestEventLoop(t *testing.T) {
 		assert.False(t, executed)
 	})
 
+	t.Run("enqueue while idle", func(t *testing.T) {
+		loop := NewEventLoop()
+		require.NoError(t, loop.Start(func() error { return nil }))
+
+		var calls []string
+		loop.EnqueueJob()(func() error { calls = append(calls, "idle"); return nil })
+		// the loop is not running
+		loop.Stop(context.Canceled)
+
+		err := loop.Start(func() error { calls = append(calls, "task"); return nil })
+		require.NoError(t, err)
+		assert.Equal(t, []string{"idle", "task"}, calls)
+	})
+
 	t.Run("error in main task", func(t *testing.T) {
 		loop := NewEventLoop()
 		expectedErr := errors.New("main task error")
@@ -250,6 +264,7 @@ func TestEventLoop(t *testing.T) {
 				return nil
 			})
 		})
+		assert.False(t, loop.busy.Load())
 	})
 
 	t.Run("error after stop", func(t *testing.T) {
@@ -0,0 +1,163 @@
+package js
+
+import (
+	"github.com/grafana/sobek"
+)
+
+// WithResetGlobals restores the global object after each run finishes,
+// to the snapshot taken when the VM was created.
+// Own properties added later are deleted, changed ones are restored,
+// include the global modules lazily instantiated by modules.Loader InitGlobal.
+// The properties are compared by the descriptors, the accessors defined by the run are never called.
+// The top-level let, const and class declarations of the scripts live in the global lexical
+// environment instead of the global object, they are not reset, use the modules to isolate them.
+// This is usually used with the pooled VMs so that runs do not see each other's globals.
+func WithResetGlobals() Option {
+	return func(vm *vmImpl) { vm.resetGlobals = true }
+}
+
+// globalsSnapshot the own property descriptors and prototype of the global object.
+type globalsSnapshot struct {
+	rt      *sobek.Runtime
+	global  *sobek.Object
+	proto   *sobek.Object
+	values  map[string]property
+	symbols map[*sobek.Symbol]property
+	// describe the original Object.getOwnPropertyDescriptor
+	describe sobek.Callable
+}
+
+// property the own property descriptor.
+type property struct {
+	accessor                           bool
+	value, getter, setter              sobek.Value
+	writable, enumerable, configurable bool
+}
+
+func (p property) same(other property) bool {
+	return p.accessor == other.accessor &&
+		p.writable == other.writable && p.enumerable == other.enumerable && p.configurable == other.configurable &&
+		sameValue(p.value, other.value) && sameValue(p.getter, other.getter) && sameValue(p.setter, other.setter)
+}
+
+func sameValue(a, b sobek.Value) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.SameAs(b)
+}
+
+// snapshotGlobals takes a snapshot of the global object,
+// the global must be the raw object not the proxy of InitGlobal.
+func snapshotGlobals(rt *sobek.Runtime, global *sobek.Object) *globalsSnapshot {
+	describe, _ := sobek.AssertFunction(rt.Get("Object").ToObject(rt).Get("getOwnPropertyDescriptor"))
+	s := &globalsSnapshot{
+		rt:       rt,
+		global:   global,
+		proto:    global.Prototype(),
+		values:   make(map[string]property),
+		symbols:  make(map[*sobek.Symbol]property),
+		describe: describe,
+	}
+	for _, key := range global.GetOwnPropertyNames() {
+		s.values[key], _ = s.property(rt.ToValue(key))
+	}
+	for _, sym := range global.Symbols() {
+		s.symbols[sym], _ = s.property(sym)
+	}
+	return s
+}
+
+// property returns the own property descriptor of the global without calling the accessors.
+func (s *globalsSnapshot) property(key sobek.Value) (p property, ok bool) {
+	value, err := s.describe(sobek.Undefined(), s.global, key)
+	if err != nil {
+		return
+	}
+	desc, ok := value.(*sobek.Object)
+	if !ok {
+		return
+	}
+	// only the own fields, the getters of Object.prototype are not called
+	for _, field := range desc.Keys() {
+		v := desc.Get(field)
+		switch field {
+		case "value":
+			p.value = v
+		case "get":
+			p.accessor, p.getter = true, v
+		case "set":
+			p.accessor, p.setter = true, v
+		case "writable":
+			p.writable = v.ToBoolean()
+		case "enumerable":
+			p.enumerable = v.ToBoolean()
+		case "configurable":
+			p.configurable = v.ToBoolean()
+		}
+	}
+	return p, true
+}
+
+// restore deletes the properties added after the snapshot and redefines the changed ones.
+func (s *globalsSnapshot) restore() {
+	global := s.global
+	for _, key := range global.GetOwnPropertyNames() {
+		prop, ok := s.values[key]
+		if !ok {
+			if err := global.Delete(key); err != nil {
+				// the non-configurable property like the global var declaration
+				if current, _ := s.property(s.rt.ToValue(key)); !current.accessor {
+					_ = global.DefineDataProperty(key, sobek.Undefined(), sobek.FLAG_NOT_SET, sobek.FLAG_NOT_SET, sobek.FLAG_NOT_SET)
+				}
+			}
+			continue
+		}
+		if current, _ := s.property(s.rt.ToValue(key)); !current.same(prop) {
+			s.define(key, prop)
+		}
+	}
+	for key, prop := range s.values {
+		if _, ok := s.property(s.rt.ToValue(key)); !ok {
+			s.define(key, prop)
+		}
+	}
+	for _, sym := range global.Symbols() {
+		prop, ok := s.symbols[sym]
+		if !ok {
+			_ = global.DeleteSymbol(sym)
+			continue
+		}
+		if current, _ := s.property(sym); !current.same(prop) {
+			s.defineSymbol(sym, prop)
+		}
+	}
+	for sym, prop := range s.symbols {
+		if _, ok := s.property(sym); !ok {
+			s.defineSymbol(sym, prop)
+		}
+	}
+	if global.Prototype() != s.proto {
+		_ = global.SetPrototype(s.proto)
+	}
+}
+
+func (s *globalsSnapshot) define(key string, p property) {
+	if p.accessor {
+		_ = s.global.DefineAccessorProperty(key, p.getter, p.setter,
+			sobek.ToFlag(p.configurable), sobek.ToFlag(p.enumerable))
+		return
+	}
+	_ = s.global.DefineDataProperty(key, p.value,
+		sobek.ToFlag(p.writable), sobek.ToFlag(p.configurable), sobek.ToFlag(p.enumerable))
+}
+
+func (s *globalsSnapshot) defineSymbol(sym *sobek.Symbol, p property) {
+	if p.accessor {
+		_ = s.global.DefineAccessorPropertySymbol(sym, p.getter, p.setter,
+			sobek.ToFlag(p.configurable), sobek.ToFlag(p.enumerable))
+		return
+	}
+	_ = s.global.DefineDataPropertySymbol(sym, p.value,
+		sobek.ToFlag(p.writable), sobek.ToFlag(p.configurable), sobek.ToFlag(p.enumerable))
+}
@@ -0,0 +1,92 @@
+package js
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/sobek"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResetGlobals(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	t.Run("added", func(t *testing.T) {
+		vm := NewVM(WithResetGlobals())
+
+		value, err := vm.RunString(ctx, `globalThis.secret = "tenant a"; var leaked = 1; secret`)
+		require.NoError(t, err)
+		assert.Equal(t, "tenant a", value.String())
+
+		value, err = vm.RunString(ctx, `typeof secret + "," + typeof leaked`)
+		require.NoError(t, err)
+		assert.Equal(t, "undefined,undefined", value.String())
+	})
+
+	t.Run("changed", func(t *testing.T) {
+		vm := NewVM(WithResetGlobals(), WithInitial(func(rt *sobek.Runtime) {
+			_ = rt.Set("config", "initial")
+		}))
+
+		_, err := vm.RunString(ctx, `config = "changed"; console = undefined; delete globalThis.JSON`)
+		require.NoError(t, err)
+
+		value, err := vm.RunString(ctx, `config + "," + typeof console + "," + typeof JSON`)
+		require.NoError(t, err)
+		assert.Equal(t, "initial,object,object", value.String())
+	})
+
+	t.Run("prototype", func(t *testing.T) {
+		vm := NewVM(WithResetGlobals())
+
+		_, err := vm.RunString(ctx, `Object.setPrototypeOf(globalThis, { polluted: true })`)
+		require.NoError(t, err)
+
+		value, err := vm.RunString(ctx, `globalThis.polluted === undefined`)
+		require.NoError(t, err)
+		assert.True(t, value.ToBoolean())
+	})
+
+	t.Run("accessor", func(t *testing.T) {
+		vm := NewVM(WithResetGlobals(), WithInitial(func(rt *sobek.Runtime) {
+			_ = rt.Set("config", "initial")
+		}))
+
+		// the accessors are never called by the restore
+		_, err := vm.RunString(ctx, `
+			Object.defineProperty(globalThis, "x", { get() { for (;;) {} }, configurable: true });
+			Object.defineProperty(globalThis, "y", { set(v) { for (;;) {} } });
+			Object.defineProperty(globalThis, "config", { get() { for (;;) {} } });
+		`)
+		require.NoError(t, err)
+
+		value, err := vm.RunString(ctx, `typeof x + "," + config + "," + y`)
+		require.NoError(t, err)
+		assert.Equal(t, "undefined,initial,undefined", value.String())
+	})
+
+	t.Run("lexical", func(t *testing.T) {
+		vm := NewVM(WithResetGlobals())
+
+		_, err := vm.RunString(ctx, `let lexical = 1; globalThis.property = 1`)
+		require.NoError(t, err)
+
+		// the global lexical environment is not reset
+		value, err := vm.RunString(ctx, `typeof lexical + "," + typeof property`)
+		require.NoError(t, err)
+		assert.Equal(t, "number,undefined", value.String())
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		vm := NewVM()
+
+		_, err := vm.RunString(ctx, `globalThis.kept = 1`)
+		require.NoError(t, err)
+
+		value, err := vm.RunString(ctx, `kept`)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), value.ToInteger())
+	})
+}
@@ -1,7 +1,6 @@
 package js
 
 import (
-	"errors"
 	"sync/atomic"
 
 	"github.com/grafana/sobek"
@@ -21,22 +20,59 @@ func Loader() modules.Loader { return loader.Load().(modules.Loader) }
 func SetLoader(ml modules.Loader) { loader.Store(ml) }
 
 // ModuleInstance return the sobek.ModuleInstance.
+// The module with top-level await may not finish the evaluation, it returns
+// an error until the evaluation settled, use EvaluateModule in the EventLoop to wait for it.
 func ModuleInstance(rt *sobek.Runtime, module sobek.CyclicModuleRecord) (sobek.ModuleInstance, error) {
-	instance := rt.GetModuleInstance(module)
-	if instance == nil {
+	promise, err := EvaluateModule(rt, module)
+	if err != nil {
+		return nil, err
+	}
+	switch promise.State() {
+	case sobek.PromiseStateRejected:
+		return nil, NewError(rt, promise.Result())
+	case sobek.PromiseStatePending:
+		return nil, errModulePending
+	default:
+		return rt.GetModuleInstance(module), nil
+	}
+}
+
+// EvaluateModule links and evaluates the module if not instantiated, returns the
+// evaluation promise which is settled after the top-level await finished.
+// The module evaluated before returns the same promise.
+func EvaluateModule(rt *sobek.Runtime, module sobek.CyclicModuleRecord) (*sobek.Promise, error) {
+	evaluated := evaluations(rt)
+	if promise, ok := evaluated[module]; ok {
+		return promise, nil
+	}
+	if rt.GetModuleInstance(module) == nil {
 		if err := module.Link(); err != nil {
 			return nil, err
 		}
-		promise := rt.CyclicModuleRecordEvaluate(module, Loader().ResolveModule)
-		switch promise.State() {
-		case sobek.PromiseStateRejected:
-			return nil, errors.New(promise.Result().String())
-		case sobek.PromiseStateFulfilled:
-		default:
+	}
+	// the module instantiated as a dependency is settled by the runtime evaluation state
+	promise := rt.CyclicModuleRecordEvaluate(module, Loader().ResolveModule)
+	evaluated[module] = promise
+	return promise, nil
+}
+
+var symEvaluations = sobek.NewSymbol("Symbol.__evaluations__")
+
+type moduleEvaluations struct {
+	promises map[sobek.CyclicModuleRecord]*sobek.Promise
+}
+
+// evaluations returns the evaluation promises of the modules in the runtime.
+func evaluations(rt *sobek.Runtime) map[sobek.CyclicModuleRecord]*sobek.Promise {
+	global := rt.GlobalObject()
+	if value := global.GetSymbol(symEvaluations); value != nil {
+		if e, ok := value.Export().(*moduleEvaluations); ok {
+			return e.promises
 		}
-		return rt.GetModuleInstance(module), nil
 	}
-	return instance, nil
+	e := &moduleEvaluations{promises: make(map[sobek.CyclicModuleRecord]*sobek.Promise)}
+	_ = global.DefineDataPropertySymbol(symEvaluations, rt.ToValue(e), sobek.FLAG_FALSE, sobek.FLAG_FALSE, sobek.FLAG_FALSE)
+	return e.promises
 }
 
 // ModuleCallable return the sobek.CyclicModuleRecord default export as sobek.Callable.
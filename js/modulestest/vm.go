@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/grafana/sobek"
 	"github.com/shiroyk/ski/js"
@@ -92,9 +93,54 @@ func New(t testing.TB, opts ...js.Option) VM {
 	})
 
 	_ = vm.Runtime().Set("assert", obj)
+	if clock, ok := js.GetClock(vm.Runtime()).(*js.FakeClock); ok {
+		_ = vm.Runtime().Set("clock", fakeClock(vm.Runtime(), clock))
+	}
 	return VM{vm}
 }
 
+// maxTimers the max number of timers runAllTimers runs, to stop the endless intervals.
+const maxTimers = 1000
+
+// fakeClock returns the clock object which controls the js.FakeClock in JavaScript.
+// The returned promises are fulfilled after the fired timer callbacks run.
+//
+//	setTimeout(() => console.log("fired"), 1000);
+//	await clock.advance(1000);
+//	await clock.runAllTimers();
+func fakeClock(rt *sobek.Runtime, clock *js.FakeClock) *sobek.Object {
+	obj := rt.NewObject()
+	_ = obj.Set("now", func(sobek.FunctionCall) sobek.Value {
+		return rt.ToValue(clock.Now().UnixMilli())
+	})
+	_ = obj.Set("advance", func(call sobek.FunctionCall) sobek.Value {
+		clock.Advance(time.Duration(call.Argument(0).ToInteger()) * time.Millisecond)
+		promise, resolve, _ := rt.NewPromise()
+		js.EnqueueJob(rt)(func() error { return resolve(sobek.Undefined()) })
+		return rt.ToValue(promise)
+	})
+	_ = obj.Set("runAllTimers", func(sobek.FunctionCall) sobek.Value {
+		promise, resolve, reject := rt.NewPromise()
+		count := 0
+		var step func() error
+		step = func() error {
+			next, ok := clock.Next()
+			if !ok {
+				return resolve(count)
+			}
+			if count++; count > maxTimers {
+				return reject(rt.NewGoError(fmt.Errorf("aborting after running %d timers, assuming an infinite loop", maxTimers)))
+			}
+			clock.Advance(next)
+			js.EnqueueJob(rt)(step)
+			return nil
+		}
+		js.EnqueueJob(rt)(step)
+		return rt.ToValue(promise)
+	})
+	return obj
+}
+
 // PromiseResult get the promise resolve result
 // panic when promise reject.
 func PromiseResult(value sobek.Value) sobek.Value {
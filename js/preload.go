@@ -0,0 +1,119 @@
+package js
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/grafana/sobek"
+)
+
+// WithPreload evaluates the modules when the VM created,
+// later runs import them reuse the live module instances.
+// The module can be a specifier resolved by the Loader or a compiled sobek.CyclicModuleRecord.
+func WithPreload(modules ...any) Option {
+	return func(vm *vmImpl) { vm.preload = append(vm.preload, modules...) }
+}
+
+// WithPreloadError call when the preload module failed,
+// the default logs the error.
+func WithPreloadError(fn func(module any, err error)) Option {
+	return func(vm *vmImpl) { vm.preloadError = fn }
+}
+
+// WithPreloadTimeout limit the evaluation time of each preload module,
+// the default is DefaultPreloadTimeout. The module timed out is reported
+// to WithPreloadError with context.DeadlineExceeded.
+func WithPreloadTimeout(d time.Duration) Option {
+	return func(vm *vmImpl) { vm.preloadTimeout = d }
+}
+
+// DefaultPreloadTimeout the default evaluation time limit of each preload module.
+const DefaultPreloadTimeout = 30 * time.Second
+
+// runPreload evaluates the preload modules in the EventLoop,
+// the top-level await of the modules is awaited.
+// Each module runs under the preload timeout and the quotas of the VM.
+func (vm *vmImpl) runPreload() {
+	onError := vm.preloadError
+	if onError == nil {
+		onError = func(module any, err error) {
+			slog.Warn("failed to preload module", "module", module, "error", err.Error())
+		}
+	}
+	timeout := vm.preloadTimeout
+	if timeout <= 0 {
+		timeout = DefaultPreloadTimeout
+	}
+	defer func() { vm.ctx = context.Background() }()
+
+	for _, module := range vm.preload {
+		err := vm.preloadModule(module, timeout)
+		if err != nil {
+			onError(module, err)
+		}
+	}
+}
+
+func (vm *vmImpl) preloadModule(module any, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	vm.runtime.ClearInterrupt()
+	defer vm.runtime.ClearInterrupt()
+	vm.ctx = ctx
+
+	interrupt := func(err error) {
+		vm.runtime.Interrupt(err)
+		vm.eventloop.Stop(err)
+	}
+	stop := context.AfterFunc(ctx, func() { interrupt(ctx.Err()) })
+	defer stop()
+	stopQuota := vm.quota.watch(vm.eventloop.busy.Load, interrupt)
+	defer stopQuota()
+
+	settled := false
+	err := vm.eventloop.Start(func() (err error) {
+		defer func() {
+			if x := recover(); x != nil {
+				err = fmt.Errorf("%v", x)
+			}
+		}()
+		record, err := resolvePreload(module)
+		if err != nil {
+			return err
+		}
+		promise, err := EvaluateModule(vm.runtime, record)
+		if err != nil {
+			return err
+		}
+		return vm.await(vm.runtime.ToValue(promise), func(sobek.Value) error {
+			settled = true
+			return nil
+		})
+	})
+	err = wrapError(vm.runtime, vm.quota.exceeded(err))
+	if err == nil && !settled {
+		err = errUnsettled
+	}
+	return err
+}
+
+func resolvePreload(module any) (sobek.CyclicModuleRecord, error) {
+	switch m := module.(type) {
+	case sobek.CyclicModuleRecord:
+		return m, nil
+	case string:
+		record, err := Loader().ResolveModule(nil, m)
+		if err != nil {
+			return nil, err
+		}
+		cm, ok := record.(sobek.CyclicModuleRecord)
+		if !ok {
+			return nil, fmt.Errorf("module %s is not a cyclic module record", m)
+		}
+		return cm, nil
+	default:
+		return nil, fmt.Errorf("preload module must be a specifier or sobek.CyclicModuleRecord, but got %T", module)
+	}
+}
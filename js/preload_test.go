@@ -0,0 +1,117 @@
+package js
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/modules"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type preloadModule struct{ init int }
+
+func (m *preloadModule) Instantiate(rt *sobek.Runtime) (sobek.Value, error) {
+	m.init++
+	return rt.ToValue(map[string]any{"init": m.init}), nil
+}
+
+func TestPreload(t *testing.T) {
+	t.Run("module record", func(t *testing.T) {
+		lib, err := CompileModule("lib", `
+			globalThis.evaluated = (globalThis.evaluated ?? 0) + 1;
+			export default () => globalThis.evaluated;
+		`)
+		require.NoError(t, err)
+
+		vm := NewVM(WithPreload(lib))
+		assert.Equal(t, int64(1), vm.Runtime().Get("evaluated").ToInteger())
+
+		for range 2 {
+			value, err := vm.RunModule(context.Background(), lib)
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), value.ToInteger())
+		}
+	})
+
+	t.Run("specifier", func(t *testing.T) {
+		mod := new(preloadModule)
+		modules.Register("preload", mod)
+		defer modules.Remove("ski/preload")
+
+		vm := NewVM(WithPreload("ski/preload"))
+		assert.Equal(t, 1, mod.init)
+
+		module, err := CompileModule("main", `
+			import preload from "ski/preload";
+			export default () => preload.init;
+		`)
+		require.NoError(t, err)
+
+		value, err := vm.RunModule(context.Background(), module)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), value.ToInteger())
+		assert.Equal(t, 1, mod.init)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		var (
+			failed any
+			cause  error
+		)
+		NewVM(WithPreload("ski/not-exists", 1), WithPreloadError(func(module any, err error) {
+			if failed == nil {
+				failed, cause = module, err
+			}
+		}))
+		assert.Equal(t, "ski/not-exists", failed)
+		assert.ErrorIs(t, cause, modules.ErrNotFoundModule)
+	})
+
+	t.Run("top-level await", func(t *testing.T) {
+		lib, err := CompileModule("lib", `
+			await null;
+			globalThis.awaited = true;
+			export default () => globalThis.awaited;
+		`)
+		require.NoError(t, err)
+
+		vm := NewVM(WithPreload(lib))
+		assert.True(t, vm.Runtime().Get("awaited").ToBoolean())
+		value, err := vm.RunModule(context.Background(), lib)
+		require.NoError(t, err)
+		assert.True(t, value.ToBoolean())
+
+		failing, err := CompileModule("failing", `await null; throw new Error("async preload");`)
+		require.NoError(t, err)
+		var cause error
+		vm = NewVM(WithPreload(failing), WithPreloadError(func(_ any, err error) { cause = err }))
+		assert.ErrorContains(t, cause, "async preload")
+
+		// the rejection of the evaluation is kept
+		_, err = vm.RunModule(context.Background(), failing)
+		assert.ErrorContains(t, err, "async preload")
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		loop, err := CompileModule("loop", `for (;;) {}`)
+		require.NoError(t, err)
+
+		var cause error
+		vm := NewVM(WithPreload(loop), WithPreloadTimeout(50*time.Millisecond),
+			WithPreloadError(func(_ any, err error) { cause = err }))
+		assert.ErrorIs(t, cause, context.DeadlineExceeded)
+
+		value, err := vm.RunString(context.Background(), `1 + 1`)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), value.ToInteger())
+
+		loop, err = CompileModule("loop", `for (;;) {}`)
+		require.NoError(t, err)
+		NewVM(WithPreload(loop), WithMaxRunTime(50*time.Millisecond),
+			WithPreloadError(func(_ any, err error) { cause = err }))
+		assert.ErrorIs(t, cause, ErrQuotaExceeded)
+	})
+}
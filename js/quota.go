@@ -0,0 +1,173 @@
+package js
+
+import (
+	"errors"
+	"fmt"
+	"runtime/metrics"
+	"sync"
+	"time"
+
+	"github.com/grafana/sobek"
+)
+
+// ErrQuotaExceeded reports whether a run was interrupted by a resource limit,
+// every QuotaExceededError matches it with errors.Is.
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// Quota the kind of VM resource limit.
+type Quota int
+
+const (
+	// QuotaRunTime the maximum execution time of a single run.
+	QuotaRunTime Quota = iota + 1
+	// QuotaCallStack the maximum call stack size.
+	QuotaCallStack
+	// QuotaAlloc the approximate allocation budget of a single run.
+	QuotaAlloc
+)
+
+func (q Quota) String() string {
+	switch q {
+	case QuotaRunTime:
+		return "run time"
+	case QuotaCallStack:
+		return "call stack"
+	case QuotaAlloc:
+		return "allocation"
+	default:
+		return "unknown"
+	}
+}
+
+// QuotaExceededError the VM was interrupted because a resource limit was hit.
+type QuotaExceededError struct {
+	// Quota the limit that tripped
+	Quota Quota
+	// Limit the configured value of the quota, time.Duration for QuotaRunTime,
+	// the frames for QuotaCallStack and the bytes for QuotaAlloc.
+	Limit int64
+
+	cause error
+}
+
+func (e *QuotaExceededError) Error() string {
+	var limit string
+	switch e.Quota {
+	case QuotaRunTime:
+		limit = time.Duration(e.Limit).String()
+	case QuotaAlloc:
+		limit = fmt.Sprintf("%d bytes", e.Limit)
+	default:
+		limit = fmt.Sprintf("%d", e.Limit)
+	}
+	return fmt.Sprintf("%s quota exceeded: limit %s", e.Quota, limit)
+}
+
+func (e *QuotaExceededError) Is(target error) bool { return target == ErrQuotaExceeded }
+
+func (e *QuotaExceededError) Unwrap() error { return e.cause }
+
+// WithMaxCallStackSize limit the call stack size of the VM.
+func WithMaxCallStackSize(size int) Option {
+	return func(vm *vmImpl) {
+		vm.quota.callStack = size
+		vm.runtime.SetMaxCallStackSize(size)
+	}
+}
+
+// WithMaxRunTime limit the execution time of each run,
+// separate from the context passed to Run.
+func WithMaxRunTime(d time.Duration) Option {
+	return func(vm *vmImpl) { vm.quota.runTime = d }
+}
+
+// WithMaxAllocBytes limit the bytes allocated during each run.
+// The allocation is sampled from the process heap statistics and only counted
+// while the run is executing, not while it is waiting in the EventLoop, so the
+// VMs waiting for timers or promises are not charged for the other VMs.
+// It is still approximate when several VMs execute at the same time.
+func WithMaxAllocBytes(n uint64) Option {
+	return func(vm *vmImpl) { vm.quota.alloc = n }
+}
+
+// quotaCheckInterval the sampling interval of the allocation budget.
+const quotaCheckInterval = 10 * time.Millisecond
+
+type quota struct {
+	runTime   time.Duration
+	callStack int
+	alloc     uint64
+}
+
+// watch starts the run time and allocation watchers,
+// interrupt is called once when a limit is hit.
+// The allocation is counted only when busy reports the run is executing.
+func (q *quota) watch(busy func() bool, interrupt func(error)) (stop func()) {
+	var (
+		once  sync.Once
+		stops []func()
+	)
+	trip := func(err error) { once.Do(func() { interrupt(err) }) }
+
+	if q.runTime > 0 {
+		t := time.AfterFunc(q.runTime, func() {
+			trip(&QuotaExceededError{Quota: QuotaRunTime, Limit: int64(q.runTime)})
+		})
+		stops = append(stops, func() { t.Stop() })
+	}
+
+	if q.alloc > 0 {
+		done := make(chan struct{})
+		last := allocBytes()
+		go func() {
+			ticker := time.NewTicker(quotaCheckInterval)
+			defer ticker.Stop()
+			var used uint64
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					current := allocBytes()
+					if busy() {
+						used += current - last
+					}
+					last = current
+					if used > q.alloc {
+						trip(&QuotaExceededError{Quota: QuotaAlloc, Limit: int64(q.alloc)})
+						return
+					}
+				}
+			}
+		}()
+		stops = append(stops, func() { close(done) })
+	}
+
+	return func() {
+		for _, s := range stops {
+			s()
+		}
+	}
+}
+
+// exceeded converts the stack overflow error to QuotaExceededError.
+func (q *quota) exceeded(err error) error {
+	if err == nil || q.callStack <= 0 {
+		return err
+	}
+	var overflow *sobek.StackOverflowError
+	if errors.As(err, &overflow) {
+		return &QuotaExceededError{Quota: QuotaCallStack, Limit: int64(q.callStack), cause: err}
+	}
+	return err
+}
+
+// allocBytes returns the cumulative bytes allocated by the process heap.
+func allocBytes() uint64 {
+	sample := []metrics.Sample{{Name: "/gc/heap/allocs:bytes"}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
@@ -0,0 +1,89 @@
+package js
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuota(t *testing.T) {
+	t.Parallel()
+
+	t.Run("run time", func(t *testing.T) {
+		vm := NewVM(WithMaxRunTime(100 * time.Millisecond))
+
+		start := time.Now()
+		_, err := vm.RunString(context.Background(), "{while(true){}}")
+		require.Error(t, err)
+		assert.Less(t, time.Since(start), time.Millisecond*200)
+		assert.ErrorIs(t, err, ErrQuotaExceeded)
+
+		var qe *QuotaExceededError
+		require.True(t, errors.As(err, &qe))
+		assert.Equal(t, QuotaRunTime, qe.Quota)
+		assert.Equal(t, int64(100*time.Millisecond), qe.Limit)
+
+		value, err := vm.RunString(context.Background(), "1 + 1")
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), value.ToInteger())
+	})
+
+	t.Run("call stack", func(t *testing.T) {
+		vm := NewVM(WithMaxCallStackSize(64))
+
+		_, err := vm.RunString(context.Background(), "(function f() { f() })()")
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ErrQuotaExceeded)
+
+		var qe *QuotaExceededError
+		require.True(t, errors.As(err, &qe))
+		assert.Equal(t, QuotaCallStack, qe.Quota)
+		assert.Equal(t, int64(64), qe.Limit)
+	})
+
+	t.Run("alloc", func(t *testing.T) {
+		vm := NewVM(WithMaxAllocBytes(1 << 20))
+
+		_, err := vm.RunString(context.Background(), "{const a = []; while(true){ a.push({}) }}")
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ErrQuotaExceeded)
+
+		var qe *QuotaExceededError
+		require.True(t, errors.As(err, &qe))
+		assert.Equal(t, QuotaAlloc, qe.Quota)
+	})
+
+	t.Run("alloc concurrent", func(t *testing.T) {
+		heavy := NewVM(WithMaxAllocBytes(1 << 20))
+		waiting := NewVM(WithMaxAllocBytes(1 << 20))
+
+		done := make(chan error, 1)
+		go func() {
+			done <- waiting.Run(context.Background(), func() error {
+				enqueue := EnqueueJob(waiting.Runtime())
+				go func() {
+					time.Sleep(300 * time.Millisecond)
+					enqueue(func() error { return nil })
+				}()
+				return nil
+			})
+		}()
+
+		_, err := heavy.RunString(context.Background(), "{const a = []; while(true){ a.push({}) }}")
+		assert.ErrorIs(t, err, ErrQuotaExceeded)
+		// the waiting VM is not charged for the allocation of the heavy VM
+		assert.NoError(t, <-done)
+	})
+
+	t.Run("not exceeded", func(t *testing.T) {
+		vm := NewVM(WithMaxRunTime(time.Second), WithMaxCallStackSize(64))
+
+		value, err := vm.RunString(context.Background(), "(function f(n) { return n > 0 ? f(n - 1) : 'done' })(10)")
+		require.NoError(t, err)
+		assert.Equal(t, "done", value.String())
+	})
+}
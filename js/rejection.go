@@ -0,0 +1,133 @@
+package js
+
+import (
+	"log/slog"
+	"slices"
+
+	"github.com/grafana/sobek"
+)
+
+// WithStrictRejections makes VM.Run return the unhandled promise rejections
+// as UnhandledRejectionError, like the Node.js --unhandled-rejections=strict.
+// The rejections prevented by the unhandledrejection listeners are ignored.
+func WithStrictRejections() Option {
+	return func(vm *vmImpl) { vm.rejections.strict = true }
+}
+
+// UnhandledRejectionError the promise rejection which has no handler
+// after the job which rejected it finished.
+type UnhandledRejectionError struct {
+	// Reason the Error of the rejection reason
+	Reason *Error
+}
+
+func (e *UnhandledRejectionError) Error() string { return "Uncaught (in promise) " + e.Reason.Error() }
+
+func (e *UnhandledRejectionError) Unwrap() error { return e.Reason }
+
+// rejectionTracker tracks the promises rejected without a handler, only used on the EventLoop goroutine.
+// https://html.spec.whatwg.org/multipage/webappapis.html#unhandled-promise-rejections
+type rejectionTracker struct {
+	strict    bool
+	pending   []*sobek.Promise            // rejected without a handler, not yet notified
+	notified  map[*sobek.Promise]struct{} // notified as unhandled, waiting for a late handler
+	scheduled bool                        // the notify job is enqueued
+}
+
+// track implements the sobek.PromiseRejectionTracker.
+func (vm *vmImpl) track(p *sobek.Promise, op sobek.PromiseRejectionOperation) {
+	r := &vm.rejections
+	switch op {
+	case sobek.PromiseRejectionReject:
+		r.pending = append(r.pending, p)
+		if !r.scheduled {
+			r.scheduled = true
+			vm.eventloop.EnqueueJob()(vm.notifyRejections)
+		}
+	case sobek.PromiseRejectionHandle:
+		if i := slices.Index(r.pending, p); i >= 0 {
+			r.pending = slices.Delete(r.pending, i, i+1)
+			return
+		}
+		if _, ok := r.notified[p]; ok {
+			delete(r.notified, p)
+			vm.eventloop.EnqueueJob()(func() error {
+				_, err := vm.dispatchRejection("rejectionhandled", p)
+				return err
+			})
+		}
+	}
+}
+
+// notifyRejections dispatches the unhandledrejection event for the pending promises,
+// the rejections not prevented are logged, or returned as errors in strict mode.
+func (vm *vmImpl) notifyRejections() error {
+	r := &vm.rejections
+	r.scheduled = false
+	pending := r.pending
+	r.pending = nil
+
+	var errs joinError
+	for _, p := range pending {
+		if r.notified == nil {
+			r.notified = make(map[*sobek.Promise]struct{})
+		}
+		r.notified[p] = struct{}{}
+
+		prevented, err := vm.dispatchRejection("unhandledrejection", p)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if prevented {
+			continue
+		}
+		rejection := &UnhandledRejectionError{Reason: NewError(vm.runtime, p.Result())}
+		if r.strict {
+			errs = append(errs, rejection)
+		} else {
+			Logger(vm.ctx).Error(rejection.Error(), slog.String("source", "unhandledrejection"))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// dispatchRejection dispatches the PromiseRejectionEvent to the global scope,
+// returns true if the event default is prevented.
+func (vm *vmImpl) dispatchRejection(typ string, p *sobek.Promise) (bool, error) {
+	rt := vm.runtime
+	global := rt.GlobalObject()
+	ctor, ok := sobek.AssertConstructor(rt.Get("PromiseRejectionEvent"))
+	if !ok {
+		return false, nil
+	}
+	init := rt.NewObject()
+	_ = init.Set("promise", p)
+	_ = init.Set("reason", p.Result())
+	_ = init.Set("cancelable", true)
+	event, err := ctor(nil, rt.ToValue(typ), init)
+	if err != nil {
+		return false, err
+	}
+	if dispatch, ok := sobek.AssertFunction(global.Get("dispatchEvent")); ok {
+		if _, err = dispatch(global, event); err != nil {
+			return false, err
+		}
+	}
+	if handler, ok := sobek.AssertFunction(global.Get("on" + typ)); ok {
+		if _, err = handler(global, event); err != nil {
+			return false, err
+		}
+	}
+	return event.Get("defaultPrevented").ToBoolean(), nil
+}
+
+// resetRejections forgets the tracked promises of the previous run.
+func (vm *vmImpl) resetRejections() {
+	vm.rejections.pending = nil
+	vm.rejections.notified = nil
+	vm.rejections.scheduled = false
+}
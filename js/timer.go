@@ -0,0 +1,270 @@
+package js
+
+import (
+	"container/heap"
+	"time"
+)
+
+// Timer is a timer scheduled on the EventLoop. All timers of an EventLoop share
+// one min-heap and one wakeup goroutine, the callback runs on the EventLoop goroutine.
+// Timers with the same deadline fire in the order they were scheduled.
+// The methods are safe for concurrent use.
+type Timer struct {
+	loop     *EventLoop
+	callback func() error
+	delay    time.Duration
+	repeat   bool
+	when     time.Time
+	seq      uint64 // the schedule order, breaks the ties of the same deadline
+	index    int    // the index in the heap, -1 if not scheduled
+	gen      uint64 // increased when stopped or reset, the queued callback of the previous generation is dropped
+	unref    bool
+}
+
+// AddTimer schedules the callback to run after the delay, if repeat is true the
+// callback runs at every delay until the Timer stopped, the repeat delay less
+// than 1ms is set to 1ms. The Timer keeps the EventLoop alive while scheduled,
+// unless Unref is called.
+func (e *EventLoop) AddTimer(delay time.Duration, repeat bool, callback func() error) *Timer {
+	t := &Timer{
+		loop:     e,
+		callback: callback,
+		delay:    delay,
+		repeat:   repeat,
+		index:    -1,
+	}
+	e.cond.L.Lock()
+	defer e.cond.L.Unlock()
+	e.schedule(t)
+	return t
+}
+
+// Stop stops the Timer, the pending callback will not run.
+// It returns false if the Timer has already fired or been stopped.
+func (t *Timer) Stop() bool {
+	e := t.loop
+	e.cond.L.Lock()
+	defer e.cond.L.Unlock()
+	t.gen++
+	if t.index < 0 {
+		return false
+	}
+	e.remove(t)
+	e.cond.Signal()
+	return true
+}
+
+// Reset reschedules the Timer to run after the delay since now,
+// a fired or stopped Timer is scheduled again.
+func (t *Timer) Reset(delay time.Duration) {
+	e := t.loop
+	e.cond.L.Lock()
+	defer e.cond.L.Unlock()
+	t.gen++
+	if t.index >= 0 {
+		e.remove(t)
+	}
+	t.delay = delay
+	e.schedule(t)
+}
+
+// Ref makes the Timer keep the EventLoop alive while scheduled.
+func (t *Timer) Ref() {
+	e := t.loop
+	e.cond.L.Lock()
+	defer e.cond.L.Unlock()
+	if t.unref && t.index >= 0 {
+		e.refTimers++
+	}
+	t.unref = false
+}
+
+// Unref allows the EventLoop to exit while the Timer is still scheduled.
+func (t *Timer) Unref() {
+	e := t.loop
+	e.cond.L.Lock()
+	defer e.cond.L.Unlock()
+	if !t.unref && t.index >= 0 {
+		e.refTimers--
+		e.cond.Signal()
+	}
+	t.unref = true
+}
+
+// HasRef returns true if the Timer keeps the EventLoop alive while scheduled.
+func (t *Timer) HasRef() bool {
+	t.loop.cond.L.Lock()
+	defer t.loop.cond.L.Unlock()
+	return !t.unref
+}
+
+// Active returns true if the Timer is scheduled.
+func (t *Timer) Active() bool {
+	t.loop.cond.L.Lock()
+	defer t.loop.cond.L.Unlock()
+	return t.index >= 0
+}
+
+// fire returns the job of the current generation, which runs the
+// callback unless the Timer is stopped or reset before the job runs.
+func (t *Timer) fire() func() error {
+	gen := t.gen
+	return func() error {
+		t.loop.cond.L.Lock()
+		current := t.gen == gen
+		t.loop.cond.L.Unlock()
+		if !current {
+			return nil
+		}
+		return t.callback()
+	}
+}
+
+// schedule pushes the Timer to the heap, must be called with the lock held.
+func (e *EventLoop) schedule(t *Timer) {
+	if t.repeat {
+		t.delay = max(t.delay, time.Millisecond)
+	}
+	e.timerSeq++
+	t.seq = e.timerSeq
+	t.when = e.clock.Now().Add(t.delay)
+	heap.Push(&e.timers, t)
+	if !t.unref {
+		e.refTimers++
+	}
+	if t.index == 0 {
+		e.wakeTimers()
+	}
+}
+
+// remove removes the Timer from the heap, must be called with the lock held.
+func (e *EventLoop) remove(t *Timer) {
+	heap.Remove(&e.timers, t.index)
+	if !t.unref {
+		e.refTimers--
+	}
+}
+
+// clearTimers removes all the timers, must be called with the lock held.
+func (e *EventLoop) clearTimers() {
+	for _, t := range e.timers {
+		t.index = -1
+		t.gen++
+	}
+	e.timers = nil
+	e.refTimers = 0
+	e.wakeTimers()
+}
+
+// wakeTimers notifies the wakeup goroutine that the earliest deadline changed,
+// the goroutine is started if not running. Must be called with the lock held.
+// The timers of a FakeClock are fired when the clock advanced, so no goroutine.
+func (e *EventLoop) wakeTimers() {
+	if _, ok := e.clock.(*FakeClock); ok {
+		return
+	}
+	if !e.timerRunning {
+		if len(e.timers) == 0 {
+			return
+		}
+		e.timerRunning = true
+		go e.runTimers()
+		return
+	}
+	select {
+	case e.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+// runTimers waits for the earliest deadline and moves the due timers
+// to the job queue, it exits when there are no timers.
+func (e *EventLoop) runTimers() {
+	wait := time.NewTimer(time.Hour)
+	defer wait.Stop()
+	for {
+		e.cond.L.Lock()
+		if len(e.timers) == 0 {
+			e.timerRunning = false
+			e.cond.L.Unlock()
+			return
+		}
+		now := e.clock.Now()
+		e.fireTimers(now)
+		var d time.Duration
+		if len(e.timers) > 0 {
+			d = e.timers[0].when.Sub(now)
+		}
+		e.cond.L.Unlock()
+
+		if d <= 0 {
+			continue
+		}
+		wait.Reset(d)
+		select {
+		case <-wait.C:
+		case <-e.wakeup:
+			wait.Stop()
+		}
+	}
+}
+
+// fireTimers moves the timers due at now to the job queue, must be called with the lock held.
+func (e *EventLoop) fireTimers(now time.Time) {
+	fired := false
+	for len(e.timers) > 0 && !e.timers[0].when.After(now) {
+		t := e.timers[0]
+		e.queue = append(e.queue, t.fire())
+		fired = true
+		if t.repeat {
+			e.timerSeq++
+			t.seq = e.timerSeq
+			t.when = t.when.Add(t.delay)
+			if _, ok := e.clock.(*FakeClock); !ok && !t.when.After(now) {
+				// skip the missed intervals of the wall clock
+				t.when = now.Add(t.delay)
+			}
+			heap.Fix(&e.timers, 0)
+		} else {
+			e.remove(t)
+		}
+	}
+	if fired {
+		e.maxQueue = max(e.maxQueue, len(e.queue))
+		e.cond.Signal()
+	}
+}
+
+// timerHeap the min-heap of timers ordered by the deadline and the schedule order.
+type timerHeap []*Timer
+
+func (h timerHeap) Len() int { return len(h) }
+
+func (h timerHeap) Less(i, j int) bool {
+	if h[i].when.Equal(h[j].when) {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].when.Before(h[j].when)
+}
+
+func (h timerHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *timerHeap) Push(x any) {
+	t := x.(*Timer)
+	t.index = len(*h)
+	*h = append(*h, t)
+}
+
+func (h *timerHeap) Pop() any {
+	old := *h
+	n := len(old)
+	t := old[n-1]
+	old[n-1] = nil
+	t.index = -1
+	*h = old[:n-1]
+	return t
+}
@@ -0,0 +1,175 @@
+package js
+
+import (
+	"container/heap"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimer(t *testing.T) {
+	t.Parallel()
+
+	t.Run("order", func(t *testing.T) {
+		loop := NewEventLoop()
+		var order []int
+		err := loop.Start(func() error {
+			for i, d := range []int{30, 10, 20, 10} {
+				loop.AddTimer(time.Duration(d)*time.Millisecond, false, func() error {
+					order = append(order, i)
+					return nil
+				})
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []int{1, 3, 2, 0}, order)
+	})
+
+	t.Run("same deadline", func(t *testing.T) {
+		var h timerHeap
+		when := time.Now()
+		for i := range 100 {
+			heap.Push(&h, &Timer{when: when, seq: uint64(i)})
+		}
+		for i := range 100 {
+			assert.Equal(t, uint64(i), heap.Pop(&h).(*Timer).seq)
+		}
+	})
+
+	t.Run("stop", func(t *testing.T) {
+		loop := NewEventLoop()
+		called := false
+		start := time.Now()
+		err := loop.Start(func() error {
+			timer := loop.AddTimer(time.Second, false, func() error { called = true; return nil })
+			assert.True(t, timer.Active())
+			assert.True(t, timer.Stop())
+			assert.False(t, timer.Stop())
+			assert.False(t, timer.Active())
+			return nil
+		})
+		require.NoError(t, err)
+		assert.False(t, called)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("stop queued", func(t *testing.T) {
+		loop := NewEventLoop()
+		called := false
+		err := loop.Start(func() error {
+			timer := loop.AddTimer(time.Millisecond, false, func() error { called = true; return nil })
+			time.Sleep(10 * time.Millisecond)
+			timer.Stop()
+			return nil
+		})
+		require.NoError(t, err)
+		assert.False(t, called)
+	})
+
+	t.Run("repeat", func(t *testing.T) {
+		loop := NewEventLoop()
+		count := 0
+		err := loop.Start(func() error {
+			var timer *Timer
+			timer = loop.AddTimer(5*time.Millisecond, true, func() error {
+				if count++; count == 3 {
+					timer.Stop()
+				}
+				return nil
+			})
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 3, count)
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		loop := NewEventLoop()
+		count := 0
+		err := loop.Start(func() error {
+			var timer *Timer
+			timer = loop.AddTimer(time.Millisecond, false, func() error {
+				if count++; count < 3 {
+					timer.Reset(time.Millisecond)
+				}
+				return nil
+			})
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 3, count)
+	})
+
+	t.Run("unref", func(t *testing.T) {
+		loop := NewEventLoop()
+		called := false
+		start := time.Now()
+		var timer *Timer
+		err := loop.Start(func() error {
+			timer = loop.AddTimer(time.Second, false, func() error { called = true; return nil })
+			timer.Unref()
+			assert.False(t, timer.HasRef())
+			return nil
+		})
+		require.NoError(t, err)
+		assert.False(t, called)
+		assert.False(t, timer.Active())
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("stop loop", func(t *testing.T) {
+		loop := NewEventLoop()
+		err := loop.Start(func() error {
+			loop.AddTimer(time.Hour, true, func() error { return nil })
+			go loop.Stop(assert.AnError)
+			return nil
+		})
+		assert.ErrorIs(t, err, assert.AnError)
+	})
+}
+
+const benchTimers = 10000
+
+// BenchmarkTimerHeap schedules the timers on the EventLoop timer heap.
+func BenchmarkTimerHeap(b *testing.B) {
+	loop := NewEventLoop()
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = loop.Start(func() error {
+			for i := range benchTimers {
+				loop.AddTimer(time.Duration(i%10)*time.Millisecond, false, func() error { return nil })
+			}
+			return nil
+		})
+	}
+}
+
+// BenchmarkTimerGoroutine schedules the timers with a goroutine and a time.Timer per timer,
+// the implementation of modules/timers before the timer heap.
+func BenchmarkTimerGoroutine(b *testing.B) {
+	loop := NewEventLoop()
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = loop.Start(func() error {
+			for i := range benchTimers {
+				enqueue := loop.EnqueueJob()
+				done := make(chan struct{})
+				timer := time.NewTimer(time.Duration(i%10) * time.Millisecond)
+				loop.Cleanup(func() { close(done) })
+				go func() {
+					defer timer.Stop()
+					select {
+					case <-timer.C:
+						enqueue(func() error { return nil })
+					case <-done:
+						enqueue(func() error { return nil })
+					}
+				}()
+			}
+			return nil
+		})
+	}
+}
@@ -14,6 +14,10 @@ func Throw(rt *sobek.Runtime, err error) {
 	if errors.As(err, &ex) { //nolint:errorlint
 		panic(ex)
 	}
+	var jsErr *Error
+	if errors.As(err, &jsErr) && jsErr.Value != nil {
+		panic(rt.ToValue(jsErr.Value))
+	}
 	panic(rt.NewGoError(err))
 }
 
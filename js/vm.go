@@ -2,11 +2,14 @@ package js
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"reflect"
 	"runtime"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/grafana/sobek"
 )
@@ -17,6 +20,7 @@ type VM interface {
 	// RunModule run the sobek.CyclicModuleRecord.
 	// To compile the module, sobek.ParseModule or CompileModule.
 	// Any additional arguments are passed to the default export function arguments.
+	// The top-level await and the returned promise are awaited.
 	RunModule(ctx context.Context, module sobek.CyclicModuleRecord, args ...any) (sobek.Value, error)
 	// RunString executes the given string
 	RunString(ctx context.Context, str string) (sobek.Value, error)
@@ -81,12 +85,38 @@ func WithRelease(fn func(VM)) Option {
 	}
 }
 
+// RunStats the statistics of a finished VM run.
+type RunStats struct {
+	// Duration the run duration, include the EventLoop jobs
+	Duration time.Duration
+	// Err the run returned error
+	Err error
+	// Panicked the run was recovered from a panic
+	Panicked bool
+	// Interrupted the run was interrupted by the context or quota
+	Interrupted bool
+	// MaxQueueLen the max length of the EventLoop job queue
+	MaxQueueLen int
+}
+
+// WithRunStats call with the statistics on VM run finish, before release.
+func WithRunStats(fn func(RunStats)) Option {
+	return func(vm *vmImpl) {
+		if prev := vm.stats; prev != nil {
+			vm.stats = func(s RunStats) { prev(s); fn(s) }
+		} else {
+			vm.stats = fn
+		}
+	}
+}
+
 // NewVM creates a new JavaScript VM
 // Initialize the EventLoop, global module, console.
 func NewVM(opts ...Option) VM {
 	rt := sobek.New()
 	rt.SetFieldNameMapper(fieldNameMapper{})
 	EnableConsole(rt, slog.String("source", "console"))
+	global := rt.GlobalObject()
 	Loader().EnableRequire(rt).EnableImportModuleDynamically(rt).InitGlobal(rt)
 
 	vm := &vmImpl{
@@ -102,6 +132,15 @@ func NewVM(opts ...Option) VM {
 	}
 
 	_ = rt.GlobalObject().SetSymbol(symbolVM, &vmself{vm})
+	rt.SetPromiseRejectionTracker(vm.track)
+
+	if len(vm.preload) > 0 {
+		vm.runPreload()
+	}
+
+	if vm.resetGlobals {
+		vm.globals = snapshotGlobals(rt, global)
+	}
 
 	return vm
 }
@@ -112,6 +151,17 @@ type (
 		runtime   *sobek.Runtime
 		eventloop *EventLoop
 		release   func()
+		stats     func(RunStats)
+		quota     quota
+
+		rejections rejectionTracker
+
+		resetGlobals bool
+		globals      *globalsSnapshot
+
+		preload        []any
+		preloadError   func(module any, err error)
+		preloadTimeout time.Duration
 	}
 
 	vmself struct{ vm *vmImpl }
@@ -123,30 +173,68 @@ func (vm *vmImpl) Runtime() *sobek.Runtime { return vm.runtime }
 // RunModule run the sobek.CyclicModuleRecord.
 // To compile the module, sobek.ParseModule or CompileModule.
 // Any additional arguments are passed to the default export function arguments.
+// The module top-level await and the promise returned by the default export
+// are awaited in the EventLoop, the fulfilled value is returned, or the
+// Error of the rejection reason.
 func (vm *vmImpl) RunModule(ctx context.Context, module sobek.CyclicModuleRecord, args ...any) (ret sobek.Value, err error) {
+	settled := false
 	err = vm.Run(ctx, func() error {
-		instance, err := ModuleInstance(vm.runtime, module)
+		promise, err := EvaluateModule(vm.runtime, module)
 		if err != nil {
 			return err
 		}
 
-		call, ok := sobek.AssertFunction(instance.GetBindingValue("default"))
-		if !ok {
-			ret = sobek.Undefined()
-			return nil
-		}
+		return vm.await(vm.runtime.ToValue(promise), func(sobek.Value) error {
+			instance := vm.runtime.GetModuleInstance(module)
+			call, ok := sobek.AssertFunction(instance.GetBindingValue("default"))
+			if !ok {
+				ret, settled = sobek.Undefined(), true
+				return nil
+			}
 
-		values := make([]sobek.Value, len(args))
-		for i, arg := range args {
-			values[i] = vm.runtime.ToValue(arg)
-		}
+			values := make([]sobek.Value, len(args))
+			for i, arg := range args {
+				values[i] = vm.runtime.ToValue(arg)
+			}
 
-		ret, err = call(sobek.Undefined(), values...)
-		return err
+			value, err := call(sobek.Undefined(), values...)
+			if err != nil {
+				return err
+			}
+			return vm.await(value, func(value sobek.Value) error {
+				ret, settled = value, true
+				return nil
+			})
+		})
 	})
+	if err == nil && !settled {
+		err = errUnsettled
+	}
 	return
 }
 
+// await calls the callback with the value, if the value is a promise the callback
+// is called in the EventLoop after the promise fulfilled, the rejection is returned
+// as Error from the EventLoop. The pending promise does not keep the
+// EventLoop alive, it is never settled if the EventLoop finished before.
+func (vm *vmImpl) await(value sobek.Value, callback func(sobek.Value) error) error {
+	if _, ok := value.Export().(*sobek.Promise); !ok {
+		return callback(value)
+	}
+	rt := vm.runtime
+	then, _ := sobek.AssertFunction(value.ToObject(rt).Get("then"))
+	_, err := then(value,
+		rt.ToValue(func(call sobek.FunctionCall) sobek.Value {
+			vm.eventloop.EnqueueJob()(func() error { return callback(call.Argument(0)) })
+			return sobek.Undefined()
+		}),
+		rt.ToValue(func(call sobek.FunctionCall) sobek.Value {
+			vm.eventloop.EnqueueJob()(func() error { return NewError(rt, call.Argument(0)) })
+			return sobek.Undefined()
+		}))
+	return err
+}
+
 // RunString executes the given string
 func (vm *vmImpl) RunString(ctx context.Context, str string) (ret sobek.Value, err error) {
 	err = vm.Run(ctx, func() error {
@@ -202,30 +290,66 @@ func (vm *vmImpl) RunProgram(ctx context.Context, p *sobek.Program) (ret sobek.V
 //		fmt.Println(total)
 //	}
 func (vm *vmImpl) Run(ctx context.Context, task func() error) (err error) {
+	start := time.Now()
+	var panicked bool
+	// resets the interrupt flag.
+	vm.runtime.ClearInterrupt()
+	vm.resetRejections()
+	vm.ctx = ctx
+
+	var interrupted atomic.Bool
+	interrupt := func(err error) {
+		interrupted.Store(true)
+		// interrupt the running JavaScript.
+		vm.runtime.Interrupt(err)
+		// stop the event loop.
+		vm.eventloop.Stop(err)
+	}
+	stop := context.AfterFunc(ctx, func() { interrupt(ctx.Err()) })
+	stopQuota := vm.quota.watch(vm.eventloop.busy.Load, interrupt)
+
 	defer func() {
+		stop()
+		stopQuota()
 		if x := recover(); x != nil {
-			if e, ok := x.(error); ok {
+			switch e := x.(type) {
+			case *sobek.Exception:
+				// thrown by js.Throw outside the JavaScript call
 				err = e
-			} else {
-				err = fmt.Errorf(`%s`, x)
+			case sobek.Value:
+				// thrown by panic(rt.NewTypeError()), converted to the exception
+				err = vm.runtime.Try(func() { panic(e) })
+			default:
+				panicked = true
+				if e, ok := x.(error); ok {
+					err = &Error{Name: "Panic", Message: e.Error(), Cause: e}
+				} else {
+					err = &Error{Name: "Panic", Message: fmt.Sprint(x)}
+				}
+				stack := stack()
+				Logger(ctx).Error(err.Error()+"\n"+stack, slog.String("stack", stack))
 			}
-			stack := stack()
-			Logger(ctx).Error(err.Error()+"\n"+stack, slog.String("stack", stack))
+		}
+		err = vm.quota.exceeded(err)
+		err = wrapError(vm.runtime, err)
+		if vm.stats != nil {
+			// the interrupt may arrive while the EventLoop is waiting,
+			// then the error is not the sobek.InterruptedError
+			var interruptedError *sobek.InterruptedError
+			vm.stats(RunStats{
+				Duration:    time.Since(start),
+				Err:         err,
+				Panicked:    panicked,
+				Interrupted: err != nil && (interrupted.Load() || errors.As(err, &interruptedError)),
+				MaxQueueLen: vm.eventloop.MaxQueueLen(),
+			})
+		}
+		if vm.globals != nil {
+			_ = vm.runtime.Try(vm.globals.restore)
 		}
 		vm.ctx = context.Background()
 		vm.release()
 	}()
-	// resets the interrupt flag.
-	vm.runtime.ClearInterrupt()
-	vm.ctx = ctx
-
-	context.AfterFunc(ctx, func() {
-		// interrupt the running JavaScript.
-		err2 := ctx.Err()
-		vm.runtime.Interrupt(err2)
-		// stop the event loop.
-		vm.eventloop.Stop(err2)
-	})
 
 	return vm.eventloop.Start(task)
 }
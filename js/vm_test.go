@@ -50,6 +50,62 @@ func TestVM(t *testing.T) {
 		assert.Equal(t, int64(5), result.ToInteger())
 	})
 
+	t.Run("async module", func(t *testing.T) {
+		vm := NewVM()
+		rt := vm.Runtime()
+		_ = rt.Set("later", func(call sobek.FunctionCall) sobek.Value {
+			promise, resolve, _ := rt.NewPromise()
+			enqueue := EnqueueJob(rt)
+			value := call.Argument(0)
+			go func() { enqueue(func() error { return resolve(value) }) }()
+			return rt.ToValue(promise)
+		})
+
+		module, err := Loader().CompileModule("test", `
+			const base = await later(40);
+			export default async function(a) {
+				return base + await later(a);
+			}
+		`)
+		require.NoError(t, err)
+
+		result, err := vm.RunModule(context.Background(), module, 2)
+		require.NoError(t, err)
+		assert.Equal(t, int64(42), result.ToInteger())
+	})
+
+	t.Run("async module rejection", func(t *testing.T) {
+		vm := NewVM()
+		module, err := Loader().CompileModule("test", `
+			export default async function() {
+				await null;
+				throw new TypeError("async error");
+			}
+		`)
+		require.NoError(t, err)
+
+		_, err = vm.RunModule(context.Background(), module)
+		var rejection *Error
+		require.ErrorAs(t, err, &rejection)
+		assert.Equal(t, "TypeError", rejection.Name)
+		assert.ErrorContains(t, err, "TypeError: async error")
+		assert.Contains(t, rejection.Stack(), "at default (test:4:")
+
+		module, err = Loader().CompileModule("test", `await Promise.reject(new Error("top-level error"));`)
+		require.NoError(t, err)
+		_, err = vm.RunModule(context.Background(), module)
+		assert.ErrorContains(t, err, "top-level error")
+	})
+
+	t.Run("async module unsettled", func(t *testing.T) {
+		vm := NewVM()
+		module, err := Loader().CompileModule("test", `export default () => new Promise(() => {})`)
+		require.NoError(t, err)
+
+		_, err = vm.RunModule(context.Background(), module)
+		assert.ErrorIs(t, err, errUnsettled)
+	})
+
 	t.Run("context cancel", func(t *testing.T) {
 		vm := NewVM()
 		ctx, cancel := context.WithCancel(context.Background())
@@ -168,3 +224,46 @@ func TestVM(t *testing.T) {
 		assert.Equal(t, []int{3, 2, 1}, results)
 	})
 }
+
+func TestRunStats(t *testing.T) {
+	var stats RunStats
+	vm := NewVM(WithRunStats(func(s RunStats) { stats = s }))
+
+	err := vm.Run(context.Background(), func() error {
+		for range 3 {
+			EnqueueJob(vm.Runtime())(func() error { return nil })
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.NoError(t, stats.Err)
+	assert.False(t, stats.Panicked)
+	assert.False(t, stats.Interrupted)
+	assert.Equal(t, 3, stats.MaxQueueLen)
+
+	err = vm.Run(context.Background(), func() error { panic("panicked") })
+	require.Error(t, err)
+	assert.Equal(t, err, stats.Err)
+	assert.True(t, stats.Panicked)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = vm.RunString(ctx, `{while(true){}}`)
+	require.Error(t, err)
+	assert.True(t, stats.Interrupted)
+	assert.GreaterOrEqual(t, stats.Duration, 50*time.Millisecond)
+
+	// interrupted while the EventLoop is waiting
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = vm.Run(ctx, func() error {
+		enqueue := EnqueueJob(vm.Runtime())
+		go func() {
+			time.Sleep(time.Second)
+			enqueue(func() error { return nil })
+		}()
+		return nil
+	})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.True(t, stats.Interrupted)
+}
@@ -20,7 +20,7 @@ import (
 //	}
 //	fmt.Println(value.Export()) // 3
 func RunModule(ctx context.Context, module sobek.CyclicModuleRecord, args ...any) (sobek.Value, error) {
-	vm, err := GetScheduler().get()
+	vm, err := GetScheduler().Get(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -37,7 +37,7 @@ func RunModule(ctx context.Context, module sobek.CyclicModuleRecord, args ...any
 //	}
 //	fmt.Println(value.Export()) // 2
 func RunString(ctx context.Context, str string) (sobek.Value, error) {
-	vm, err := GetScheduler().get()
+	vm, err := GetScheduler().Get(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -58,7 +58,7 @@ func RunString(ctx context.Context, str string) (sobek.Value, error) {
 //	}
 //	fmt.Println(value.Export()) // 2
 func RunProgram(ctx context.Context, program *sobek.Program) (sobek.Value, error) {
-	vm, err := GetScheduler().get()
+	vm, err := GetScheduler().Get(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -77,7 +77,7 @@ func RunProgram(ctx context.Context, program *sobek.Program) (sobek.Value, error
 //		panic(err)
 //	}
 func Run(ctx context.Context, fn func(*sobek.Runtime) error) error {
-	vm, err := GetScheduler().get()
+	vm, err := GetScheduler().Get(ctx)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,149 @@
+package ski
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/shiroyk/ski/js"
+)
+
+// MetricsSink receives the Scheduler and VM telemetry.
+// The methods are called concurrently, implementations must be safe for concurrent use.
+type MetricsSink interface {
+	// VMCreated is called when the Scheduler creates a new VM.
+	VMCreated()
+	// VMDiscarded is called when the Scheduler discards a VM.
+	VMDiscarded()
+	// ObserveWait records the time spent waiting for a VM.
+	ObserveWait(time.Duration)
+	// ObserveRun records the statistics of a finished run.
+	ObserveRun(js.RunStats)
+}
+
+type noopSink struct{}
+
+func (noopSink) VMCreated()                {}
+func (noopSink) VMDiscarded()              {}
+func (noopSink) ObserveWait(time.Duration) {}
+func (noopSink) ObserveRun(js.RunStats)    {}
+
+var (
+	// durationBuckets the upper bounds in milliseconds of the duration histograms
+	durationBuckets = []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+	// queueBuckets the upper bounds of the EventLoop queue length histogram
+	queueBuckets = []float64{1, 2, 4, 8, 16, 32, 64, 128, 256, 512, 1024}
+)
+
+// ExpvarSink the MetricsSink which implements expvar.Var,
+// so it can be published with expvar.Publish.
+//
+//	sink := ski.NewExpvarSink()
+//	expvar.Publish("ski", sink)
+//	ski.SetScheduler(ski.NewScheduler(ski.SchedulerOptions{MetricsSink: sink}))
+type ExpvarSink struct {
+	created     atomic.Int64
+	discarded   atomic.Int64
+	runs        atomic.Int64
+	errored     atomic.Int64
+	panicked    atomic.Int64
+	interrupted atomic.Int64
+	wait        *histogram
+	run         *histogram
+	queue       *histogram
+}
+
+// NewExpvarSink returns a new ExpvarSink.
+func NewExpvarSink() *ExpvarSink {
+	return &ExpvarSink{
+		wait:  newHistogram(durationBuckets),
+		run:   newHistogram(durationBuckets),
+		queue: newHistogram(queueBuckets),
+	}
+}
+
+func (e *ExpvarSink) VMCreated() { e.created.Add(1) }
+
+func (e *ExpvarSink) VMDiscarded() { e.discarded.Add(1) }
+
+func (e *ExpvarSink) ObserveWait(d time.Duration) { e.wait.observe(milliseconds(d)) }
+
+func (e *ExpvarSink) ObserveRun(stats js.RunStats) {
+	e.runs.Add(1)
+	switch {
+	case stats.Panicked:
+		e.panicked.Add(1)
+	case stats.Interrupted:
+		e.interrupted.Add(1)
+	case stats.Err != nil:
+		e.errored.Add(1)
+	}
+	e.run.observe(milliseconds(stats.Duration))
+	e.queue.observe(float64(stats.MaxQueueLen))
+}
+
+// String returns the JSON value of the metrics, implements expvar.Var.
+func (e *ExpvarSink) String() string {
+	text, _ := json.Marshal(map[string]any{
+		"vmsCreated":      e.created.Load(),
+		"vmsDiscarded":    e.discarded.Load(),
+		"runs":            e.runs.Load(),
+		"runsErrored":     e.errored.Load(),
+		"runsPanicked":    e.panicked.Load(),
+		"runsInterrupted": e.interrupted.Load(),
+		"waitMs":          e.wait,
+		"runMs":           e.run,
+		"queueLength":     e.queue,
+	})
+	return string(text)
+}
+
+// histogram the non-cumulative bucketed histogram.
+type histogram struct {
+	bounds []float64
+	counts []atomic.Int64 // the last one counts the overflow
+	count  atomic.Int64
+	sum    atomic.Uint64 // the sum in float64 bits
+}
+
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{
+		bounds: bounds,
+		counts: make([]atomic.Int64, len(bounds)+1),
+	}
+}
+
+func (h *histogram) observe(v float64) {
+	i := 0
+	for i < len(h.bounds) && v > h.bounds[i] {
+		i++
+	}
+	h.counts[i].Add(1)
+	h.count.Add(1)
+	for {
+		old := h.sum.Load()
+		if h.sum.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+func (h *histogram) MarshalJSON() ([]byte, error) {
+	buckets := make(map[string]int64, len(h.counts))
+	for i := range h.counts {
+		le := "+Inf"
+		if i < len(h.bounds) {
+			le = strconv.FormatFloat(h.bounds[i], 'f', -1, 64)
+		}
+		buckets[le] = h.counts[i].Load()
+	}
+	return json.Marshal(map[string]any{
+		"count":   h.count.Load(),
+		"sum":     math.Float64frombits(h.sum.Load()),
+		"buckets": buckets,
+	})
+}
+
+func milliseconds(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
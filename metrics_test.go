@@ -0,0 +1,76 @@
+package ski
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpvarSink(t *testing.T) {
+	sink := NewExpvarSink()
+	var _ expvar.Var = sink
+
+	s := NewScheduler(SchedulerOptions{
+		MaxVMs:         2,
+		DiscardOnError: true,
+		MetricsSink:    sink,
+	})
+	defer s.Close()
+
+	vm, err := s.Get(context.Background())
+	require.NoError(t, err)
+	_, err = vm.RunString(context.Background(), `Promise.resolve().then(() => 1)`)
+	require.NoError(t, err)
+
+	vm, err = s.Get(context.Background())
+	require.NoError(t, err)
+	err = vm.Run(context.Background(), func() error { return errors.New("failed") })
+	require.Error(t, err)
+
+	vm, err = s.Get(context.Background())
+	require.NoError(t, err)
+	err = vm.Run(context.Background(), func() error { panic("panicked") })
+	require.Error(t, err)
+
+	vm, err = s.Get(context.Background())
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = vm.RunString(ctx, `{while(true){}}`)
+	require.Error(t, err)
+
+	var data struct {
+		VMsCreated      int64 `json:"vmsCreated"`
+		VMsDiscarded    int64 `json:"vmsDiscarded"`
+		Runs            int64 `json:"runs"`
+		RunsErrored     int64 `json:"runsErrored"`
+		RunsPanicked    int64 `json:"runsPanicked"`
+		RunsInterrupted int64 `json:"runsInterrupted"`
+		WaitMs          struct {
+			Count   int64            `json:"count"`
+			Buckets map[string]int64 `json:"buckets"`
+		} `json:"waitMs"`
+		RunMs struct {
+			Count int64   `json:"count"`
+			Sum   float64 `json:"sum"`
+		} `json:"runMs"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(sink.String()), &data))
+
+	assert.Equal(t, int64(3), data.VMsCreated)
+	assert.Equal(t, int64(3), data.VMsDiscarded)
+	assert.Equal(t, int64(4), data.Runs)
+	assert.Equal(t, int64(1), data.RunsErrored)
+	assert.Equal(t, int64(1), data.RunsPanicked)
+	assert.Equal(t, int64(1), data.RunsInterrupted)
+	assert.Equal(t, int64(4), data.WaitMs.Count)
+	assert.Len(t, data.WaitMs.Buckets, len(durationBuckets)+1)
+	assert.Equal(t, int64(4), data.RunMs.Count)
+	assert.GreaterOrEqual(t, data.RunMs.Sum, float64(50))
+}
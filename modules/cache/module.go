@@ -30,6 +30,22 @@ func (c *Module) Instantiate(rt *sobek.Runtime) (sobek.Value, error) {
 	}), nil
 }
 
+// Describe returns the TypeScript declaration of the module.
+func (*Module) Describe() string {
+	return `/** Returns the string of the key, undefined if not exists. */
+export function get(key: string): string | undefined;
+/** Returns the ArrayBuffer of the key, undefined if not exists. */
+export function getBytes(key: string): ArrayBuffer | undefined;
+/** Saves the string with the key, the timeout in milliseconds. */
+export function set(key: string, value: string, timeout?: number): void;
+/** Saves the bytes with the key, the timeout in milliseconds. */
+export function setBytes(key: string, value: string | ArrayBuffer | ArrayBufferView, timeout?: number): void;
+/** Removes the key. */
+export function del(key: string): void;
+const _default: { get: typeof get; getBytes: typeof getBytes; set: typeof set; setBytes: typeof setBytes; del: typeof del };
+export default _default;`
+}
+
 // Get returns string.
 func (c *Module) Get(call sobek.FunctionCall, vm *sobek.Runtime) sobek.Value {
 	if bytes, err := c.Cache.Get(js.Context(vm), call.Argument(0).String()); err == nil && bytes != nil {
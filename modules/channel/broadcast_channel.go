@@ -0,0 +1,175 @@
+package channel
+
+import (
+	"sync"
+
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js/types"
+	"github.com/shiroyk/ski/modules/clone"
+)
+
+// BroadcastChannel allows communication between the VMs of the process
+// which subscribe the channel with the same name.
+// https://developer.mozilla.org/en-US/docs/Web/API/BroadcastChannel
+type BroadcastChannel struct{}
+
+func (b *BroadcastChannel) Instantiate(rt *sobek.Runtime) (sobek.Value, error) {
+	ctor := rt.ToValue(b.constructor).ToObject(rt)
+	p := b.prototype(rt)
+	_ = p.DefineDataProperty("constructor", ctor, sobek.FLAG_FALSE, sobek.FLAG_FALSE, sobek.FLAG_FALSE)
+	_ = ctor.SetPrototype(p)
+	_ = ctor.Set("prototype", p)
+	return ctor, nil
+}
+
+func (b *BroadcastChannel) prototype(rt *sobek.Runtime) *sobek.Object {
+	p := rt.NewObject()
+	e := rt.Get("EventTarget")
+	if e == nil {
+		panic(rt.NewTypeError("EventTarget is undefined"))
+	}
+	_ = p.SetPrototype(e.ToObject(rt).Get("prototype").ToObject(rt))
+	_ = p.DefineAccessorProperty("name", rt.ToValue(b.name), nil, sobek.FLAG_FALSE, sobek.FLAG_TRUE)
+	_ = p.Set("postMessage", b.postMessage)
+	_ = p.Set("close", b.close)
+	_ = p.Set("ref", b.ref)
+	_ = p.Set("unref", b.unref)
+	_ = p.DefineAccessorProperty("onmessage", rt.ToValue(b.getOnmessage), rt.ToValue(b.setOnmessage), sobek.FLAG_FALSE, sobek.FLAG_TRUE)
+	_ = p.DefineAccessorProperty("onmessageerror", rt.ToValue(b.getOnmessageerror), rt.ToValue(b.setOnmessageerror), sobek.FLAG_FALSE, sobek.FLAG_TRUE)
+	_ = p.SetSymbol(sobek.SymToStringTag, func(sobek.FunctionCall) sobek.Value { return rt.ToValue("BroadcastChannel") })
+	return p
+}
+
+func (b *BroadcastChannel) constructor(call sobek.ConstructorCall, rt *sobek.Runtime) *sobek.Object {
+	if len(call.Arguments) < 1 {
+		panic(rt.NewTypeError("Failed to construct 'BroadcastChannel': 1 argument required, but only 0 present."))
+	}
+
+	ch := &broadcastChannel{name: call.Argument(0).String()}
+	obj := types.New(rt, "EventTarget")
+	_ = obj.SetSymbol(symBroadcastChannel, ch)
+	_ = obj.SetPrototype(call.This.Prototype())
+	ch.bind(rt, obj, ch.close)
+	ch.start()
+	subscribe(ch)
+	return obj
+}
+
+// name returns the name of the channel.
+func (*BroadcastChannel) name(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	return rt.ToValue(toBroadcastChannel(rt, call.This).name)
+}
+
+// postMessage sends a message to all the other BroadcastChannel with the same name.
+func (*BroadcastChannel) postMessage(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	this := toBroadcastChannel(rt, call.This)
+	if this.isClosed() {
+		e := rt.NewTypeError("Failed to execute 'postMessage' on 'BroadcastChannel': Channel is closed")
+		_ = e.DefineDataProperty("name", rt.ToValue("InvalidStateError"), sobek.FLAG_TRUE, sobek.FLAG_FALSE, sobek.FLAG_TRUE)
+		panic(e)
+	}
+	data, err := clone.Serialize(rt, call.Argument(0))
+	if err != nil {
+		clone.Throw(rt, err)
+	}
+	for _, ch := range subscribers(this.name) {
+		if ch != this {
+			ch.deliver(data)
+		}
+	}
+	return sobek.Undefined()
+}
+
+// close closes the channel, it no longer receives messages.
+func (*BroadcastChannel) close(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	toBroadcastChannel(rt, call.This).close()
+	return sobek.Undefined()
+}
+
+// ref the channel keeps the VM running until closed, this is the default.
+func (*BroadcastChannel) ref(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	toBroadcastChannel(rt, call.This).setRef(true)
+	return call.This
+}
+
+// unref the channel does not keep the VM running.
+func (*BroadcastChannel) unref(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	toBroadcastChannel(rt, call.This).setRef(false)
+	return call.This
+}
+
+func (*BroadcastChannel) getOnmessage(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	return toBroadcastChannel(rt, call.This).onmessage.value
+}
+
+func (*BroadcastChannel) setOnmessage(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	toBroadcastChannel(rt, call.This).onmessage.set(rt, call.This.ToObject(rt), call.Argument(0))
+	return sobek.Undefined()
+}
+
+func (*BroadcastChannel) getOnmessageerror(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	return toBroadcastChannel(rt, call.This).onmessageerror.value
+}
+
+func (*BroadcastChannel) setOnmessageerror(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	toBroadcastChannel(rt, call.This).onmessageerror.set(rt, call.This.ToObject(rt), call.Argument(0))
+	return sobek.Undefined()
+}
+
+var symBroadcastChannel = sobek.NewSymbol("Symbol.BroadcastChannel")
+
+func toBroadcastChannel(rt *sobek.Runtime, value sobek.Value) *broadcastChannel {
+	if o, ok := value.(*sobek.Object); ok {
+		if v := o.GetSymbol(symBroadcastChannel); v != nil {
+			return v.Export().(*broadcastChannel)
+		}
+	}
+	panic(rt.NewTypeError(`Value of "this" must be of type BroadcastChannel`))
+}
+
+type broadcastChannel struct {
+	endpoint
+	name string
+}
+
+func (b *broadcastChannel) close() {
+	unsubscribe(b)
+	b.shutdown(false)
+}
+
+// channels the subscribed BroadcastChannel by name.
+var channels = struct {
+	sync.RWMutex
+	m map[string]map[*broadcastChannel]struct{}
+}{m: make(map[string]map[*broadcastChannel]struct{})}
+
+func subscribe(ch *broadcastChannel) {
+	channels.Lock()
+	defer channels.Unlock()
+	subs, ok := channels.m[ch.name]
+	if !ok {
+		subs = make(map[*broadcastChannel]struct{})
+		channels.m[ch.name] = subs
+	}
+	subs[ch] = struct{}{}
+}
+
+func unsubscribe(ch *broadcastChannel) {
+	channels.Lock()
+	defer channels.Unlock()
+	subs := channels.m[ch.name]
+	delete(subs, ch)
+	if len(subs) == 0 {
+		delete(channels.m, ch.name)
+	}
+}
+
+func subscribers(name string) []*broadcastChannel {
+	channels.RLock()
+	defer channels.RUnlock()
+	subs := make([]*broadcastChannel, 0, len(channels.m[name]))
+	for ch := range channels.m[name] {
+		subs = append(subs, ch)
+	}
+	return subs
+}
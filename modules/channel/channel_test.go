@@ -0,0 +1,230 @@
+package channel
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shiroyk/ski/js/modulestest"
+	_ "github.com/shiroyk/ski/modules/timers"
+	_ "github.com/shiroyk/ski/modules/worker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageChannel(t *testing.T) {
+	t.Parallel()
+	vm := modulestest.New(t)
+	ctx := context.Background()
+
+	t.Run("postMessage", func(t *testing.T) {
+		result, err := vm.RunModule(ctx, `
+			export default () => new Promise((resolve) => {
+				const { port1, port2 } = new MessageChannel();
+				assert.true(port1 instanceof MessagePort);
+				assert.true(port1 instanceof EventTarget);
+				const received = [];
+				port1.onmessage = (e) => {
+					assert.true(e instanceof MessageEvent);
+					received.push(e.data);
+					if (received.length === 2) {
+						port1.close();
+						resolve(received);
+					}
+				};
+				port2.postMessage({ n: 1 });
+				port2.postMessage(new Map([["n", 2]]));
+			});
+		`)
+		require.NoError(t, err)
+		obj := modulestest.PromiseResult(result).ToObject(vm.Runtime())
+		assert.EqualValues(t, 1, obj.Get("0").ToObject(vm.Runtime()).Get("n").ToInteger())
+		assert.Equal(t, "Map", obj.Get("1").ToObject(vm.Runtime()).ClassName())
+	})
+
+	t.Run("start", func(t *testing.T) {
+		result, err := vm.RunModule(ctx, `
+			export default () => new Promise((resolve) => {
+				const { port1, port2 } = new MessageChannel();
+				port2.postMessage("queued");
+				port1.addEventListener("message", (e) => {
+					port1.close();
+					resolve(e.data);
+				});
+				port1.start();
+			});
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, "queued", modulestest.PromiseResult(result).String())
+	})
+
+	t.Run("close event", func(t *testing.T) {
+		result, err := vm.RunModule(ctx, `
+			export default () => new Promise((resolve) => {
+				const { port1, port2 } = new MessageChannel();
+				port1.onmessage = () => {};
+				port1.addEventListener("close", () => resolve("closed"));
+				port2.close();
+			});
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, "closed", modulestest.PromiseResult(result).String())
+	})
+
+	t.Run("listener order", func(t *testing.T) {
+		result, err := vm.RunModule(ctx, `
+			export default () => new Promise((resolve) => {
+				const { port1, port2 } = new MessageChannel();
+				const calls = [];
+				port1.addEventListener("message", () => calls.push("first"));
+				port1.onmessage = function () { calls.push("onmessage:" + (this === port1)) };
+				port1.addEventListener("message", () => {
+					port1.close();
+					resolve(calls.concat("last").join(","));
+				});
+				port2.postMessage(null);
+			});
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, "first,onmessage:true,last", modulestest.PromiseResult(result).String())
+	})
+
+	t.Run("unref", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		result, err := vm.RunModule(ctx, `
+			export default () => {
+				const { port1 } = new MessageChannel();
+				port1.onmessage = () => {};
+				const before = port1.hasRef();
+				port1.unref();
+				return [before, port1.hasRef()];
+			}
+		`)
+		require.NoError(t, err)
+		obj := result.ToObject(vm.Runtime())
+		assert.True(t, obj.Get("0").ToBoolean())
+		assert.False(t, obj.Get("1").ToBoolean())
+	})
+
+	t.Run("transfer to worker", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "worker.js")
+		require.NoError(t, os.WriteFile(path, []byte(`
+			onmessage = (e) => {
+				e.data.port.postMessage("hello from worker");
+				close();
+			};
+		`), 0o600))
+		result, err := vm.RunModule(ctx, `
+			export default (url) => new Promise((resolve) => {
+				const { port1, port2 } = new MessageChannel();
+				port1.onmessage = (e) => {
+					port1.close();
+					resolve(e.data);
+				};
+				const worker = new Worker(url);
+				worker.postMessage({ port: port2 }, [port2]);
+			});
+		`, "file://"+filepath.ToSlash(path))
+		require.NoError(t, err)
+		assert.Equal(t, "hello from worker", modulestest.PromiseResult(result).String())
+	})
+
+	t.Run("not transferred", func(t *testing.T) {
+		_, err := vm.RunModule(ctx, `
+			const { port1, port2 } = new MessageChannel();
+			try {
+				port1.postMessage(port2);
+				assert.true(false, "should throw");
+			} catch (e) {
+				assert.equal(e.name, "DataCloneError");
+			}
+			try {
+				port1.postMessage(null, [port1]);
+				assert.true(false, "should throw");
+			} catch (e) {
+				assert.equal(e.name, "DataCloneError");
+			}
+			port1.close();
+		`)
+		assert.NoError(t, err)
+	})
+}
+
+func TestBroadcastChannel(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	const receivers = 3
+	var (
+		ready sync.WaitGroup
+		done  sync.WaitGroup
+	)
+	ready.Add(receivers)
+	done.Add(receivers)
+	results := make([]string, receivers)
+	for i := range receivers {
+		go func() {
+			defer done.Done()
+			vm := modulestest.New(t)
+			result, err := vm.RunModule(ctx, `
+				export default (ready) => new Promise((resolve) => {
+					const channel = new BroadcastChannel("config");
+					assert.equal(channel.name, "config");
+					channel.onmessage = (e) => {
+						channel.close();
+						resolve(e.data.version);
+					};
+					ready();
+				});
+			`, func() { ready.Done() })
+			if assert.NoError(t, err) {
+				results[i] = modulestest.PromiseResult(result).String()
+			}
+		}()
+	}
+
+	ready.Wait()
+	vm := modulestest.New(t)
+	_, err := vm.RunModule(ctx, `
+		const channel = new BroadcastChannel("config");
+		channel.postMessage({ version: "v2" });
+		channel.close();
+		try {
+			channel.postMessage("closed");
+			assert.true(false, "should throw");
+		} catch (e) {
+			assert.equal(e.name, "InvalidStateError");
+		}
+	`)
+	require.NoError(t, err)
+	done.Wait()
+	assert.Equal(t, []string{"v2", "v2", "v2"}, results)
+
+	t.Run("between runs", func(t *testing.T) {
+		pooled, sender := modulestest.New(t), modulestest.New(t)
+		_, err := pooled.RunModule(ctx, `
+			const channel = new BroadcastChannel("reload");
+			channel.unref();
+			channel.onmessage = (e) => { globalThis.version = e.data };
+			globalThis.version = "v1";
+		`)
+		require.NoError(t, err)
+
+		// the unreferenced channel outlives the run
+		_, err = sender.RunModule(ctx, `
+			const channel = new BroadcastChannel("reload");
+			channel.postMessage("v2");
+			channel.close();
+		`)
+		require.NoError(t, err)
+
+		result, err := pooled.RunModule(ctx, `export default () => globalThis.version`)
+		require.NoError(t, err)
+		assert.Equal(t, "v2", result.String())
+	})
+}
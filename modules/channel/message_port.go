@@ -0,0 +1,263 @@
+package channel
+
+import (
+	"errors"
+
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js/types"
+	"github.com/shiroyk/ski/modules/clone"
+)
+
+// MessageChannel creates a new message channel and sends data through its two MessagePort properties.
+// https://developer.mozilla.org/en-US/docs/Web/API/MessageChannel
+type MessageChannel struct{}
+
+func (m *MessageChannel) Instantiate(rt *sobek.Runtime) (sobek.Value, error) {
+	ctor := rt.ToValue(m.constructor).ToObject(rt)
+	p := m.prototype(rt)
+	_ = p.DefineDataProperty("constructor", ctor, sobek.FLAG_FALSE, sobek.FLAG_FALSE, sobek.FLAG_FALSE)
+	_ = ctor.SetPrototype(p)
+	_ = ctor.Set("prototype", p)
+	return ctor, nil
+}
+
+func (m *MessageChannel) prototype(rt *sobek.Runtime) *sobek.Object {
+	p := rt.NewObject()
+	_ = p.DefineAccessorProperty("port1", rt.ToValue(m.port1), nil, sobek.FLAG_FALSE, sobek.FLAG_TRUE)
+	_ = p.DefineAccessorProperty("port2", rt.ToValue(m.port2), nil, sobek.FLAG_FALSE, sobek.FLAG_TRUE)
+	_ = p.SetSymbol(sobek.SymToStringTag, func(sobek.FunctionCall) sobek.Value { return rt.ToValue("MessageChannel") })
+	return p
+}
+
+func (m *MessageChannel) constructor(call sobek.ConstructorCall, rt *sobek.Runtime) *sobek.Object {
+	port1, port2 := new(port), new(port)
+	port1.other, port2.other = port2, port1
+
+	obj := rt.NewObject()
+	_ = obj.SetSymbol(symMessageChannel, [2]*sobek.Object{newPort(rt, port1), newPort(rt, port2)})
+	_ = obj.SetPrototype(call.This.Prototype())
+	return obj
+}
+
+func (*MessageChannel) port1(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	return toMessageChannel(rt, call.This)[0]
+}
+
+func (*MessageChannel) port2(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	return toMessageChannel(rt, call.This)[1]
+}
+
+var (
+	symMessageChannel = sobek.NewSymbol("Symbol.MessageChannel")
+	symMessagePort    = sobek.NewSymbol("Symbol.MessagePort")
+)
+
+func toMessageChannel(rt *sobek.Runtime, value sobek.Value) [2]*sobek.Object {
+	if o, ok := value.(*sobek.Object); ok {
+		if v := o.GetSymbol(symMessageChannel); v != nil {
+			return v.Export().([2]*sobek.Object)
+		}
+	}
+	panic(rt.NewTypeError(`Value of "this" must be of type MessageChannel`))
+}
+
+// MessagePort represents one of the two ports of a MessageChannel,
+// it can be transferred to other VMs by postMessage.
+// https://developer.mozilla.org/en-US/docs/Web/API/MessagePort
+type MessagePort struct{}
+
+func (m *MessagePort) Instantiate(rt *sobek.Runtime) (sobek.Value, error) {
+	ctor := rt.ToValue(m.constructor).ToObject(rt)
+	p := m.prototype(rt)
+	_ = p.DefineDataProperty("constructor", ctor, sobek.FLAG_FALSE, sobek.FLAG_FALSE, sobek.FLAG_FALSE)
+	_ = ctor.SetPrototype(p)
+	_ = ctor.Set("prototype", p)
+	return ctor, nil
+}
+
+func (m *MessagePort) prototype(rt *sobek.Runtime) *sobek.Object {
+	p := rt.NewObject()
+	e := rt.Get("EventTarget")
+	if e == nil {
+		panic(rt.NewTypeError("EventTarget is undefined"))
+	}
+	_ = p.SetPrototype(e.ToObject(rt).Get("prototype").ToObject(rt))
+	_ = p.Set("postMessage", m.postMessage)
+	_ = p.Set("start", m.start)
+	_ = p.Set("close", m.close)
+	_ = p.Set("ref", m.ref)
+	_ = p.Set("unref", m.unref)
+	_ = p.Set("hasRef", m.hasRef)
+	_ = p.DefineAccessorProperty("onmessage", rt.ToValue(m.getOnmessage), rt.ToValue(m.setOnmessage), sobek.FLAG_FALSE, sobek.FLAG_TRUE)
+	_ = p.DefineAccessorProperty("onmessageerror", rt.ToValue(m.getOnmessageerror), rt.ToValue(m.setOnmessageerror), sobek.FLAG_FALSE, sobek.FLAG_TRUE)
+	_ = p.SetSymbol(sobek.SymToStringTag, func(sobek.FunctionCall) sobek.Value { return rt.ToValue("MessagePort") })
+	return p
+}
+
+func (*MessagePort) constructor(_ sobek.ConstructorCall, rt *sobek.Runtime) *sobek.Object {
+	panic(rt.NewTypeError("Illegal constructor"))
+}
+
+// postMessage sends a message to the entangled port.
+func (*MessagePort) postMessage(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	this := toPort(rt, call.This)
+	transfer := clone.Transfer(rt, call.Argument(1))
+	for _, t := range transfer {
+		if o, ok := t.(*sobek.Object); ok {
+			if v := o.GetSymbol(symMessagePort); v != nil && v.Export() == this {
+				clone.Throw(rt, &clone.DataCloneError{Message: "MessagePort could not transfer itself"})
+			}
+		}
+	}
+	data, err := clone.Serialize(rt, call.Argument(0), transfer...)
+	if err != nil {
+		clone.Throw(rt, err)
+	}
+	this.postMessage(data)
+	return sobek.Undefined()
+}
+
+// start starts the sending of messages queued on the port.
+func (*MessagePort) start(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	toPort(rt, call.This).start()
+	return sobek.Undefined()
+}
+
+// close disconnects the port, the entangled port is also closed.
+func (*MessagePort) close(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	toPort(rt, call.This).close()
+	return sobek.Undefined()
+}
+
+// ref the started port keeps the VM running, this is the default.
+func (*MessagePort) ref(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	toPort(rt, call.This).setRef(true)
+	return call.This
+}
+
+// unref the started port does not keep the VM running.
+func (*MessagePort) unref(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	toPort(rt, call.This).setRef(false)
+	return call.This
+}
+
+// hasRef returns true if the port keeps the VM running.
+func (*MessagePort) hasRef(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	return rt.ToValue(toPort(rt, call.This).hasRef())
+}
+
+func (*MessagePort) getOnmessage(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	return toPort(rt, call.This).onmessage.value
+}
+
+// setOnmessage sets the message handler and starts the port.
+func (*MessagePort) setOnmessage(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	this := toPort(rt, call.This)
+	this.onmessage.set(rt, call.This.ToObject(rt), call.Argument(0))
+	this.start()
+	return sobek.Undefined()
+}
+
+func (*MessagePort) getOnmessageerror(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	return toPort(rt, call.This).onmessageerror.value
+}
+
+func (*MessagePort) setOnmessageerror(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	toPort(rt, call.This).onmessageerror.set(rt, call.This.ToObject(rt), call.Argument(0))
+	return sobek.Undefined()
+}
+
+func toPort(rt *sobek.Runtime, value sobek.Value) *port {
+	if o, ok := value.(*sobek.Object); ok {
+		if v := o.GetSymbol(symMessagePort); v != nil {
+			return v.Export().(*port)
+		}
+	}
+	panic(rt.NewTypeError(`Value of "this" must be of type MessagePort`))
+}
+
+// newPort returns the MessagePort object of the port bound to the runtime.
+func newPort(rt *sobek.Runtime, p *port) *sobek.Object {
+	ctor := rt.Get("MessagePort")
+	if ctor == nil {
+		panic(rt.NewTypeError("MessagePort is not defined"))
+	}
+	obj := types.New(rt, "EventTarget")
+	_ = obj.SetPrototype(ctor.ToObject(rt).Get("prototype").ToObject(rt))
+	_ = obj.SetSymbol(symMessagePort, p)
+	_ = obj.SetSymbol(clone.SymTransferable, p)
+	p.bind(rt, obj, p.close)
+	return obj
+}
+
+// port the MessagePort implementation, implements clone.Transferable.
+type port struct {
+	endpoint
+	other *port // the entangled port, guarded by the endpoint lock
+}
+
+func (p *port) postMessage(data any) {
+	p.mu.Lock()
+	other := p.other
+	p.mu.Unlock()
+	if other != nil {
+		other.deliver(data)
+	}
+}
+
+// close disentangles the ports, both ports receive the close event.
+// The entangled port is closed after the messages already enqueued are dispatched.
+func (p *port) close() {
+	p.mu.Lock()
+	other := p.other
+	p.other = nil
+	p.mu.Unlock()
+	p.shutdown(true)
+	if other == nil {
+		return
+	}
+	other.mu.Lock()
+	other.other = nil
+	loop := other.loop
+	other.mu.Unlock()
+	if loop == nil {
+		other.shutdown(true)
+		return
+	}
+	loop.EnqueueJob()(func() error {
+		other.shutdown(true)
+		return nil
+	})
+}
+
+// Transferable returns the error if the port is closed or already transferred.
+func (p *port) Transferable() error {
+	p.mu.Lock()
+	closed, bound := p.closed, p.loop != nil
+	p.mu.Unlock()
+	if closed || !bound {
+		return &clone.DataCloneError{Message: "MessagePort is detached"}
+	}
+	return nil
+}
+
+// Transfer detaches the port from the current runtime, the messages
+// are queued until the port is received and started.
+func (p *port) Transfer() (clone.Receiver, error) {
+	if err := p.Transferable(); err != nil {
+		return nil, err
+	}
+	p.unbind()
+	return p, nil
+}
+
+// Receive binds the port to the target runtime.
+func (p *port) Receive(rt *sobek.Runtime) (sobek.Value, error) {
+	p.mu.Lock()
+	bound := p.loop != nil
+	p.mu.Unlock()
+	if bound {
+		return nil, errors.New("MessagePort is already received")
+	}
+	return newPort(rt, p), nil
+}
@@ -0,0 +1,253 @@
+// Package channel the MessageChannel and BroadcastChannel implementation,
+// the messages are delivered across the VMs of the process.
+package channel
+
+import (
+	"sync"
+
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+	"github.com/shiroyk/ski/js/types"
+	"github.com/shiroyk/ski/modules"
+	"github.com/shiroyk/ski/modules/clone"
+	_ "github.com/shiroyk/ski/modules/dom"
+)
+
+func init() {
+	modules.Register("channel", modules.Global{
+		"MessageChannel":   new(MessageChannel),
+		"MessagePort":      new(MessagePort),
+		"BroadcastChannel": new(BroadcastChannel),
+	})
+}
+
+// endpoint the message receiver bound to a runtime.
+// The messages are dispatched on the runtime goroutine through the EventLoop,
+// the EventLoop is kept alive while the endpoint is active and referenced.
+// The endpoint outlives the VM run, the unreferenced one keeps receiving the
+// messages until closed, which are dispatched when the VM runs again.
+// The referenced one is closed if the run is stopped while it keeps the EventLoop alive.
+type endpoint struct {
+	// only used on the runtime goroutine
+	onmessage      *handler
+	onmessageerror *handler
+
+	mu      sync.Mutex
+	rt      *sobek.Runtime
+	this    *sobek.Object
+	loop    *js.EventLoop // nil when the endpoint is not bound to a runtime
+	close   func()
+	active  bool // dispatches the messages, otherwise queues them
+	unref   bool
+	closed  bool
+	ref     js.Enqueue
+	refs    uint64 // the count of the ref taken, identifies the current one
+	pending []any
+}
+
+// bind the endpoint to the runtime, must be called on the runtime goroutine.
+func (e *endpoint) bind(rt *sobek.Runtime, this *sobek.Object, close func()) {
+	e.onmessage, e.onmessageerror = newHandler("message"), newHandler("messageerror")
+	e.mu.Lock()
+	e.rt, e.this, e.loop, e.close = rt, this, js.GetEventLoop(rt), close
+	e.mu.Unlock()
+}
+
+// unbind detaches the endpoint from the runtime, the messages are queued until bound again.
+func (e *endpoint) unbind() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.rt, e.this, e.loop = nil, nil, nil
+	e.active = false
+	e.updateRef()
+}
+
+// updateRef holds or releases the EventLoop, must be called with the lock held.
+func (e *endpoint) updateRef() {
+	hold := e.loop != nil && e.active && !e.unref && !e.closed
+	switch {
+	case hold && e.ref == nil:
+		e.ref = e.loop.EnqueueJob()
+		e.refs++
+		refs := e.refs
+		// the EventLoop only finishes with the ref held when it is stopped
+		e.loop.Cleanup(func() { e.stopped(refs) })
+	case !hold && e.ref != nil:
+		e.ref(func() error { return nil })
+		e.ref = nil
+	}
+}
+
+// stopped closes the endpoint if the stopped EventLoop finished with the ref held.
+func (e *endpoint) stopped(refs uint64) {
+	e.mu.Lock()
+	held := e.ref != nil && e.refs == refs
+	if held {
+		e.ref = nil // the EventLoop already finished
+	}
+	close := e.close
+	e.mu.Unlock()
+	if held {
+		close()
+	}
+}
+
+func (e *endpoint) setRef(ref bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.unref = !ref
+	e.updateRef()
+}
+
+func (e *endpoint) hasRef() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.ref != nil
+}
+
+func (e *endpoint) isClosed() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.closed
+}
+
+// start dispatches the queued messages and the later ones.
+func (e *endpoint) start() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.active || e.closed || e.loop == nil {
+		return
+	}
+	e.active = true
+	e.updateRef()
+	pending := e.pending
+	e.pending = nil
+	for _, data := range pending {
+		e.enqueue(data)
+	}
+}
+
+// deliver the serialized message, can be called from any goroutine.
+func (e *endpoint) deliver(data any) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	switch {
+	case e.closed:
+	case e.loop == nil, !e.active:
+		e.pending = append(e.pending, data)
+	default:
+		e.enqueue(data)
+	}
+}
+
+// enqueue the message to the EventLoop, must be called with the lock held.
+func (e *endpoint) enqueue(data any) {
+	rt := e.rt
+	e.loop.EnqueueJob()(func() error {
+		e.mu.Lock()
+		closed, moved := e.closed, e.rt != rt
+		e.mu.Unlock()
+		switch {
+		case closed:
+			return nil
+		case moved:
+			// transferred to another runtime after enqueued
+			e.deliver(data)
+			return nil
+		}
+		value, err := clone.Deserialize(rt, data)
+		if err != nil {
+			return e.emit(rt, "messageerror", sobek.Null())
+		}
+		return e.emit(rt, "message", value)
+	})
+}
+
+// shutdown closes the endpoint and releases the EventLoop,
+// the close event is dispatched if event is true.
+func (e *endpoint) shutdown(event bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return
+	}
+	e.closed = true
+	e.pending = nil
+	if event && e.loop != nil {
+		rt := e.rt
+		e.loop.EnqueueJob()(func() error { return e.emit(rt, "close", sobek.Null()) })
+	}
+	e.updateRef()
+}
+
+// emit dispatches the MessageEvent to the event listeners, include the handler.
+func (e *endpoint) emit(rt *sobek.Runtime, typ string, data sobek.Value) error {
+	e.mu.Lock()
+	this := e.this
+	e.mu.Unlock()
+	if this == nil {
+		return nil
+	}
+
+	init := rt.NewObject()
+	_ = init.Set("data", data)
+	var event *sobek.Object
+	if ex := rt.Try(func() {
+		event = types.New(rt, "MessageEvent", rt.ToValue(typ), init)
+	}); ex != nil {
+		return ex
+	}
+	if fn, ok := sobek.AssertFunction(this.Get("dispatchEvent")); ok {
+		if _, err := fn(this, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handler the event handler property like onmessage. The handler is added
+// as a listener, so it is called in the order of the listeners registration.
+type handler struct {
+	typ      string
+	value    sobek.Value
+	listener sobek.Value // calls the current value
+}
+
+func newHandler(typ string) *handler {
+	return &handler{typ: typ, value: sobek.Null()}
+}
+
+// set the handler, the listener of the handler is added to the target.
+func (h *handler) set(rt *sobek.Runtime, target *sobek.Object, value sobek.Value) {
+	if _, ok := sobek.AssertFunction(value); !ok {
+		value = sobek.Null()
+	}
+	h.value = value
+
+	method := "addEventListener"
+	switch {
+	case sobek.IsNull(value) && h.listener != nil:
+		method = "removeEventListener"
+	case !sobek.IsNull(value) && h.listener == nil:
+		h.listener = rt.ToValue(func(call sobek.FunctionCall) sobek.Value {
+			fn, ok := sobek.AssertFunction(h.value)
+			if !ok {
+				return sobek.Undefined()
+			}
+			ret, err := fn(target, call.Arguments...)
+			if err != nil {
+				js.Throw(rt, err)
+			}
+			return ret
+		})
+	default:
+		return
+	}
+	fn, _ := sobek.AssertFunction(target.Get(method))
+	if _, err := fn(target, rt.ToValue(h.typ), h.listener); err != nil {
+		js.Throw(rt, err)
+	}
+	if method == "removeEventListener" {
+		h.listener = nil
+	}
+}
@@ -0,0 +1,266 @@
+package modules
+
+import (
+	"maps"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+	"weak"
+
+	"github.com/grafana/sobek"
+)
+
+// Constructor is implemented by the Class type which can be constructed by new,
+// the Construct initializes the zero value from the constructor arguments.
+type Constructor interface {
+	Construct(call sobek.ConstructorCall, rt *sobek.Runtime)
+}
+
+// Class builds the JavaScript class of the Go type T, the members are
+// reflected from the methods and the tagged fields of *T:
+//
+//   - func(sobek.FunctionCall, *sobek.Runtime) sobek.Value methods are the prototype methods
+//   - func(*sobek.Runtime) sobek.Value methods are the getters
+//   - SetXxx(*sobek.Runtime, sobek.Value) methods are the setters of xxx
+//   - the fields tagged `js:"name"` are the accessors, `js:"name,readonly"` has no setter
+//
+// The Go names are converted to camel case, such as HasRef to hasRef and URL to url.
+// If *T not implements the Constructor, the constructor throws the TypeError Illegal constructor.
+// The instances are the ordinary objects, the *T of an instance is kept in a side table which the
+// members check the brand of "this" by. The prototype of an instance is from new.target, so the
+// JavaScript subclasses work and can define their own properties.
+//
+//	type point struct {
+//		X int64 `js:"x"`
+//		Y int64 `js:"y"`
+//	}
+//
+//	func (p *point) Construct(call sobek.ConstructorCall, rt *sobek.Runtime) {
+//		p.X, p.Y = call.Argument(0).ToInteger(), call.Argument(1).ToInteger()
+//	}
+//
+//	func (p *point) ToString(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+//		return rt.ToValue(fmt.Sprintf("(%d, %d)", p.X, p.Y))
+//	}
+//
+//	modules.Register("point", modules.Global{"Point": &modules.Class[point]{Name: "Point"}})
+type Class[T any] struct {
+	// Name the class name, also the Symbol.toStringTag of the instances
+	Name string
+	// Statics the static methods of the class
+	Statics map[string]func(sobek.FunctionCall, *sobek.Runtime) sobek.Value
+
+	once      sync.Once
+	sym       *sobek.Symbol
+	methods   []classMethod[T]
+	accessors []*classAccessor[T]
+
+	mu        sync.Mutex
+	instances map[weak.Pointer[sobek.Object]]*T // removed when the instance is collected
+}
+
+type classMethod[T any] struct {
+	name string
+	fn   func(*T, sobek.FunctionCall, *sobek.Runtime) sobek.Value
+}
+
+type classAccessor[T any] struct {
+	name string
+	get  func(*T, *sobek.Runtime) sobek.Value
+	set  func(*T, *sobek.Runtime, sobek.Value)
+}
+
+// Instantiate returns the class constructor.
+func (c *Class[T]) Instantiate(rt *sobek.Runtime) (sobek.Value, error) {
+	c.once.Do(c.init)
+
+	proto := rt.NewObject()
+	ctor := rt.ToValue(func(call sobek.ConstructorCall) *sobek.Object {
+		return c.construct(call, rt)
+	}).(*sobek.Object)
+
+	for _, m := range c.methods {
+		fn := m.fn
+		_ = proto.Set(m.name, func(call sobek.FunctionCall) sobek.Value {
+			return fn(c.This(rt, call.This), call, rt)
+		})
+	}
+	for _, a := range c.accessors {
+		var getter, setter sobek.Value
+		if get := a.get; get != nil {
+			getter = rt.ToValue(func(call sobek.FunctionCall) sobek.Value {
+				return get(c.This(rt, call.This), rt)
+			})
+		}
+		if set := a.set; set != nil {
+			setter = rt.ToValue(func(call sobek.FunctionCall) sobek.Value {
+				set(c.This(rt, call.This), rt, call.Argument(0))
+				return sobek.Undefined()
+			})
+		}
+		_ = proto.DefineAccessorProperty(a.name, getter, setter, sobek.FLAG_FALSE, sobek.FLAG_TRUE)
+	}
+	_ = proto.DefineDataPropertySymbol(sobek.SymToStringTag, rt.ToValue(c.Name), sobek.FLAG_FALSE, sobek.FLAG_FALSE, sobek.FLAG_TRUE)
+	_ = proto.DefineDataProperty("constructor", ctor, sobek.FLAG_FALSE, sobek.FLAG_FALSE, sobek.FLAG_FALSE)
+
+	_ = ctor.DefineDataProperty("name", rt.ToValue(c.Name), sobek.FLAG_FALSE, sobek.FLAG_FALSE, sobek.FLAG_TRUE)
+	_ = ctor.Set("prototype", proto)
+	for _, name := range slices.Sorted(maps.Keys(c.Statics)) {
+		_ = ctor.Set(name, c.Statics[name])
+	}
+
+	_ = rt.GlobalObject().SetSymbol(c.sym, ctor)
+	return ctor, nil
+}
+
+// New returns the instance of the Go value, for the instances created by Go.
+func (c *Class[T]) New(rt *sobek.Runtime, value *T) *sobek.Object {
+	ctor := c.constructor(rt)
+	obj := rt.NewObject()
+	_ = obj.SetPrototype(ctor.Get("prototype").ToObject(rt))
+	c.wrap(obj, value)
+	return obj
+}
+
+// This returns the Go value of the instance, throws the TypeError if the value is not an instance.
+func (c *Class[T]) This(rt *sobek.Runtime, value sobek.Value) *T {
+	if v, ok := c.Unwrap(value); ok {
+		return v
+	}
+	panic(rt.NewTypeError(`Value of "this" must be of type ` + c.Name))
+}
+
+// Unwrap returns the Go value of the instance, false if the value is not an instance.
+func (c *Class[T]) Unwrap(value sobek.Value) (*T, bool) {
+	obj, ok := value.(*sobek.Object)
+	if !ok {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.instances[weak.Make(obj)]
+	return v, ok
+}
+
+// wrap adds the instance to the side table until it is collected.
+func (c *Class[T]) wrap(obj *sobek.Object, value *T) {
+	key := weak.Make(obj)
+	c.mu.Lock()
+	if c.instances == nil {
+		c.instances = make(map[weak.Pointer[sobek.Object]]*T)
+	}
+	c.instances[key] = value
+	c.mu.Unlock()
+	runtime.AddCleanup(obj, func(key weak.Pointer[sobek.Object]) {
+		c.mu.Lock()
+		delete(c.instances, key)
+		c.mu.Unlock()
+	}, key)
+}
+
+func (c *Class[T]) construct(call sobek.ConstructorCall, rt *sobek.Runtime) *sobek.Object {
+	value := new(T)
+	ctor, ok := any(value).(Constructor)
+	if !ok {
+		panic(rt.NewTypeError("Illegal constructor"))
+	}
+	ctor.Construct(call, rt)
+	obj := rt.NewObject()
+	_ = obj.SetPrototype(call.This.Prototype())
+	c.wrap(obj, value)
+	return obj
+}
+
+// constructor returns the class constructor of the runtime, the global class
+// is instantiated by the loader if it has not been accessed yet.
+func (c *Class[T]) constructor(rt *sobek.Runtime) *sobek.Object {
+	c.once.Do(c.init)
+	global := rt.GlobalObject()
+	if ctor, ok := global.GetSymbol(c.sym).(*sobek.Object); ok {
+		return ctor
+	}
+	// the global proxy instantiates the registered global class
+	_ = rt.Get(c.Name)
+	if ctor, ok := global.GetSymbol(c.sym).(*sobek.Object); ok {
+		return ctor
+	}
+	ctor, err := c.Instantiate(rt)
+	if err != nil {
+		panic(rt.NewGoError(err))
+	}
+	return ctor.(*sobek.Object)
+}
+
+// init reflects the members of *T.
+func (c *Class[T]) init() {
+	c.sym = sobek.NewSymbol("Symbol." + c.Name)
+
+	index := make(map[string]*classAccessor[T])
+	accessor := func(name string) *classAccessor[T] {
+		a, ok := index[name]
+		if !ok {
+			a = &classAccessor[T]{name: name}
+			index[name] = a
+			c.accessors = append(c.accessors, a)
+		}
+		return a
+	}
+
+	typ := reflect.TypeFor[*T]()
+	if elem := typ.Elem(); elem.Kind() == reflect.Struct {
+		for _, field := range reflect.VisibleFields(elem) {
+			tag := field.Tag.Get("js")
+			if !field.IsExported() || tag == "" || tag == "-" {
+				continue
+			}
+			name, opts, _ := strings.Cut(tag, ",")
+			fieldIndex := field.Index
+			a := accessor(name)
+			a.get = func(t *T, rt *sobek.Runtime) sobek.Value {
+				return rt.ToValue(reflect.ValueOf(t).Elem().FieldByIndex(fieldIndex).Interface())
+			}
+			if opts == "readonly" {
+				continue
+			}
+			a.set = func(t *T, rt *sobek.Runtime, value sobek.Value) {
+				ptr := reflect.ValueOf(t).Elem().FieldByIndex(fieldIndex).Addr().Interface()
+				if err := rt.ExportTo(value, ptr); err != nil {
+					panic(rt.NewTypeError(err.Error()))
+				}
+			}
+		}
+	}
+
+	for i := range typ.NumMethod() {
+		m := typ.Method(i)
+		switch fn := m.Func.Interface().(type) {
+		case func(*T, sobek.FunctionCall, *sobek.Runtime) sobek.Value:
+			c.methods = append(c.methods, classMethod[T]{name: camelCase(m.Name), fn: fn})
+		case func(*T, *sobek.Runtime) sobek.Value:
+			accessor(camelCase(m.Name)).get = fn
+		case func(*T, *sobek.Runtime, sobek.Value):
+			if name, ok := strings.CutPrefix(m.Name, "Set"); ok && name != "" {
+				accessor(camelCase(name)).set = fn
+			}
+		}
+	}
+}
+
+// camelCase lowers the leading upper case letters of the Go name,
+// such as HasRef to hasRef, URL to url and URLPath to urlPath.
+func camelCase(name string) string {
+	runes := []rune(name)
+	for i, r := range runes {
+		if !unicode.IsUpper(r) {
+			break
+		}
+		if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			break
+		}
+		runes[i] = unicode.ToLower(r)
+	}
+	return string(runes)
+}
@@ -0,0 +1,153 @@
+package modules
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/grafana/sobek"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testPoint struct {
+	X     int64  `js:"x"`
+	Y     int64  `js:"y"`
+	Label string `js:"label,readonly"`
+	hits  int
+}
+
+func (p *testPoint) Construct(call sobek.ConstructorCall, rt *sobek.Runtime) {
+	p.X, p.Y = call.Argument(0).ToInteger(), call.Argument(1).ToInteger()
+	p.Label = "point"
+}
+
+func (p *testPoint) ToString(_ sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	p.hits++
+	return rt.ToValue(fmt.Sprintf("(%d, %d)", p.X, p.Y))
+}
+
+func (p *testPoint) Hits(rt *sobek.Runtime) sobek.Value { return rt.ToValue(p.hits) }
+
+func (p *testPoint) SetHits(rt *sobek.Runtime, value sobek.Value) { p.hits = int(value.ToInteger()) }
+
+type testHandle struct{ id int64 }
+
+func (h *testHandle) ID(rt *sobek.Runtime) sobek.Value { return rt.ToValue(h.id) }
+
+func TestClass(t *testing.T) {
+	point := &Class[testPoint]{
+		Name: "Point",
+		Statics: map[string]func(sobek.FunctionCall, *sobek.Runtime) sobek.Value{
+			"origin": func(_ sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+				return rt.ToValue(map[string]int{"x": 0, "y": 0})
+			},
+		},
+	}
+	handle := &Class[testHandle]{Name: "Handle"}
+	Register("class", Global{"Point": point, "Handle": handle})
+	defer Remove("class")
+
+	vm := NewTestVM(t, NewLoader())
+
+	t.Run("members", func(t *testing.T) {
+		_, err := vm.RunString(`
+		{
+		const p = new Point(1, 2);
+		assert.equal(p.x, 1);
+		assert.equal(p.toString(), "(1, 2)");
+		assert.equal(p.hits, 1);
+		p.hits = 5;
+		p.x = 3;
+		assert.equal(p.toString(), "(3, 2)");
+		assert.equal(p.hits, 6);
+		p.label = "changed";
+		assert.equal(p.label, "point");
+		assert.equal(Object.prototype.toString.call(p), "[object Point]");
+		assert.equal(Point.name, "Point");
+		assert.equal(Point.origin().x, 0);
+		assert.true(p instanceof Point);
+		assert.true(Point.prototype.constructor === Point);
+		}
+		`)
+		require.NoError(t, err)
+	})
+
+	t.Run("brand check", func(t *testing.T) {
+		_, err := vm.RunString(`Point.prototype.toString.call({})`)
+		assert.ErrorContains(t, err, `Value of "this" must be of type Point`)
+		_, err = vm.RunString(`Object.getOwnPropertyDescriptor(Point.prototype, "x").get.call({})`)
+		assert.ErrorContains(t, err, `Value of "this" must be of type Point`)
+		_, err = vm.RunString(`Point.prototype.toString.call(Object.create(new Point(1, 2)))`)
+		assert.ErrorContains(t, err, `Value of "this" must be of type Point`)
+		_, err = vm.RunString(`
+		{
+		const p = new Point(1, 2);
+		assert.equal(Object.getOwnPropertySymbols(p).length, 0);
+		assert.equal(Object.getOwnPropertyNames(p).length, 0);
+		assert.equal(p.construct, undefined);
+		assert.equal(p.setHits, undefined);
+		}
+		`)
+		require.NoError(t, err)
+	})
+
+	t.Run("subclass", func(t *testing.T) {
+		_, err := vm.RunString(`
+		{
+		class Point3D extends Point {
+			w = 4;
+			constructor(x, y, z) {
+				super(x, y);
+				this.z = z;
+			}
+			toString() { return super.toString() + this.z + "D"; }
+		}
+		const p = new Point3D(1, 2, 3);
+		assert.true(p instanceof Point3D);
+		assert.true(p instanceof Point);
+		assert.equal(p.x, 1);
+		assert.equal(p.z, 3);
+		assert.equal(p.w, 4);
+		assert.equal(p.toString(), "(1, 2)3D");
+		p.extra = true;
+		assert.equal(Object.keys(p).join(), "w,z,extra");
+		assert.true(Object.getPrototypeOf(p) === Point3D.prototype);
+		}
+		`)
+		require.NoError(t, err)
+	})
+
+	t.Run("illegal constructor", func(t *testing.T) {
+		_, err := vm.RunString(`new Handle()`)
+		assert.ErrorContains(t, err, "Illegal constructor")
+	})
+
+	t.Run("new", func(t *testing.T) {
+		obj := handle.New(vm, &testHandle{id: 7})
+		require.NoError(t, vm.Set("h", obj))
+		_, err := vm.RunString(`
+		assert.equal(h.iD, undefined);
+		assert.equal(h.id, 7);
+		assert.true(h instanceof Handle);
+		`)
+		require.NoError(t, err)
+		v, ok := handle.Unwrap(obj)
+		require.True(t, ok)
+		assert.Equal(t, int64(7), v.id)
+		_, ok = point.Unwrap(obj)
+		assert.False(t, ok)
+	})
+
+	t.Run("camel case", func(t *testing.T) {
+		for name, expected := range map[string]string{
+			"HasRef":  "hasRef",
+			"URL":     "url",
+			"URLPath": "urlPath",
+			"ToJSON":  "toJSON",
+			"ID":      "id",
+			"value":   "value",
+		} {
+			assert.Equal(t, expected, camelCase(name))
+		}
+	})
+}
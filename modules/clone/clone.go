@@ -0,0 +1,578 @@
+// Package clone implements the HTML structured clone algorithm.
+// https://html.spec.whatwg.org/multipage/structured-data.html
+//
+// Serialize converts a JavaScript value to the runtime independent
+// intermediate representation, which can be passed to other goroutines
+// and deserialized into any runtime with Deserialize.
+//
+// The intermediate representation is composed of the following types:
+//   - Undefined, nil (null), bool, float64, string and *big.Int for the primitives
+//   - *Date, *RegExp, *Boxed (Boolean, Number, String and BigInt objects) and *Error
+//   - *ArrayBuffer and *ArrayBufferView (TypedArray and DataView)
+//   - *Array, *Object, *Map and *Set
+//   - *Blob and *File
+//   - *Transferred for the transferred host objects, e.g. MessagePort
+//
+// The objects are pointers, so the shared and cyclic references are preserved.
+package clone
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"reflect"
+	"slices"
+
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/modules/buffer"
+)
+
+type (
+	// Undefined the undefined value.
+	Undefined struct{}
+
+	// Date the Date object, Time is the milliseconds since the epoch, NaN for an invalid date.
+	Date struct{ Time float64 }
+
+	// RegExp the RegExp object.
+	RegExp struct{ Source, Flags string }
+
+	// Boxed the primitive wrapper object, e.g. new String("foo").
+	Boxed struct{ Value any }
+
+	// Error the Error object, Name is one of the standard error constructor names.
+	Error struct {
+		Name     string
+		Message  string
+		Stack    string
+		Cause    any
+		HasCause bool
+	}
+
+	// ArrayBuffer the ArrayBuffer object.
+	ArrayBuffer struct{ Data []byte }
+
+	// ArrayBufferView the TypedArray or DataView object,
+	// Length is the element length, or the byte length of DataView.
+	ArrayBufferView struct {
+		Type       string
+		Buffer     *ArrayBuffer
+		ByteOffset int64
+		Length     int64
+	}
+
+	// Property the own enumerable property of an Object or Array.
+	Property struct {
+		Key   string
+		Value any
+	}
+
+	// Array the Array object, Properties contains the indexes and other properties.
+	Array struct {
+		Length     int64
+		Properties []Property
+	}
+
+	// Object the ordinary object.
+	Object struct{ Properties []Property }
+
+	// Map the Map object, Entries are the key value pairs.
+	Map struct{ Entries [][2]any }
+
+	// Set the Set object.
+	Set struct{ Values []any }
+
+	// Blob the Blob object.
+	Blob struct {
+		Data buffer.Reader
+		Size int64
+		Type string
+	}
+
+	// File the File object.
+	File struct {
+		Blob
+		Name         string
+		LastModified int64
+	}
+
+	// Transferred the host object in the transfer list.
+	Transferred struct{ Receiver Receiver }
+)
+
+// SymTransferable the host objects which can be put in the transfer list
+// set the Transferable implementation with this symbol.
+var SymTransferable = sobek.NewSymbol("Symbol.Transferable")
+
+// Transferable the host object which can be transferred to other runtimes.
+type Transferable interface {
+	// Transferable returns the error if the object could not be transferred,
+	// the whole transfer list is checked before any object is detached.
+	Transferable() error
+	// Transfer detaches the object from the source runtime.
+	Transfer() (Receiver, error)
+}
+
+// Receiver the transferred host object.
+type Receiver interface {
+	// Receive attaches the object to the target runtime.
+	Receive(rt *sobek.Runtime) (sobek.Value, error)
+}
+
+// DataCloneError the value could not be cloned.
+type DataCloneError struct{ Message string }
+
+func (e *DataCloneError) Error() string { return "DataCloneError: " + e.Message }
+
+// Clone returns the structured clone of the value in the same runtime.
+func Clone(rt *sobek.Runtime, value sobek.Value, transfer ...sobek.Value) (sobek.Value, error) {
+	v, err := Serialize(rt, value, transfer...)
+	if err != nil {
+		return nil, err
+	}
+	return Deserialize(rt, v)
+}
+
+// Serialize converts the value to the intermediate representation.
+// The transferred ArrayBuffer are detached after serialized, the others are copied.
+// The transferred host objects must implement Transferable, which are
+// represented by *Transferred. Nothing is detached if the value or any
+// object of the transfer list could not be transferred.
+func Serialize(rt *sobek.Runtime, value sobek.Value, transfer ...sobek.Value) (any, error) {
+	s := &serializer{
+		rt:       rt,
+		memory:   make(map[*sobek.Object]any),
+		transfer: make(map[*sobek.Object]sobek.ArrayBuffer, len(transfer)),
+	}
+
+	transferables := make(map[*Transferred]Transferable)
+	for _, t := range transfer {
+		obj, ok := t.(*sobek.Object)
+		if !ok {
+			return nil, &DataCloneError{"value could not be transferred"}
+		}
+		if _, ok = s.memory[obj]; ok {
+			return nil, &DataCloneError{"value is duplicated in the transfer list"}
+		}
+		if _, ok = s.transfer[obj]; ok {
+			return nil, &DataCloneError{"ArrayBuffer is duplicated in the transfer list"}
+		}
+		if t, ok := transferable(obj); ok {
+			if err := t.Transferable(); err != nil {
+				return nil, err
+			}
+			node := new(Transferred)
+			transferables[node] = t
+			s.memory[obj] = node
+			continue
+		}
+		if obj.ExportType() != typeArrayBuffer {
+			return nil, &DataCloneError{"value could not be transferred"}
+		}
+		ab := obj.Export().(sobek.ArrayBuffer)
+		if ab.Detached() {
+			return nil, &DataCloneError{"ArrayBuffer is already detached"}
+		}
+		s.transfer[obj] = ab
+	}
+
+	var ret any
+	if ex := rt.Try(func() { ret = s.serialize(value) }); ex != nil {
+		return nil, ex
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	for node, t := range transferables {
+		receiver, err := t.Transfer()
+		if err != nil {
+			return nil, err
+		}
+		node.Receiver = receiver
+	}
+	for _, ab := range s.transfer {
+		ab.Detach()
+	}
+	return ret, nil
+}
+
+// Deserialize converts the intermediate representation to a value of the runtime.
+// The same representation can be deserialized many times, each one returns a copy.
+func Deserialize(rt *sobek.Runtime, value any) (ret sobek.Value, err error) {
+	d := &deserializer{rt: rt, memory: make(map[any]*sobek.Object)}
+	if ex := rt.Try(func() { ret = d.deserialize(value) }); ex != nil {
+		return nil, ex
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	return ret, nil
+}
+
+var (
+	typeArrayBuffer = reflect.TypeOf(sobek.ArrayBuffer{})
+	typeObject      = reflect.TypeOf(map[string]any(nil))
+
+	typedArrayTypes = []string{
+		"Int8Array", "Uint8Array", "Uint8ClampedArray",
+		"Int16Array", "Uint16Array",
+		"Int32Array", "Uint32Array",
+		"Float32Array", "Float64Array",
+		"BigInt64Array", "BigUint64Array",
+	}
+
+	errorNames = []string{
+		"Error", "EvalError", "RangeError", "ReferenceError",
+		"SyntaxError", "TypeError", "URIError",
+	}
+)
+
+func transferable(obj *sobek.Object) (Transferable, bool) {
+	if v := obj.GetSymbol(SymTransferable); v != nil {
+		t, ok := v.Export().(Transferable)
+		return t, ok
+	}
+	return nil, false
+}
+
+type serializer struct {
+	rt       *sobek.Runtime
+	memory   map[*sobek.Object]any
+	transfer map[*sobek.Object]sobek.ArrayBuffer
+	err      error
+}
+
+// fail records the first error, the serialization continues with undefined.
+func (s *serializer) fail(format string, args ...any) any {
+	if s.err == nil {
+		s.err = &DataCloneError{fmt.Sprintf(format, args...)}
+	}
+	return Undefined{}
+}
+
+func (s *serializer) serialize(value sobek.Value) any {
+	if s.err != nil {
+		return Undefined{}
+	}
+
+	switch {
+	case value == nil, sobek.IsUndefined(value):
+		return Undefined{}
+	case sobek.IsNull(value):
+		return nil
+	case sobek.IsNumber(value):
+		return value.ToFloat()
+	case sobek.IsString(value):
+		return value.String()
+	case sobek.IsBigInt(value):
+		return value.Export().(*big.Int)
+	}
+
+	obj, ok := value.(*sobek.Object)
+	if !ok {
+		if value.ExportType().Kind() == reflect.Bool {
+			return value.ToBoolean()
+		}
+		return s.fail("%s could not be cloned", value.String())
+	}
+
+	if v, ok := s.memory[obj]; ok {
+		return v
+	}
+
+	if _, ok = sobek.AssertFunction(obj); ok {
+		return s.fail("function could not be cloned")
+	}
+
+	if _, ok = transferable(obj); ok {
+		return s.fail("%s could not be cloned without transfer", obj.String())
+	}
+
+	if r, typ, ok := buffer.GetReader(obj); ok {
+		b := Blob{Data: r, Size: obj.Get("size").ToInteger(), Type: typ}
+		if obj.ExportType() == buffer.TypeFile {
+			f := &File{Blob: b, Name: obj.Get("name").String(), LastModified: obj.Get("lastModified").ToInteger()}
+			s.memory[obj] = f
+			return f
+		}
+		s.memory[obj] = &b
+		return &b
+	}
+
+	if obj.ExportType() == typeArrayBuffer {
+		ab := obj.Export().(sobek.ArrayBuffer)
+		if ab.Detached() {
+			return s.fail("ArrayBuffer is detached")
+		}
+		data := ab.Bytes()
+		if _, ok = s.transfer[obj]; !ok {
+			data = slices.Clone(data)
+		}
+		ret := &ArrayBuffer{Data: data}
+		s.memory[obj] = ret
+		return ret
+	}
+
+	switch obj.ClassName() {
+	case "Boolean", "Number", "String", "BigInt":
+		return s.boxed(obj)
+	case "Date":
+		return s.date(obj)
+	case "RegExp":
+		ret := &RegExp{Source: obj.Get("source").String(), Flags: obj.Get("flags").String()}
+		s.memory[obj] = ret
+		return ret
+	case "Error":
+		return s.error(obj)
+	case "Map":
+		ret := new(Map)
+		s.memory[obj] = ret
+		s.rt.ForOf(obj, func(entry sobek.Value) bool {
+			kv := entry.ToObject(s.rt)
+			ret.Entries = append(ret.Entries, [2]any{s.serialize(kv.Get("0")), s.serialize(kv.Get("1"))})
+			return s.err == nil
+		})
+		return ret
+	case "Set":
+		ret := new(Set)
+		s.memory[obj] = ret
+		s.rt.ForOf(obj, func(v sobek.Value) bool {
+			ret.Values = append(ret.Values, s.serialize(v))
+			return s.err == nil
+		})
+		return ret
+	case "Array":
+		ret := &Array{Length: obj.Get("length").ToInteger()}
+		s.memory[obj] = ret
+		ret.Properties = s.properties(obj)
+		return ret
+	}
+
+	if typ, ok := s.viewType(obj); ok {
+		return s.view(obj, typ)
+	}
+
+	if obj.ClassName() != "Object" || obj.ExportType() != typeObject {
+		return s.fail("%s could not be cloned", obj.String())
+	}
+	ret := new(Object)
+	s.memory[obj] = ret
+	ret.Properties = s.properties(obj)
+	return ret
+}
+
+func (s *serializer) view(obj *sobek.Object, typ string) any {
+	ret := &ArrayBufferView{
+		Type:       typ,
+		ByteOffset: obj.Get("byteOffset").ToInteger(),
+	}
+	s.memory[obj] = ret
+	if typ == "DataView" {
+		ret.Length = obj.Get("byteLength").ToInteger()
+	} else {
+		ret.Length = obj.Get("length").ToInteger()
+	}
+	ab, ok := s.serialize(obj.Get("buffer")).(*ArrayBuffer)
+	if !ok {
+		return s.fail("%s buffer could not be cloned", typ)
+	}
+	ret.Buffer = ab
+	return ret
+}
+
+func (s *serializer) boxed(obj *sobek.Object) any {
+	valueOf, ok := sobek.AssertFunction(obj.Get("valueOf"))
+	if !ok {
+		return s.fail("%s object could not be cloned", obj.ClassName())
+	}
+	v, err := valueOf(obj)
+	if err != nil {
+		panic(err)
+	}
+	ret := &Boxed{Value: s.serialize(v)}
+	s.memory[obj] = ret
+	return ret
+}
+
+func (s *serializer) date(obj *sobek.Object) any {
+	ret := &Date{Time: math.NaN()}
+	if getTime, ok := sobek.AssertFunction(obj.Get("getTime")); ok {
+		v, err := getTime(obj)
+		if err != nil {
+			panic(err)
+		}
+		ret.Time = v.ToFloat()
+	}
+	s.memory[obj] = ret
+	return ret
+}
+
+func (s *serializer) error(obj *sobek.Object) any {
+	ret := &Error{Name: "Error"}
+	s.memory[obj] = ret
+	if name := obj.Get("name"); name != nil && slices.Contains(errorNames, name.String()) {
+		ret.Name = name.String()
+	}
+	if msg := obj.Get("message"); msg != nil && !sobek.IsUndefined(msg) {
+		ret.Message = msg.String()
+	}
+	if stack := obj.Get("stack"); stack != nil && !sobek.IsUndefined(stack) {
+		ret.Stack = stack.String()
+	}
+	if slices.Contains(obj.GetOwnPropertyNames(), "cause") {
+		ret.Cause, ret.HasCause = s.serialize(obj.Get("cause")), true
+	}
+	return ret
+}
+
+func (s *serializer) properties(obj *sobek.Object) []Property {
+	keys := obj.Keys()
+	props := make([]Property, 0, len(keys))
+	for _, key := range keys {
+		props = append(props, Property{Key: key, Value: s.serialize(obj.Get(key))})
+		if s.err != nil {
+			break
+		}
+	}
+	return props
+}
+
+// viewType returns the constructor name of TypedArray or DataView.
+func (s *serializer) viewType(obj *sobek.Object) (string, bool) {
+	if ctor, ok := s.rt.Get("DataView").(*sobek.Object); ok && s.rt.InstanceOf(obj, ctor) {
+		return "DataView", true
+	}
+	for _, typ := range typedArrayTypes {
+		if ctor, ok := s.rt.Get(typ).(*sobek.Object); ok && s.rt.InstanceOf(obj, ctor) {
+			return typ, true
+		}
+	}
+	return "", false
+}
+
+type deserializer struct {
+	rt     *sobek.Runtime
+	memory map[any]*sobek.Object
+	err    error
+}
+
+func (d *deserializer) new(name string, args ...any) *sobek.Object {
+	ctor := d.rt.Get(name)
+	if ctor == nil {
+		panic(d.rt.NewTypeError("%s is not defined", name))
+	}
+	values := make([]sobek.Value, len(args))
+	for i, arg := range args {
+		values[i] = d.rt.ToValue(arg)
+	}
+	obj, err := d.rt.New(ctor, values...)
+	if err != nil {
+		panic(err)
+	}
+	return obj
+}
+
+func (d *deserializer) deserialize(value any) sobek.Value {
+	switch v := value.(type) {
+	case nil:
+		return sobek.Null()
+	case Undefined:
+		return sobek.Undefined()
+	case bool, float64, string:
+		return d.rt.ToValue(v)
+	case *big.Int:
+		return d.rt.ToValue(new(big.Int).Set(v))
+	}
+
+	if reflect.TypeOf(value).Kind() == reflect.Pointer {
+		if obj, ok := d.memory[value]; ok {
+			return obj
+		}
+	}
+
+	var ret *sobek.Object
+	switch v := value.(type) {
+	case *Boxed:
+		ret = d.deserialize(v.Value).ToObject(d.rt)
+	case *Date:
+		ret = d.new("Date", v.Time)
+	case *RegExp:
+		ret = d.new("RegExp", v.Source, v.Flags)
+	case *Error:
+		name := v.Name
+		if !slices.Contains(errorNames, name) {
+			name = "Error"
+		}
+		ret = d.new(name, v.Message)
+		d.memory[value] = ret
+		if v.Stack != "" {
+			_ = ret.DefineDataProperty("stack", d.rt.ToValue(v.Stack), sobek.FLAG_TRUE, sobek.FLAG_FALSE, sobek.FLAG_TRUE)
+		}
+		if v.HasCause {
+			_ = ret.DefineDataProperty("cause", d.deserialize(v.Cause), sobek.FLAG_TRUE, sobek.FLAG_FALSE, sobek.FLAG_TRUE)
+		}
+	case *ArrayBuffer:
+		ret = d.rt.ToValue(d.rt.NewArrayBuffer(slices.Clone(v.Data))).(*sobek.Object)
+	case *ArrayBufferView:
+		ab := d.deserialize(v.Buffer)
+		ret = d.new(v.Type, ab, v.ByteOffset, v.Length)
+	case *Blob:
+		ret = buffer.NewBlob(d.rt, io.NewSectionReader(v.Data, 0, v.Size), v.Size, v.Type).(*sobek.Object)
+	case *Transferred:
+		if v.Receiver == nil {
+			panic(d.rt.NewTypeError("transferred object is not received"))
+		}
+		received, err := v.Receiver.Receive(d.rt)
+		if err != nil {
+			panic(d.rt.NewGoError(err))
+		}
+		obj, ok := received.(*sobek.Object)
+		if !ok {
+			return received
+		}
+		ret = obj
+	case *File:
+		ret = buffer.NewFile(d.rt, io.NewSectionReader(v.Data, 0, v.Size), v.Size, v.Type, v.Name, v.LastModified).(*sobek.Object)
+	case *Array:
+		ret = d.rt.NewArray()
+		d.memory[value] = ret
+		_ = ret.Set("length", v.Length)
+		for _, p := range v.Properties {
+			_ = ret.Set(p.Key, d.deserialize(p.Value))
+		}
+	case *Object:
+		ret = d.rt.NewObject()
+		d.memory[value] = ret
+		for _, p := range v.Properties {
+			_ = ret.Set(p.Key, d.deserialize(p.Value))
+		}
+	case *Map:
+		ret = d.new("Map")
+		d.memory[value] = ret
+		set, _ := sobek.AssertFunction(ret.Get("set"))
+		for _, entry := range v.Entries {
+			if _, err := set(ret, d.deserialize(entry[0]), d.deserialize(entry[1])); err != nil {
+				panic(err)
+			}
+		}
+	case *Set:
+		ret = d.new("Set")
+		d.memory[value] = ret
+		add, _ := sobek.AssertFunction(ret.Get("add"))
+		for _, item := range v.Values {
+			if _, err := add(ret, d.deserialize(item)); err != nil {
+				panic(err)
+			}
+		}
+	default:
+		if d.err == nil {
+			d.err = &DataCloneError{fmt.Sprintf("%T could not be deserialized", value)}
+		}
+		return sobek.Undefined()
+	}
+
+	d.memory[value] = ret
+	return ret
+}
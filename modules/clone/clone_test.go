@@ -0,0 +1,210 @@
+package clone
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+	"github.com/shiroyk/ski/js/modulestest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStructuredClone(t *testing.T) {
+	t.Parallel()
+	vm := modulestest.New(t)
+	ctx := context.Background()
+
+	t.Run("primitives", func(t *testing.T) {
+		_, err := vm.RunModule(ctx, `
+			assert.equal(structuredClone(1), 1);
+			assert.equal(structuredClone("foo"), "foo");
+			assert.equal(structuredClone(true), true);
+			assert.equal(structuredClone(null), null);
+			assert.true(structuredClone(undefined) === undefined);
+			assert.true(structuredClone(10n) === 10n);
+			assert.true(Number.isNaN(structuredClone(NaN)));
+		`)
+		assert.NoError(t, err)
+	})
+
+	t.Run("objects", func(t *testing.T) {
+		_, err := vm.RunModule(ctx, `
+			const date = new Date(1700000000000);
+			const source = {
+				date,
+				regexp: /foo/gi,
+				map: new Map([["a", 1], [{ b: 2 }, [3]]]),
+				set: new Set([1, "2", date]),
+				array: [1, , 3],
+				boxed: new String("bar"),
+				error: new RangeError("out of range", { cause: "test" }),
+			};
+			const clone = structuredClone(source);
+			assert.true(clone !== source);
+			assert.true(clone.date instanceof Date);
+			assert.equal(clone.date.getTime(), 1700000000000);
+			assert.true(clone.regexp instanceof RegExp);
+			assert.equal(clone.regexp.source, "foo");
+			assert.equal(clone.regexp.flags, "gi");
+			assert.true(clone.map instanceof Map);
+			assert.equal(clone.map.get("a"), 1);
+			assert.equal([...clone.map.keys()][1].b, 2);
+			assert.true(clone.set instanceof Set);
+			assert.equal(clone.set.size, 3);
+			assert.true(clone.set.has(clone.date), "shared reference");
+			assert.equal(clone.array.length, 3);
+			assert.true(!(1 in clone.array), "hole");
+			assert.true(clone.boxed instanceof String);
+			assert.equal(clone.boxed.valueOf(), "bar");
+			assert.true(clone.error instanceof RangeError);
+			assert.equal(clone.error.message, "out of range");
+			assert.equal(clone.error.cause, "test");
+		`)
+		assert.NoError(t, err)
+	})
+
+	t.Run("cyclic", func(t *testing.T) {
+		_, err := vm.RunModule(ctx, `
+			const source = { name: "root" };
+			source.self = source;
+			source.children = [source];
+			const clone = structuredClone(source);
+			assert.true(clone !== source);
+			assert.true(clone.self === clone);
+			assert.true(clone.children[0] === clone);
+		`)
+		assert.NoError(t, err)
+	})
+
+	t.Run("buffers", func(t *testing.T) {
+		_, err := vm.RunModule(ctx, `
+			const buffer = new Uint8Array([1, 2, 3, 4]).buffer;
+			const view = new Uint16Array(buffer, 2, 1);
+			const clone = structuredClone({ buffer, view, data: new DataView(buffer, 1) });
+			assert.true(clone.buffer !== buffer);
+			assert.equal(clone.buffer.byteLength, 4);
+			assert.true(clone.view instanceof Uint16Array);
+			assert.true(clone.view.buffer === clone.buffer, "shared buffer");
+			assert.equal(clone.view.byteOffset, 2);
+			assert.equal(clone.view.length, 1);
+			assert.true(clone.data instanceof DataView);
+			assert.equal(clone.data.byteLength, 3);
+			new Uint8Array(clone.buffer)[0] = 9;
+			assert.equal(new Uint8Array(buffer)[0], 1);
+		`)
+		assert.NoError(t, err)
+	})
+
+	t.Run("blob", func(t *testing.T) {
+		result, err := vm.RunModule(ctx, `
+			export default async () => {
+				const clone = structuredClone({
+					blob: new Blob(["foo"], { type: "text/plain" }),
+					file: new File(["bar"], "bar.txt", { lastModified: 1 }),
+				});
+				assert.true(clone.blob instanceof Blob);
+				assert.equal(clone.blob.type, "text/plain");
+				assert.true(clone.file instanceof File);
+				assert.equal(clone.file.name, "bar.txt");
+				assert.equal(clone.file.lastModified, 1);
+				return await clone.blob.text() + await clone.file.text();
+			}
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, "foobar", modulestest.PromiseResult(result).String())
+	})
+
+	t.Run("transfer", func(t *testing.T) {
+		_, err := vm.RunModule(ctx, `
+			const buffer = new Uint8Array([1, 2, 3]).buffer;
+			const clone = structuredClone(buffer, { transfer: [buffer] });
+			assert.equal(buffer.byteLength, 0);
+			assert.equal(clone.byteLength, 3);
+			try {
+				structuredClone(buffer, { transfer: [buffer] });
+				assert.true(false, "should throw");
+			} catch (e) {
+				assert.equal(e.name, "DataCloneError");
+			}
+		`)
+		assert.NoError(t, err)
+	})
+
+	t.Run("uncloneable", func(t *testing.T) {
+		_, err := vm.RunModule(ctx, `
+			for (const value of [() => {}, Symbol("foo"), { fn() {} }, new WeakMap(), Promise.resolve()]) {
+				try {
+					structuredClone(value);
+					assert.true(false, "should throw");
+				} catch (e) {
+					assert.equal(e.name, "DataCloneError");
+				}
+			}
+		`)
+		assert.NoError(t, err)
+	})
+}
+
+func TestSerialize(t *testing.T) {
+	t.Parallel()
+	source, target := js.NewVM(), js.NewVM()
+
+	value, err := source.Runtime().RunString(`({ a: [1, "2", new Map([[3, new Date(0)]])] })`)
+	require.NoError(t, err)
+
+	data, err := Serialize(source.Runtime(), value)
+	require.NoError(t, err)
+	obj, ok := data.(*Object)
+	require.True(t, ok)
+	require.Len(t, obj.Properties, 1)
+	assert.Equal(t, "a", obj.Properties[0].Key)
+
+	for range 2 {
+		ret, err := Deserialize(target.Runtime(), data)
+		require.NoError(t, err)
+		_ = target.Runtime().Set("ret", ret)
+		v, err := target.Runtime().RunString(`ret.a[0] + ret.a[1] + ret.a[2].get(3).getTime()`)
+		require.NoError(t, err)
+		assert.Equal(t, "120", v.String())
+	}
+}
+
+type testTransferable struct {
+	err         error
+	transferred bool
+}
+
+func (t *testTransferable) Transferable() error { return t.err }
+
+func (t *testTransferable) Transfer() (Receiver, error) {
+	t.transferred = true
+	return nil, nil
+}
+
+func TestSerializeTransfer(t *testing.T) {
+	t.Parallel()
+	rt := js.NewVM().Runtime()
+
+	transferable := func(t *testTransferable) *sobek.Object {
+		obj := rt.NewObject()
+		_ = obj.SetSymbol(SymTransferable, t)
+		return obj
+	}
+	first, second := new(testTransferable), &testTransferable{err: &DataCloneError{"detached"}}
+	buffer := rt.ToValue(rt.NewArrayBuffer(make([]byte, 8)))
+
+	_, err := Serialize(rt, sobek.Undefined(), buffer, transferable(first), transferable(second))
+	assert.ErrorContains(t, err, "detached")
+	// nothing is detached when the transfer list failed
+	assert.False(t, first.transferred)
+	assert.False(t, buffer.Export().(sobek.ArrayBuffer).Detached())
+
+	second.err = nil
+	_, err = Serialize(rt, sobek.Undefined(), buffer, transferable(first), transferable(second))
+	require.NoError(t, err)
+	assert.True(t, first.transferred)
+	assert.True(t, second.transferred)
+	assert.True(t, buffer.Export().(sobek.ArrayBuffer).Detached())
+}
@@ -0,0 +1,62 @@
+package clone
+
+import (
+	"errors"
+
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+	"github.com/shiroyk/ski/modules"
+)
+
+func init() {
+	modules.Register("structuredClone", modules.Global{
+		"structuredClone": modules.ModuleFunc(structuredClone),
+	})
+}
+
+// structuredClone creates a deep clone of a given value using the structured clone algorithm.
+// https://developer.mozilla.org/en-US/docs/Web/API/Window/structuredClone
+func structuredClone(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	if len(call.Arguments) < 1 {
+		panic(rt.NewTypeError("Failed to execute 'structuredClone': 1 argument required, but only 0 present."))
+	}
+	ret, err := Clone(rt, call.Argument(0), Transfer(rt, call.Argument(1))...)
+	if err != nil {
+		Throw(rt, err)
+	}
+	return ret
+}
+
+// Transfer returns the transfer list of the options,
+// which is an array or an object with the transfer property.
+func Transfer(rt *sobek.Runtime, options sobek.Value) []sobek.Value {
+	if options == nil || sobek.IsUndefined(options) || sobek.IsNull(options) {
+		return nil
+	}
+	obj := options.ToObject(rt)
+	if obj.ClassName() != "Array" {
+		list := obj.Get("transfer")
+		if list == nil || sobek.IsUndefined(list) {
+			return nil
+		}
+		obj = list.ToObject(rt)
+	}
+	var transfer []sobek.Value
+	rt.ForOf(obj, func(v sobek.Value) bool {
+		transfer = append(transfer, v)
+		return true
+	})
+	return transfer
+}
+
+// Throw throws the DataCloneError as a JavaScript error with the name DataCloneError,
+// the other errors are thrown with js.Throw.
+func Throw(rt *sobek.Runtime, err error) {
+	var dataCloneError *DataCloneError
+	if !errors.As(err, &dataCloneError) {
+		js.Throw(rt, err)
+	}
+	e := rt.NewTypeError(dataCloneError.Message)
+	_ = e.DefineDataProperty("name", rt.ToValue("DataCloneError"), sobek.FLAG_TRUE, sobek.FLAG_FALSE, sobek.FLAG_TRUE)
+	panic(e)
+}
@@ -38,6 +38,63 @@ func (*Crypto) Instantiate(rt *sobek.Runtime) (sobek.Value, error) {
 	}), nil
 }
 
+// Describe returns the TypeScript declaration of the module.
+func (*Crypto) Describe() string {
+	return `type Input = string | ArrayBuffer | ArrayBufferView;
+export interface Encoder {
+	base64(): string;
+	hex(): string;
+	string(): string;
+	binary(): ArrayBuffer;
+}
+export interface Hasher {
+	update(input: Input): void;
+	digest(): Encoder;
+	reset(): void;
+	encrypt(input: Input): Encoder;
+}
+export interface Cipher {
+	encrypt(input: Input): Encoder;
+	decrypt(input: Input): Encoder;
+}
+/** The algorithm is the mode and padding, such as "CBC/PKCS7". */
+export function aes(key: Input, iv: Input, algorithm?: string): Cipher;
+export function des(key: Input, iv: Input, algorithm?: string): Cipher;
+export function tripleDes(key: Input, iv: Input, algorithm?: string): Cipher;
+export function createCipher(algorithm: string, key: Input, iv: Input): Cipher;
+export function createHash(algorithm: string): Hasher;
+export function createHMAC(algorithm: string, key: Input): Hasher;
+export function hmac(algorithm: string, key: Input, input: Input): Encoder;
+export function randomBytes(size: number): ArrayBuffer;
+export function md5(input: Input): Encoder;
+export function ripemd160(input: Input): Encoder;
+export function sha1(input: Input): Encoder;
+export function sha256(input: Input): Encoder;
+export function sha384(input: Input): Encoder;
+export function sha512(input: Input): Encoder;
+export function sha512_224(input: Input): Encoder;
+export function sha512_256(input: Input): Encoder;
+const _default: {
+	aes: typeof aes;
+	des: typeof des;
+	tripleDes: typeof tripleDes;
+	createCipher: typeof createCipher;
+	createHash: typeof createHash;
+	createHMAC: typeof createHMAC;
+	hmac: typeof hmac;
+	randomBytes: typeof randomBytes;
+	md5: typeof md5;
+	ripemd160: typeof ripemd160;
+	sha1: typeof sha1;
+	sha256: typeof sha256;
+	sha384: typeof sha384;
+	sha512: typeof sha512;
+	sha512_224: typeof sha512_224;
+	sha512_256: typeof sha512_256;
+};
+export default _default;`
+}
+
 // Encoder the encoded
 type Encoder struct{ data []byte }
 
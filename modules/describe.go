@@ -0,0 +1,455 @@
+package modules
+
+import (
+	"fmt"
+	"maps"
+	"math/big"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/sobek"
+)
+
+// Describer is implemented by the Module which describes its TypeScript declaration.
+// The module returns the declarations inside its `declare module` block, such as
+// "export function get(key: string): string | undefined;". The Global member returns
+// its global declaration, such as "declare function sleep(ms: number): void;".
+type Describer interface {
+	Describe() string
+}
+
+const (
+	// ModulesDeclaration the file name of the module declarations.
+	ModulesDeclaration = "modules.d.ts"
+	// GlobalsDeclaration the file name of the Global module declarations.
+	GlobalsDeclaration = "globals.d.ts"
+)
+
+const declarationHeader = "// Code generated by ski types. DO NOT EDIT.\n\n"
+
+// maxDescribeDepth the max depth of the inferred object types.
+const maxDescribeDepth = 3
+
+// Describe returns the TypeScript declarations of the registered modules keyed by the file name.
+// The modules are declared as the ambient modules in ModulesDeclaration, the Global modules
+// are declared in GlobalsDeclaration, which is separated since the web standard globals
+// may conflict with the TypeScript dom lib.
+//
+// The declaration of the Module without Describer is inferred from the value instantiated
+// on the runtime, the wrapped Go functions are typed from their signatures.
+func Describe(rt *sobek.Runtime) map[string]string {
+	var mods, globals strings.Builder
+	mods.WriteString(declarationHeader)
+	globals.WriteString(declarationHeader)
+
+	registered := All()
+	for _, name := range slices.Sorted(maps.Keys(registered)) {
+		switch mod := registered[name].(type) {
+		case Global:
+			members := slices.Sorted(maps.Keys(mod))
+			for _, member := range members {
+				globals.WriteString(describeGlobal(rt, member, mod[member]))
+			}
+			if strings.HasPrefix(name, nodePrefix) {
+				// the node modules export the globals
+				fmt.Fprintf(&mods, "declare module %q {\n", name)
+				fmt.Fprintf(&mods, "\texport { %s };\n", strings.Join(members, ", "))
+				mods.WriteString("\tconst _default: {\n")
+				for _, member := range members {
+					fmt.Fprintf(&mods, "\t\t%s: typeof %s;\n", member, member)
+				}
+				mods.WriteString("\t};\n\texport default _default;\n}\n\n")
+			}
+		default:
+			fmt.Fprintf(&mods, "declare module %q {\n%s}\n\n", name, indent(describeModule(rt, mod)))
+		}
+	}
+
+	return map[string]string{
+		ModulesDeclaration: mods.String(),
+		GlobalsDeclaration: globals.String(),
+	}
+}
+
+// describeGlobal returns the global declaration of the Global member.
+func describeGlobal(rt *sobek.Runtime, name string, mod Module) string {
+	if d, ok := mod.(Describer); ok {
+		return strings.TrimSpace(d.Describe()) + "\n\n"
+	}
+	return fmt.Sprintf("declare var %s: %s;\n\n", name, typeOf(rt, instantiate(rt, mod), 0))
+}
+
+// describeModule returns the declarations of the module exports, the named
+// exports are the own properties of the module value as the goModule.
+func describeModule(rt *sobek.Runtime, mod Module) string {
+	if d, ok := mod.(Describer); ok {
+		return strings.TrimSpace(d.Describe()) + "\n"
+	}
+
+	value := instantiate(rt, mod)
+	obj, ok := value.(*sobek.Object)
+	if !ok {
+		return "const _default: any;\nexport default _default;\n"
+	}
+
+	var b strings.Builder
+	var fields []string
+	_, isFunc := sobek.AssertFunction(obj)
+	for _, name := range obj.GetOwnPropertyNames() {
+		if isFunc && (name == "length" || name == "name" || name == "prototype") {
+			continue
+		}
+		typ := memberType(rt, obj, name, 0)
+		local := name
+		if isIdentifier(name) && !reserved[name] {
+			fmt.Fprintf(&b, "export const %s: %s;\n", name, typ)
+		} else {
+			// the reserved word and the non identifier are exported by the alias
+			local = "_" + nonIdentifier.ReplaceAllString(name, "_")
+			fmt.Fprintf(&b, "const %s: %s;\nexport { %s as %s };\n", local, typ, local, propertyKey(name))
+		}
+		fields = append(fields, fmt.Sprintf("\t%s: typeof %s;\n", propertyKey(name), local))
+	}
+	if isFunc {
+		fmt.Fprintf(&b, "const _default: %s;\n", typeOf(rt, obj, 0))
+	} else {
+		b.WriteString("const _default: {\n")
+		for _, field := range fields {
+			b.WriteString(field)
+		}
+		b.WriteString("};\n")
+	}
+	b.WriteString("export default _default;\n")
+	return b.String()
+}
+
+// instantiate returns the module value, nil if the module failed to instantiate.
+func instantiate(rt *sobek.Runtime, mod Module) (value sobek.Value) {
+	defer func() {
+		if recover() != nil {
+			value = nil
+		}
+	}()
+	v, err := mod.Instantiate(rt)
+	if err != nil {
+		return nil
+	}
+	return v
+}
+
+// memberType returns the type of the object property, any if the getter throws.
+func memberType(rt *sobek.Runtime, obj *sobek.Object, name string, depth int) (ret string) {
+	defer func() {
+		if recover() != nil {
+			ret = "any"
+		}
+	}()
+	return typeOf(rt, obj.Get(name), depth)
+}
+
+var (
+	typeFunctionCall        = reflect.TypeOf((func(sobek.FunctionCall) sobek.Value)(nil))
+	typeFunctionCallRuntime = reflect.TypeOf((func(sobek.FunctionCall, *sobek.Runtime) sobek.Value)(nil))
+	typeValue               = reflect.TypeOf((*sobek.Value)(nil)).Elem()
+	typeError               = reflect.TypeOf((*error)(nil)).Elem()
+	typeBigInt              = reflect.TypeOf((*big.Int)(nil))
+)
+
+// typeOf infers the TypeScript type of the value.
+func typeOf(rt *sobek.Runtime, value sobek.Value, depth int) string {
+	switch {
+	case value == nil || sobek.IsUndefined(value):
+		return "undefined"
+	case sobek.IsNull(value):
+		return "null"
+	}
+
+	obj, ok := value.(*sobek.Object)
+	if !ok {
+		switch t := value.ExportType(); {
+		case t == nil:
+			return "any"
+		case t == typeBigInt:
+			return "bigint"
+		default:
+			switch t.Kind() {
+			case reflect.String:
+				return "string"
+			case reflect.Bool:
+				return "boolean"
+			case reflect.Int64, reflect.Float64:
+				return "number"
+			}
+		}
+		return "any"
+	}
+
+	if _, ok := sobek.AssertFunction(obj); ok {
+		if t := reflect.TypeOf(obj.Export()); t != nil && t.Kind() == reflect.Func &&
+			t != typeFunctionCall && t != typeFunctionCallRuntime {
+			return goFuncType(t, 0, depth)
+		}
+		if isClass(obj) {
+			return classType(rt, obj, depth)
+		}
+		return "(...args: any[]) => any"
+	}
+
+	switch obj.ClassName() {
+	case "Array":
+		return "any[]"
+	case "Promise":
+		return "Promise<any>"
+	case "ArrayBuffer":
+		return "ArrayBuffer"
+	}
+	if depth >= maxDescribeDepth {
+		return "any"
+	}
+
+	keys := obj.Keys()
+	if len(keys) == 0 {
+		return "Record<string, any>"
+	}
+	var b strings.Builder
+	b.WriteString("{\n")
+	for _, key := range keys {
+		writeMember(&b, depth+1, propertyKey(key), memberType(rt, obj, key, depth+1))
+	}
+	b.WriteString(strings.Repeat("\t", depth) + "}")
+	return b.String()
+}
+
+// isClass returns true if the value is a constructor with the prototype members.
+func isClass(obj *sobek.Object) bool {
+	if _, ok := sobek.AssertConstructor(obj); !ok {
+		return false
+	}
+	proto, ok := obj.Get("prototype").(*sobek.Object)
+	if !ok {
+		return false
+	}
+	return slices.ContainsFunc(proto.GetOwnPropertyNames(), func(name string) bool { return name != "constructor" })
+}
+
+// classType returns the constructor type of the class, with the prototype
+// members as the instance type and the own properties as the statics.
+func classType(rt *sobek.Runtime, ctor *sobek.Object, depth int) string {
+	proto := ctor.Get("prototype").ToObject(rt)
+	tabs := strings.Repeat("\t", depth+1)
+
+	var b strings.Builder
+	b.WriteString("{\n")
+	b.WriteString(tabs + "new (...args: any[]): {\n")
+	for _, name := range proto.GetOwnPropertyNames() {
+		if name == "constructor" {
+			continue
+		}
+		key := propertyKey(name)
+		desc := descriptor(rt, proto, name)
+		switch {
+		case desc == nil:
+			writeMember(&b, depth+2, key, "any")
+		case !isUndefined(desc.Get("get")) || !isUndefined(desc.Get("set")):
+			// the getters check the brand of "this", so the type is unknown
+			if isUndefined(desc.Get("set")) {
+				key = "readonly " + key
+			}
+			writeMember(&b, depth+2, key, "any")
+		default:
+			writeMember(&b, depth+2, key, typeOf(rt, desc.Get("value"), depth+2))
+		}
+	}
+	b.WriteString(tabs + "};\n")
+	for _, name := range ctor.GetOwnPropertyNames() {
+		switch name {
+		case "length", "name", "prototype":
+			continue
+		}
+		writeMember(&b, depth+1, propertyKey(name), memberType(rt, ctor, name, depth+1))
+	}
+	b.WriteString(strings.Repeat("\t", depth) + "}")
+	return b.String()
+}
+
+// descriptor returns the own property descriptor of the object.
+func descriptor(rt *sobek.Runtime, obj *sobek.Object, name string) *sobek.Object {
+	object, ok := rt.Get("Object").(*sobek.Object)
+	if !ok {
+		return nil
+	}
+	getOwnPropertyDescriptor, ok := sobek.AssertFunction(object.Get("getOwnPropertyDescriptor"))
+	if !ok {
+		return nil
+	}
+	desc, err := getOwnPropertyDescriptor(object, obj, rt.ToValue(name))
+	if err != nil {
+		return nil
+	}
+	ret, _ := desc.(*sobek.Object)
+	return ret
+}
+
+// goFuncType returns the function type of the Go function signature,
+// the first skip parameters are skipped, such as the method receiver.
+func goFuncType(t reflect.Type, skip, depth int) string {
+	params := make([]string, 0, t.NumIn())
+	for i := skip; i < t.NumIn(); i++ {
+		in := t.In(i)
+		if t.IsVariadic() && i == t.NumIn()-1 {
+			params = append(params, fmt.Sprintf("...args: %s", goType(in, depth)))
+			continue
+		}
+		params = append(params, fmt.Sprintf("arg%d: %s", i-skip, goType(in, depth)))
+	}
+
+	var results []reflect.Type
+	for i := range t.NumOut() {
+		if out := t.Out(i); out != typeError {
+			results = append(results, out)
+		}
+	}
+	ret := "void"
+	switch len(results) {
+	case 0:
+	case 1:
+		ret = goType(results[0], depth)
+	default:
+		ret = "any[]"
+	}
+	return fmt.Sprintf("(%s) => %s", strings.Join(params, ", "), ret)
+}
+
+// goType returns the TypeScript type of the Go type as it converted by the runtime.
+func goType(t reflect.Type, depth int) string {
+	switch t {
+	case typeValue:
+		return "any"
+	case typeError:
+		return "Error"
+	case typeBigInt:
+		return "bigint"
+	case typeFunctionCall, typeFunctionCallRuntime:
+		return "(...args: any[]) => any"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return goType(t.Elem(), depth) + "[]"
+	case reflect.Map:
+		return fmt.Sprintf("Record<string, %s>", goType(t.Elem(), depth))
+	case reflect.Func:
+		return goFuncType(t, 0, depth)
+	case reflect.Pointer, reflect.Struct:
+		if depth >= maxDescribeDepth {
+			return "any"
+		}
+		return goStructType(t, depth)
+	default:
+		return "any"
+	}
+}
+
+// goStructType returns the object type of the exported fields and methods,
+// which names are mapped as the field name mapper of the VM.
+func goStructType(t reflect.Type, depth int) string {
+	ptr := t
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	} else {
+		ptr = reflect.PointerTo(t)
+	}
+
+	var b strings.Builder
+	b.WriteString("{\n")
+	if t.Kind() == reflect.Struct {
+		for _, field := range reflect.VisibleFields(t) {
+			if !field.IsExported() || field.Anonymous {
+				continue
+			}
+			if name := fieldName(field); name != "" {
+				writeMember(&b, depth+1, propertyKey(name), goType(field.Type, depth+1))
+			}
+		}
+	}
+	for i := range ptr.NumMethod() {
+		m := ptr.Method(i)
+		if m.Type.NumIn() == 3 && m.Type.In(1) == reflect.TypeOf(sobek.FunctionCall{}) {
+			writeMember(&b, depth+1, uncap(m.Name), "(...args: any[]) => any")
+			continue
+		}
+		writeMember(&b, depth+1, uncap(m.Name), goFuncType(m.Type, 1, depth+1))
+	}
+	b.WriteString(strings.Repeat("\t", depth) + "}")
+	return b.String()
+}
+
+func writeMember(b *strings.Builder, depth int, key, typ string) {
+	b.WriteString(strings.Repeat("\t", depth))
+	b.WriteString(key)
+	b.WriteString(": ")
+	b.WriteString(typ)
+	b.WriteString(";\n")
+}
+
+func isUndefined(value sobek.Value) bool { return value == nil || sobek.IsUndefined(value) }
+
+func uncap(name string) string { return strings.ToLower(name[:1]) + name[1:] }
+
+// fieldName returns the name of the field as the `js` tag, empty if the field is hidden by `js:"-"`.
+func fieldName(field reflect.StructField) string {
+	if tag, ok := field.Tag.Lookup("js"); ok {
+		if tag == "-" {
+			return ""
+		}
+		return tag
+	}
+	return uncap(field.Name)
+}
+
+// indent indents the lines of the declarations.
+func indent(s string) string {
+	lines := strings.SplitAfter(s, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			lines[i] = "\t" + line
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+var (
+	identifier    = regexp.MustCompile(`^[A-Za-z_$][\w$]*$`)
+	nonIdentifier = regexp.MustCompile(`[^\w$]`)
+)
+
+func isIdentifier(name string) bool { return identifier.MatchString(name) }
+
+func propertyKey(name string) string {
+	if isIdentifier(name) {
+		return name
+	}
+	return strconv.Quote(name)
+}
+
+// reserved the reserved words which cannot be the binding names.
+var reserved = map[string]bool{
+	"break": true, "case": true, "catch": true, "class": true, "const": true, "continue": true,
+	"debugger": true, "default": true, "delete": true, "do": true, "else": true, "enum": true,
+	"export": true, "extends": true, "false": true, "finally": true, "for": true, "function": true,
+	"if": true, "import": true, "in": true, "instanceof": true, "new": true, "null": true,
+	"return": true, "super": true, "switch": true, "this": true, "throw": true, "true": true,
+	"try": true, "typeof": true, "var": true, "void": true, "while": true, "with": true,
+	"yield": true, "let": true, "static": true, "implements": true, "interface": true,
+	"package": true, "private": true, "protected": true, "public": true, "await": true,
+}
@@ -0,0 +1,87 @@
+package modules
+
+import (
+	"testing"
+
+	"github.com/grafana/sobek"
+	"github.com/stretchr/testify/assert"
+)
+
+type testDescribeHasher struct{}
+
+func (testDescribeHasher) Sum(input string, size int) ([]byte, error) { return nil, nil }
+
+type testDescribeConfig struct {
+	Name    string `js:"displayName"`
+	Secret  string `js:"-"`
+	MaxAge  int    `js:"max-age"`
+	Verbose bool
+}
+
+type testDescribeModule struct{}
+
+func (testDescribeModule) Instantiate(rt *sobek.Runtime) (sobek.Value, error) {
+	ret := rt.NewObject()
+	_ = ret.Set("version", "1.0")
+	_ = ret.Set("hash", func(input string, size int) (string, error) { return input, nil })
+	_ = ret.Set("hasher", func() *testDescribeHasher { return nil })
+	_ = ret.Set("config", func() testDescribeConfig { return testDescribeConfig{} })
+	_ = ret.Set("call", func(call sobek.FunctionCall) sobek.Value { return sobek.Undefined() })
+	_ = ret.Set("delete", func(key string) {})
+	_ = ret.Set("options", map[string]any{"debug": true, "max-size": 10})
+	return ret, nil
+}
+
+type testDescribedModule struct{ testDescribeModule }
+
+func (testDescribedModule) Describe() string { return "export function ping(): string;" }
+
+type testBrokenModule struct{}
+
+func (testBrokenModule) Instantiate(*sobek.Runtime) (sobek.Value, error) { panic("broken") }
+
+func TestDescribe(t *testing.T) {
+	Register("describe", new(testDescribeModule))
+	Register("described", new(testDescribedModule))
+	Register("broken", new(testBrokenModule))
+	Register("node:describe", Global{
+		"Point": &Class[testPoint]{Name: "Point"},
+		"sleep": ModuleFunc(func(sobek.FunctionCall, *sobek.Runtime) sobek.Value { return nil }),
+	})
+	defer Remove("ski/describe", "ski/described", "ski/broken", "node:describe")
+
+	rt := NewTestVM(t, NewLoader())
+	files := Describe(rt)
+	mods, globals := files[ModulesDeclaration], files[GlobalsDeclaration]
+
+	t.Run("module", func(t *testing.T) {
+		assert.Contains(t, mods, `declare module "ski/describe" {`)
+		assert.Contains(t, mods, "\texport const version: string;\n")
+		assert.Contains(t, mods, "\texport const hash: (arg0: string, arg1: number) => string;\n")
+		assert.Contains(t, mods, "\texport const call: (...args: any[]) => any;\n")
+		assert.Contains(t, mods, "\texport const hasher: () => {\n\t\tsum: (arg0: string, arg1: number) => number[];\n\t};\n")
+		assert.Contains(t, mods, "\tconst _delete: (arg0: string) => void;\n\texport { _delete as delete };\n")
+		assert.Contains(t, mods, "\t\t\"max-size\": number;\n")
+		assert.Contains(t, mods, "\texport const config: () => {\n\t\tdisplayName: string;\n\t\t\"max-age\": number;\n\t\tverbose: boolean;\n\t};\n")
+		assert.NotContains(t, mods, "secret")
+		assert.Contains(t, mods, "\t\tdelete: typeof _delete;\n")
+		assert.Contains(t, mods, "\texport default _default;\n}")
+	})
+
+	t.Run("describer", func(t *testing.T) {
+		assert.Contains(t, mods, "declare module \"ski/described\" {\n\texport function ping(): string;\n}")
+	})
+
+	t.Run("broken", func(t *testing.T) {
+		assert.Contains(t, mods, "declare module \"ski/broken\" {\n\tconst _default: any;\n\texport default _default;\n}")
+	})
+
+	t.Run("global", func(t *testing.T) {
+		assert.Contains(t, globals, "declare var sleep: (...args: any[]) => any;\n")
+		assert.Contains(t, globals, "declare var Point: {\n\tnew (...args: any[]): {\n")
+		assert.Contains(t, globals, "\t\ttoString: (...args: any[]) => any;\n")
+		assert.Contains(t, globals, "\t\treadonly label: any;\n")
+		assert.Contains(t, globals, "\t\tx: any;\n")
+		assert.Contains(t, mods, "declare module \"node:describe\" {\n\texport { Point, sleep };\n")
+	})
+}
@@ -0,0 +1,80 @@
+package modules_test
+
+import (
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/modules"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	_ "github.com/shiroyk/ski/modules/cache"
+	_ "github.com/shiroyk/ski/modules/crypto"
+	_ "github.com/shiroyk/ski/modules/ext"
+	_ "github.com/shiroyk/ski/modules/fetch"
+	_ "github.com/shiroyk/ski/modules/http"
+)
+
+var (
+	declaredExport = regexp.MustCompile(`(?m)^export (?:function|const|let|var|class) (\w+)`)
+	declaredAlias  = regexp.MustCompile(`(?m)^export \{([^}]*)\}`)
+)
+
+// declaredNames returns the value names exported by the declaration, the types are not included.
+func declaredNames(decl string) []string {
+	var names []string
+	for _, m := range declaredExport.FindAllStringSubmatch(decl, -1) {
+		names = append(names, m[1])
+	}
+	for _, m := range declaredAlias.FindAllStringSubmatch(decl, -1) {
+		for _, spec := range strings.Split(m[1], ",") {
+			fields := strings.Fields(spec)
+			if len(fields) > 0 {
+				names = append(names, strings.Trim(fields[len(fields)-1], `"`))
+			}
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// exportedNames returns the named exports of the module value as the goModule.
+func exportedNames(t *testing.T, rt *sobek.Runtime, mod modules.Module) []string {
+	value, err := mod.Instantiate(rt)
+	require.NoError(t, err)
+	obj, ok := value.(*sobek.Object)
+	if !ok {
+		return nil
+	}
+	_, isFunc := sobek.AssertFunction(obj)
+	var names []string
+	for _, name := range obj.GetOwnPropertyNames() {
+		if isFunc && (name == "length" || name == "name" || name == "prototype") {
+			continue
+		}
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func TestDescriberExports(t *testing.T) {
+	registered := modules.All()
+	for _, name := range slices.Sorted(maps.Keys(registered)) {
+		mod := registered[name]
+		if _, ok := mod.(modules.Global); ok {
+			continue
+		}
+		d, ok := mod.(modules.Describer)
+		if !ok {
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, exportedNames(t, sobek.New(), mod), declaredNames(d.Describe()))
+		})
+	}
+}
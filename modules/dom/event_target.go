@@ -58,10 +58,11 @@ func (e *eventTarget) Instantiate(rt *sobek.Runtime) (sobek.Value, error) {
 }
 
 func (e *eventTarget) constructor(call sobek.ConstructorCall, rt *sobek.Runtime) *sobek.Object {
-	target := NewEventTarget()
+	target := &_eventTarget{listeners: make(map[string][]EventListener)}
 	ret := rt.NewObject()
 	_ = ret.SetSymbol(symEventTarget, target)
 	_ = ret.SetPrototype(call.This.ToObject(rt).Prototype())
+	target.value = ret
 	return ret
 }
 
@@ -193,9 +194,13 @@ func toEventTarget(rt *sobek.Runtime, value sobek.Value, isValue ...bool) EventT
 type _eventTarget struct {
 	parentTarget EventTarget
 	listeners    map[string][]EventListener
+	value        *sobek.Object // the JavaScript object of the target, so the event target is the same object
 }
 
 func (t *_eventTarget) toValue(this sobek.Value, rt *sobek.Runtime) sobek.Value {
+	if t.value != nil {
+		return t.value
+	}
 	if this == nil {
 		this = rt.Get("EventTarget")
 	}
@@ -0,0 +1,141 @@
+package dom
+
+import (
+	"github.com/grafana/sobek"
+)
+
+// MessageEvent represents a message received by a target object.
+// https://html.spec.whatwg.org/multipage/comms.html#messageevent
+type MessageEvent interface {
+	Event
+	// Data returns the data sent by the message emitter.
+	Data() sobek.Value
+	// Origin returns the origin of the message emitter.
+	Origin() string
+	// LastEventID returns the unique ID for the event.
+	LastEventID() string
+}
+
+type messageEvent struct{}
+
+func (m *messageEvent) Instantiate(rt *sobek.Runtime) (sobek.Value, error) {
+	ctor := rt.ToValue(m.constructor).ToObject(rt)
+	p := m.prototype(rt)
+	_ = p.DefineDataProperty("constructor", ctor, sobek.FLAG_FALSE, sobek.FLAG_FALSE, sobek.FLAG_FALSE)
+	_ = ctor.SetPrototype(p)
+	_ = ctor.Set("prototype", p)
+	return ctor, nil
+}
+
+func (m *messageEvent) constructor(call sobek.ConstructorCall, rt *sobek.Runtime) *sobek.Object {
+	if len(call.Arguments) < 1 {
+		panic(rt.NewTypeError("Failed to construct 'MessageEvent': 1 argument required, but only 0 present."))
+	}
+
+	evt := &_messageEvent{_event: NewEvent(call.Argument(0).String()).(*_event), data: sobek.Null()}
+
+	options := call.Argument(1)
+	if !sobek.IsUndefined(options) && !sobek.IsNull(options) {
+		obj := options.ToObject(rt)
+		if v := obj.Get("bubbles"); v != nil {
+			evt.setBubbles(v.ToBoolean())
+		}
+		if v := obj.Get("cancelable"); v != nil {
+			evt.setCancelable(v.ToBoolean())
+		}
+		if v := obj.Get("data"); v != nil {
+			evt.data = v
+		}
+		if v := obj.Get("origin"); v != nil {
+			evt.origin = v.String()
+		}
+		if v := obj.Get("lastEventId"); v != nil {
+			evt.lastEventID = v.String()
+		}
+	}
+
+	ret := rt.NewObject()
+	_ = ret.SetSymbol(symEvent, evt)
+	_ = ret.SetPrototype(call.This.ToObject(rt).Prototype())
+	return ret
+}
+
+func (m *messageEvent) prototype(rt *sobek.Runtime) *sobek.Object {
+	p := rt.NewObject()
+	e := rt.Get("Event")
+	if e == nil {
+		panic(rt.NewTypeError("Event is undefined"))
+	}
+	_ = p.SetPrototype(e.ToObject(rt).Get("prototype").ToObject(rt))
+
+	_ = p.DefineAccessorProperty("data", rt.ToValue(m.data), nil, sobek.FLAG_FALSE, sobek.FLAG_TRUE)
+	_ = p.DefineAccessorProperty("origin", rt.ToValue(m.origin), nil, sobek.FLAG_FALSE, sobek.FLAG_TRUE)
+	_ = p.DefineAccessorProperty("lastEventId", rt.ToValue(m.lastEventId), nil, sobek.FLAG_FALSE, sobek.FLAG_TRUE)
+	_ = p.DefineAccessorProperty("source", rt.ToValue(m.source), nil, sobek.FLAG_FALSE, sobek.FLAG_TRUE)
+	_ = p.DefineAccessorProperty("ports", rt.ToValue(m.ports), nil, sobek.FLAG_FALSE, sobek.FLAG_TRUE)
+	_ = p.SetSymbol(sobek.SymToStringTag, func(sobek.FunctionCall) sobek.Value { return rt.ToValue("MessageEvent") })
+
+	return p
+}
+
+func (*messageEvent) data(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	return toMessageEvent(rt, call.This).Data()
+}
+
+func (*messageEvent) origin(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	return rt.ToValue(toMessageEvent(rt, call.This).Origin())
+}
+
+func (*messageEvent) lastEventId(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	return rt.ToValue(toMessageEvent(rt, call.This).LastEventID())
+}
+
+func (*messageEvent) source(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	toMessageEvent(rt, call.This)
+	return sobek.Null()
+}
+
+func (*messageEvent) ports(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	toMessageEvent(rt, call.This)
+	return rt.NewArray()
+}
+
+func toMessageEvent(rt *sobek.Runtime, value sobek.Value) MessageEvent {
+	if evt, ok := toEvent(rt, value).(MessageEvent); ok {
+		return evt
+	}
+	panic(rt.NewTypeError(`Value of "this" must be of type MessageEvent`))
+}
+
+// NewMessageEvent creates a new MessageEvent instance,
+// the data must belong to the runtime where the event is dispatched.
+func NewMessageEvent(typ string, data sobek.Value) MessageEvent {
+	if data == nil {
+		data = sobek.Null()
+	}
+	return &_messageEvent{_event: NewEvent(typ).(*_event), data: data}
+}
+
+type _messageEvent struct {
+	*_event
+	data        sobek.Value
+	origin      string
+	lastEventID string
+}
+
+func (e *_messageEvent) Data() sobek.Value   { return e.data }
+func (e *_messageEvent) Origin() string      { return e.origin }
+func (e *_messageEvent) LastEventID() string { return e.lastEventID }
+
+func (e *_messageEvent) toValue(this sobek.Value, rt *sobek.Runtime) sobek.Value {
+	if this == nil {
+		this = rt.Get("MessageEvent")
+	}
+	if this == nil {
+		panic(rt.NewTypeError("MessageEvent is not defined"))
+	}
+	ret := rt.NewObject()
+	_ = ret.SetSymbol(symEvent, e)
+	_ = ret.SetPrototype(this.ToObject(rt).Prototype())
+	return ret
+}
@@ -4,6 +4,7 @@ import (
 	"sync/atomic"
 
 	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
 	"github.com/shiroyk/ski/modules"
 )
 
@@ -13,11 +14,48 @@ type toValue interface {
 
 func init() {
 	modules.Register("dom", modules.Global{
-		"Event":       new(event),
-		"EventTarget": new(eventTarget),
+		"Event":                 new(event),
+		"EventTarget":           new(eventTarget),
+		"MessageEvent":          new(messageEvent),
+		"PromiseRejectionEvent": new(promiseRejectionEvent),
+		"addEventListener":      globalListener("addEventListener"),
+		"removeEventListener":   globalListener("removeEventListener"),
+		"dispatchEvent":         globalListener("dispatchEvent"),
 	})
 }
 
 var ids atomic.Uint32
 
 func newID() uint32 { return ids.Add(1) }
+
+var symGlobalTarget = sobek.NewSymbol("Symbol.__globalEventTarget__")
+
+// globalListener returns the global function which calls the EventTarget method
+// of the runtime global event target, such as the unhandledrejection listeners.
+func globalListener(name string) modules.ModuleFunc {
+	return func(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+		global := rt.GlobalObject()
+		target, ok := global.GetSymbol(symGlobalTarget).(*sobek.Object)
+		if !ok {
+			ctor, ok := sobek.AssertConstructor(rt.Get("EventTarget"))
+			if !ok {
+				panic(rt.NewTypeError("EventTarget is undefined"))
+			}
+			var err error
+			if target, err = ctor(nil); err != nil {
+				js.Throw(rt, err)
+			}
+			// the event target is the global object
+			if t, ok := toEventTarget(rt, target).(*_eventTarget); ok {
+				t.value = global
+			}
+			_ = global.SetSymbol(symGlobalTarget, target)
+		}
+		method, _ := sobek.AssertFunction(target.Get(name))
+		ret, err := method(target, call.Arguments...)
+		if err != nil {
+			js.Throw(rt, err)
+		}
+		return ret
+	}
+}
@@ -0,0 +1,112 @@
+package dom
+
+import (
+	"github.com/grafana/sobek"
+)
+
+// PromiseRejectionEvent represents the event dispatched to the global scope
+// when a promise is rejected without a handler, or a handler is added later.
+// https://html.spec.whatwg.org/multipage/webappapis.html#promiserejectionevent
+type PromiseRejectionEvent interface {
+	Event
+	// Promise returns the promise which was rejected.
+	Promise() sobek.Value
+	// Reason returns the rejection reason of the promise.
+	Reason() sobek.Value
+}
+
+type promiseRejectionEvent struct{}
+
+func (p *promiseRejectionEvent) Instantiate(rt *sobek.Runtime) (sobek.Value, error) {
+	ctor := rt.ToValue(p.constructor).ToObject(rt)
+	proto := p.prototype(rt)
+	_ = proto.DefineDataProperty("constructor", ctor, sobek.FLAG_FALSE, sobek.FLAG_FALSE, sobek.FLAG_FALSE)
+	_ = ctor.SetPrototype(proto)
+	_ = ctor.Set("prototype", proto)
+	return ctor, nil
+}
+
+func (p *promiseRejectionEvent) constructor(call sobek.ConstructorCall, rt *sobek.Runtime) *sobek.Object {
+	if len(call.Arguments) < 2 {
+		panic(rt.NewTypeError("Failed to construct 'PromiseRejectionEvent': 2 arguments required, but only 1 present."))
+	}
+
+	evt := &_promiseRejectionEvent{_event: NewEvent(call.Argument(0).String()).(*_event), reason: sobek.Undefined()}
+
+	options := call.Argument(1)
+	if sobek.IsUndefined(options) || sobek.IsNull(options) {
+		panic(rt.NewTypeError("Failed to construct 'PromiseRejectionEvent': required member promise is undefined."))
+	}
+	obj := options.ToObject(rt)
+	if v := obj.Get("bubbles"); v != nil {
+		evt.setBubbles(v.ToBoolean())
+	}
+	if v := obj.Get("cancelable"); v != nil {
+		evt.setCancelable(v.ToBoolean())
+	}
+	if v := obj.Get("promise"); v != nil && !sobek.IsUndefined(v) {
+		evt.promise = v
+	} else {
+		panic(rt.NewTypeError("Failed to construct 'PromiseRejectionEvent': required member promise is undefined."))
+	}
+	if v := obj.Get("reason"); v != nil {
+		evt.reason = v
+	}
+
+	ret := rt.NewObject()
+	_ = ret.SetSymbol(symEvent, evt)
+	_ = ret.SetPrototype(call.This.ToObject(rt).Prototype())
+	return ret
+}
+
+func (p *promiseRejectionEvent) prototype(rt *sobek.Runtime) *sobek.Object {
+	proto := rt.NewObject()
+	e := rt.Get("Event")
+	if e == nil {
+		panic(rt.NewTypeError("Event is undefined"))
+	}
+	_ = proto.SetPrototype(e.ToObject(rt).Get("prototype").ToObject(rt))
+
+	_ = proto.DefineAccessorProperty("promise", rt.ToValue(p.promise), nil, sobek.FLAG_FALSE, sobek.FLAG_TRUE)
+	_ = proto.DefineAccessorProperty("reason", rt.ToValue(p.reason), nil, sobek.FLAG_FALSE, sobek.FLAG_TRUE)
+	_ = proto.SetSymbol(sobek.SymToStringTag, func(sobek.FunctionCall) sobek.Value { return rt.ToValue("PromiseRejectionEvent") })
+
+	return proto
+}
+
+func (*promiseRejectionEvent) promise(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	return toPromiseRejectionEvent(rt, call.This).Promise()
+}
+
+func (*promiseRejectionEvent) reason(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	return toPromiseRejectionEvent(rt, call.This).Reason()
+}
+
+func toPromiseRejectionEvent(rt *sobek.Runtime, value sobek.Value) PromiseRejectionEvent {
+	if evt, ok := toEvent(rt, value).(PromiseRejectionEvent); ok {
+		return evt
+	}
+	panic(rt.NewTypeError(`Value of "this" must be of type PromiseRejectionEvent`))
+}
+
+type _promiseRejectionEvent struct {
+	*_event
+	promise sobek.Value
+	reason  sobek.Value
+}
+
+func (e *_promiseRejectionEvent) Promise() sobek.Value { return e.promise }
+func (e *_promiseRejectionEvent) Reason() sobek.Value  { return e.reason }
+
+func (e *_promiseRejectionEvent) toValue(this sobek.Value, rt *sobek.Runtime) sobek.Value {
+	if this == nil {
+		this = rt.Get("PromiseRejectionEvent")
+	}
+	if this == nil {
+		panic(rt.NewTypeError("PromiseRejectionEvent is not defined"))
+	}
+	ret := rt.NewObject()
+	_ = ret.SetSymbol(symEvent, e)
+	_ = ret.SetPrototype(this.ToObject(rt).Prototype())
+	return ret
+}
@@ -0,0 +1,66 @@
+package dom
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/shiroyk/ski/js"
+	"github.com/shiroyk/ski/js/modulestest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromiseRejectionEvent(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	t.Run("unhandledrejection", func(t *testing.T) {
+		vm := modulestest.New(t)
+		_, err := vm.RunModule(ctx, `
+			const events = [];
+			addEventListener("unhandledrejection", (event) => {
+				assert.true(event instanceof PromiseRejectionEvent);
+				events.push(event.type + ":" + event.reason);
+			});
+			addEventListener("rejectionhandled", (event) => {
+				events.push(event.type + ":" + event.reason);
+				assert.equal(events.join(","), "unhandledrejection:error,rejectionhandled:error");
+			});
+			const promise = Promise.reject("error");
+			setTimeout(() => promise.catch(() => {}), 10);
+		`)
+		require.NoError(t, err)
+	})
+
+	t.Run("handled", func(t *testing.T) {
+		vm := modulestest.New(t, js.WithStrictRejections())
+		_, err := vm.RunModule(ctx, `
+			let called = false;
+			onunhandledrejection = () => { called = true };
+			const promise = Promise.reject("error");
+			Promise.resolve().then(() => promise.catch(() => {}));
+			setTimeout(() => assert.true(!called), 10);
+		`)
+		require.NoError(t, err)
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		vm := modulestest.New(t, js.WithStrictRejections())
+		_, err := vm.RunModule(ctx, `
+			Promise.reject(new Error("async error"));
+		`)
+		var rejection *js.UnhandledRejectionError
+		require.True(t, errors.As(err, &rejection))
+		assert.ErrorContains(t, err, "Uncaught (in promise) Error: async error")
+	})
+
+	t.Run("preventDefault", func(t *testing.T) {
+		vm := modulestest.New(t, js.WithStrictRejections())
+		_, err := vm.RunModule(ctx, `
+			addEventListener("unhandledrejection", (event) => event.preventDefault());
+			Promise.reject(new Error("async error"));
+		`)
+		require.NoError(t, err)
+	})
+}
@@ -18,6 +18,14 @@ func (e Ext) Instantiate(rt *sobek.Runtime) (sobek.Value, error) {
 	return ext, nil
 }
 
+// Describe returns the TypeScript declaration of the module.
+func (Ext) Describe() string {
+	return `/** The values of the VM run context. */
+export const context: Record<string, any>;
+const _default: { context: typeof context };
+export default _default;`
+}
+
 func (Ext) context(rt *sobek.Runtime) sobek.Value {
 	obj := rt.NewObject()
 	_ = obj.Set("toString", func(call sobek.FunctionCall) sobek.Value {
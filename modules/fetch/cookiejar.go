@@ -39,6 +39,32 @@ func (c *CookieJarModule) Instantiate(rt *sobek.Runtime) (sobek.Value, error) {
 	return ctor, nil
 }
 
+// Describe returns the TypeScript declaration of the module.
+func (*CookieJarModule) Describe() string {
+	return `export interface Cookie {
+	name: string;
+	value: string;
+	/** The expiration time in milliseconds since the epoch. */
+	expires?: number;
+	domain?: string;
+	path?: string;
+	secure?: boolean;
+	httpOnly?: boolean;
+	sameSite?: "lax" | "strict" | "none";
+}
+const _default: {
+	/** Returns the cookie of the url with the name, null if not exists. */
+	get(url: string, name: string): Cookie | null;
+	/** Returns the cookies of the url, filtered by the name if provided. */
+	getAll(url: string, name?: string): Cookie[];
+	/** Sets the cookie of the url. */
+	set(url: string, cookie: Cookie): void;
+	/** Removes the cookies of the url. */
+	del(url: string): void;
+};
+export default _default;`
+}
+
 func (c *CookieJarModule) get(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 	url := call.Argument(0)
 	if sobek.IsUndefined(url) || sobek.IsNull(url) {
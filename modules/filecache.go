@@ -0,0 +1,177 @@
+package modules
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// CacheMode the mode of the remote modules cache.
+type CacheMode int
+
+const (
+	// CacheDefault loads the modules from the cache, downloads the modules not cached.
+	CacheDefault CacheMode = iota
+	// CacheOffline loads the modules only from the cache.
+	CacheOffline
+	// CacheReload downloads the modules again, then updates the cache and the lockfile.
+	CacheReload
+)
+
+var (
+	// ErrIntegrity the module does not match the lockfile
+	ErrIntegrity = errors.New("integrity check failed")
+	// ErrNotCached the module is not cached in the offline mode
+	ErrNotCached = errors.New("module not cached")
+)
+
+type (
+	// fileCache caches the remote modules in the directory.
+	fileCache struct {
+		fl       FileLoader
+		dir      string
+		lockfile string
+		mode     CacheMode
+
+		mu   sync.Mutex
+		lock lockfile
+	}
+
+	// lockfile records the sha256 of the remote modules.
+	lockfile struct {
+		Version int               `json:"version"`
+		Remote  map[string]string `json:"remote"`
+	}
+)
+
+// CacheFileLoader returns a FileLoader which caches the http and https modules
+// loaded by the fl in the dir, so the modules are downloaded only once.
+// The sha256 of the modules are recorded in the lockfile, the module which does
+// not match the lockfile fails to load with ErrIntegrity. The lockfile is disabled if empty.
+func CacheFileLoader(fl FileLoader, dir, lockfile string, mode CacheMode) (FileLoader, error) {
+	c := &fileCache{fl: fl, dir: dir, lockfile: lockfile, mode: mode}
+	c.lock.Version = 1
+	c.lock.Remote = make(map[string]string)
+
+	if lockfile != "" {
+		data, err := os.ReadFile(lockfile)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			return nil, err
+		default:
+			if err = json.Unmarshal(data, &c.lock); err != nil {
+				return nil, fmt.Errorf("invalid lockfile %s: %w", lockfile, err)
+			}
+			if c.lock.Remote == nil {
+				c.lock.Remote = make(map[string]string)
+			}
+		}
+	}
+	return c.load, nil
+}
+
+func (c *fileCache) load(specifier *url.URL, name string) ([]byte, error) {
+	if specifier.Scheme != "http" && specifier.Scheme != "https" {
+		return c.fl(specifier, name)
+	}
+
+	key := specifier.String()
+	path := c.path(specifier)
+	if c.mode != CacheReload {
+		data, err := os.ReadFile(path)
+		if err == nil {
+			if err = c.verify(key, data); err != nil {
+				return nil, err
+			}
+			return data, nil
+		}
+		if c.mode == CacheOffline {
+			return nil, fmt.Errorf("%w: %s", ErrNotCached, key)
+		}
+	}
+
+	data, err := c.fl(specifier, name)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.verify(key, data); err != nil {
+		return nil, err
+	}
+	if err = writeFile(path, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// path returns the cache path of the module.
+func (c *fileCache) path(specifier *url.URL) string {
+	sum := sha256.Sum256([]byte(specifier.String()))
+	host := strings.ReplaceAll(specifier.Host, ":", "_")
+	return filepath.Join(c.dir, specifier.Scheme, host, hex.EncodeToString(sum[:]))
+}
+
+// verify checks the sha256 of the module with the lockfile,
+// records the module which not in the lockfile.
+func (c *fileCache) verify(key string, data []byte) error {
+	if c.lockfile == "" {
+		return nil
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if expected, ok := c.lock.Remote[key]; ok && c.mode != CacheReload {
+		if expected != hash {
+			return fmt.Errorf("%w: %s expected sha256 %s, got %s", ErrIntegrity, key, expected, hash)
+		}
+		return nil
+	}
+	if c.lock.Remote[key] == hash {
+		return nil
+	}
+	c.lock.Remote[key] = hash
+
+	data, err := json.MarshalIndent(c.lock, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(c.lockfile, append(data, '\n'))
+}
+
+// writeFile writes the file by renaming the temporary file, so the file is never partially written.
+func writeFile(path string, data []byte) (err error) {
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(f.Name())
+		}
+	}()
+	if _, err = f.Write(data); err != nil {
+		_ = f.Close()
+		return
+	}
+	if err = f.Chmod(0o644); err != nil {
+		_ = f.Close()
+		return
+	}
+	if err = f.Close(); err != nil {
+		return
+	}
+	return os.Rename(f.Name(), path)
+}
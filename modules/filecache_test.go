@@ -0,0 +1,92 @@
+package modules
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheFileLoader(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	cacheDir, lockPath := filepath.Join(dir, "cache"), filepath.Join(dir, "ski.lock")
+
+	calls, content := 0, "export default 1;"
+	remote := func(specifier *url.URL, _ string) ([]byte, error) {
+		if specifier.Scheme == "file" {
+			return []byte("file"), nil
+		}
+		calls++
+		return []byte(content), nil
+	}
+	specifier, _ := url.Parse("https://esm.sh/mod@1?target=es2022")
+
+	load := func(t *testing.T, mode CacheMode) ([]byte, error) {
+		fl, err := CacheFileLoader(remote, cacheDir, lockPath, mode)
+		require.NoError(t, err)
+		return fl(specifier, "")
+	}
+
+	t.Run("offline not cached", func(t *testing.T) {
+		_, err := load(t, CacheOffline)
+		assert.ErrorIs(t, err, ErrNotCached)
+		assert.Equal(t, 0, calls)
+	})
+
+	t.Run("download", func(t *testing.T) {
+		data, err := load(t, CacheDefault)
+		require.NoError(t, err)
+		assert.Equal(t, content, string(data))
+		assert.Equal(t, 1, calls)
+
+		data, err = os.ReadFile(lockPath)
+		require.NoError(t, err)
+		var lock lockfile
+		require.NoError(t, json.Unmarshal(data, &lock))
+		assert.Equal(t, map[string]string{
+			specifier.String(): "56332e0a55734bc2b73df56a2df8635ed5c5b24b6d7a456b41de7cab9a2f3814",
+		}, lock.Remote)
+	})
+
+	t.Run("cached", func(t *testing.T) {
+		for _, mode := range []CacheMode{CacheDefault, CacheOffline} {
+			data, err := load(t, mode)
+			require.NoError(t, err)
+			assert.Equal(t, content, string(data))
+		}
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("file", func(t *testing.T) {
+		fl, err := CacheFileLoader(remote, cacheDir, lockPath, CacheOffline)
+		require.NoError(t, err)
+		data, err := fl(&url.URL{Scheme: "file", Path: "main.js"}, "main.js")
+		require.NoError(t, err)
+		assert.Equal(t, "file", string(data))
+	})
+
+	t.Run("integrity", func(t *testing.T) {
+		content = "export default 2;"
+		fl, err := CacheFileLoader(remote, filepath.Join(dir, "other"), lockPath, CacheDefault)
+		require.NoError(t, err)
+		_, err = fl(specifier, "")
+		assert.ErrorIs(t, err, ErrIntegrity)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("reload", func(t *testing.T) {
+		data, err := load(t, CacheReload)
+		require.NoError(t, err)
+		assert.Equal(t, content, string(data))
+		assert.Equal(t, 3, calls)
+
+		data, err = load(t, CacheOffline)
+		require.NoError(t, err)
+		assert.Equal(t, content, string(data))
+	})
+}
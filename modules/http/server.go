@@ -37,6 +37,45 @@ func (s *Server) Instantiate(rt *sobek.Runtime) (sobek.Value, error) {
 	return ctor, nil
 }
 
+// Describe returns the TypeScript declaration of the module.
+func (*Server) Describe() string {
+	return `export type Handler = (request: Request) => Response | Promise<Response>;
+export interface ServeOptions {
+	/** The port to listen on, default 8000. */
+	port?: number;
+	/** The hostname to listen on, default "127.0.0.1". */
+	hostname?: string;
+	maxHeaderSize?: number;
+	/** The keep-alive timeout in milliseconds. */
+	keepAliveTimeout?: number;
+	/** The request timeout in milliseconds. */
+	requestTimeout?: number;
+	/** Shutdowns the server when aborted. */
+	signal?: AbortSignal;
+	handler?: Handler;
+	/** Handles the error thrown by the handler. */
+	onError?: (error: Error & { method: string; url: string; headers: Record<string, string[]> }) => Response | Promise<Response>;
+	onListen?: (addr: { hostname: string; port: number }) => void;
+}
+export interface HttpServer {
+	readonly listening: boolean;
+	readonly addr: { hostname: string; port: number };
+	readonly url: string;
+	/** Resolves when the server is finished. */
+	readonly finished: Promise<void>;
+	ref(): this;
+	unref(): this;
+	/** Gracefully shutdowns the server without interrupting any active connections. */
+	shutdown(): Promise<void>;
+	/** Immediately closes all active connections. */
+	close(): void;
+}
+function serve(handler: Handler): HttpServer;
+function serve(port: number, handler: Handler): HttpServer;
+function serve(options: ServeOptions, handler?: Handler): HttpServer;
+export default serve;`
+}
+
 func (s *Server) prototype(rt *sobek.Runtime) *sobek.Object {
 	p := rt.NewObject()
 	_ = p.DefineAccessorProperty("listening", rt.ToValue(s.listening), nil, sobek.FLAG_FALSE, sobek.FLAG_TRUE)
@@ -137,14 +176,18 @@ func (s *Server) constructor(call sobek.ConstructorCall, rt *sobek.Runtime) *sob
 		serv.onError = func(this sobek.Value, args ...sobek.Value) (sobek.Value, error) {
 			code := http.StatusInternalServerError
 			msg := http.StatusText(code)
+			attrs := []any{slog.String("source", "server")}
 			if len(args) > 0 {
 				err := args[0].ToObject(rt)
 				url := err.Get("url").String()
 				method := err.Get("method").String()
 				message := err.Get("message").String()
 				msg = fmt.Sprintf("Internal Server Error %s %s %s", method, url, message)
+				if stack := err.Get("stack"); stack != nil && sobek.IsString(stack) {
+					attrs = append(attrs, slog.String("stack", stack.String()))
+				}
 			}
-			serv.logger().Error(msg, slog.String("source", "server"))
+			serv.logger().Error(msg, attrs...)
 			return fetch.NewResponse(rt, &http.Response{
 				StatusCode: code,
 				Header:     make(http.Header),
@@ -167,6 +210,8 @@ func (s *Server) constructor(call sobek.ConstructorCall, rt *sobek.Runtime) *sob
 	serv.server.Handler = serv
 	serv.ref = js.EnqueueJob(rt)
 	ln := serv.listen()
+	// close the server when the run is finished or interrupted, so the port can be reused
+	js.Cleanup(rt, func() { _ = serv.close() })
 
 	go func() {
 		js.EnqueueJob(rt)(func() error {
@@ -355,11 +400,17 @@ func (s *httpServer) writeError(w http.ResponseWriter, r *http.Request, done fun
 		jsErr  *sobek.Object
 		result sobek.Value
 		err    error
+		ex     *sobek.Exception
+		thrown *js.Error
+		ok     bool
 	)
 
-	ex, ok := rawErr.(*sobek.Exception)
-	if ok {
+	switch {
+	case errors.As(rawErr, &ex):
 		jsErr, ok = ex.Value().(*sobek.Object)
+	case errors.As(rawErr, &thrown):
+		// keep the original error object of the rejection, with its stack
+		jsErr, ok = thrown.Value.(*sobek.Object)
 	}
 	if !ok {
 		jsErr, err = s.rt.New(s.rt.Get("Error"), s.rt.ToValue(rawErr.Error()))
@@ -418,7 +469,7 @@ func (s *httpServer) handlePromise(w http.ResponseWriter, r *http.Request, done
 		if ex, ok := p.Result().Export().(error); ok {
 			err = ex
 		} else {
-			err = errors.New(p.Result().String())
+			err = js.NewError(s.rt, p.Result())
 		}
 	case sobek.PromiseStateFulfilled:
 		if res, ok := fetch.ToResponse(p.Result()); ok {
@@ -456,7 +507,7 @@ func (s *httpServer) handlePendingPromise(w http.ResponseWriter, r *http.Request
 		if v.ExportType() == types.TypeError {
 			s.writeError(w, r, done, v.Export().(error))
 		} else {
-			s.writeError(w, r, done, errors.New(v.String()))
+			s.writeError(w, r, done, js.NewError(s.rt, v))
 		}
 		return sobek.Undefined()
 	})
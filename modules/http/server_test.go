@@ -84,6 +84,28 @@ func TestHttpServer(t *testing.T) {
 		require.NoError(t, err)
 	})
 
+	t.Run("async error handling", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(t.Context(), time.Second)
+		defer cancel()
+		_, err := vm.RunModule(ctx, `
+		class HandlerError extends Error {}
+		const s = serve({
+			handler: async (req) => { await null; throw new HandlerError("async error"); },
+			onError: (err) => {
+				assert.true(err instanceof HandlerError);
+				assert.contains("async error", err.message);
+				assert.contains("at handler", err.stack);
+				return new Response(err.message, { status: 500 });
+			}
+		});
+		const res = await fetch(s.url);
+		assert.equal(res.status, 500);
+		assert.equal(await res.text(), "async error");
+		await s.shutdown();
+		`)
+		require.NoError(t, err)
+	})
+
 	t.Run("error on onError", func(t *testing.T) {
 		ctx, cancel := context.WithTimeout(t.Context(), time.Second)
 		defer cancel()
@@ -267,4 +289,15 @@ func TestHttpServer(t *testing.T) {
 		`)
 		require.NoError(t, err)
 	})
+
+	t.Run("interrupted", func(t *testing.T) {
+		// the server is closed when the run is interrupted, so the port can be reused
+		for range 2 {
+			ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+			_, err := vm.RunModule(ctx, `serve(3001, (req) => new Response("ok"));`)
+			cancel()
+			require.Error(t, err)
+			assert.NotContains(t, err.Error(), "address already in use")
+		}
+	})
 }
@@ -0,0 +1,134 @@
+package modules
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+type (
+	// ImportMap the import map remaps the module specifiers to the addresses,
+	// see https://html.spec.whatwg.org/multipage/webappapis.html#import-maps.
+	ImportMap struct {
+		imports specifierMap
+		scopes  []importScope
+	}
+
+	// specifierMap the entries sorted by the key descending, so the longest prefix matches first.
+	specifierMap []specifierEntry
+
+	specifierEntry struct {
+		key     string
+		address *url.URL
+	}
+
+	importScope struct {
+		prefix  string
+		imports specifierMap
+	}
+)
+
+// ParseImportMap parses the import map JSON with the `imports` and `scopes`,
+// the relative specifiers and addresses are resolved against the base URL.
+func ParseImportMap(data []byte, base *url.URL) (*ImportMap, error) {
+	var raw struct {
+		Imports map[string]string            `json:"imports"`
+		Scopes  map[string]map[string]string `json:"scopes"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid import map: %w", err)
+	}
+
+	im := new(ImportMap)
+	var err error
+	if im.imports, err = parseSpecifierMap(raw.Imports, base); err != nil {
+		return nil, err
+	}
+	for prefix, imports := range raw.Scopes {
+		u, ok := parseURLLike(base, prefix)
+		if !ok {
+			u = resolveURL(base, prefix)
+		}
+		scope := importScope{prefix: u.String()}
+		if scope.imports, err = parseSpecifierMap(imports, base); err != nil {
+			return nil, err
+		}
+		im.scopes = append(im.scopes, scope)
+	}
+	sort.Slice(im.scopes, func(i, j int) bool { return im.scopes[i].prefix > im.scopes[j].prefix })
+	return im, nil
+}
+
+func parseSpecifierMap(raw map[string]string, base *url.URL) (specifierMap, error) {
+	m := make(specifierMap, 0, len(raw))
+	for key, value := range raw {
+		if key == "" {
+			return nil, fmt.Errorf("invalid import map specifier %q", key)
+		}
+		address, ok := parseURLLike(base, value)
+		if !ok {
+			return nil, fmt.Errorf("invalid import map address %q of %q", value, key)
+		}
+		if strings.HasSuffix(key, "/") && !strings.HasSuffix(address.String(), "/") {
+			return nil, fmt.Errorf("invalid import map address %q of %q, the address must end with /", value, key)
+		}
+		if u, ok := parseURLLike(base, key); ok {
+			key = u.String()
+		}
+		m = append(m, specifierEntry{key, address})
+	}
+	sort.Slice(m, func(i, j int) bool { return m[i].key > m[j].key })
+	return m, nil
+}
+
+// resolve returns the address of the specifier imported by the referrer module URL,
+// the relative specifier is resolved against the base directory. The scopes are
+// matched by the referrer, or the base directory if the referrer is nil such as the scripts.
+func (im *ImportMap) resolve(base, referrer *url.URL, specifier string) (*url.URL, bool) {
+	normalized := specifier
+	if u, ok := parseURLLike(base, specifier); ok {
+		normalized = u.String()
+	}
+
+	var ref string
+	if referrer != nil {
+		ref = referrer.String()
+	} else if ref = base.String(); !strings.HasSuffix(ref, "/") {
+		ref += "/"
+	}
+	for _, scope := range im.scopes {
+		if scope.prefix == ref || strings.HasSuffix(scope.prefix, "/") && strings.HasPrefix(ref, scope.prefix) {
+			if address, ok := scope.imports.resolve(normalized); ok {
+				return address, true
+			}
+		}
+	}
+	return im.imports.resolve(normalized)
+}
+
+func (m specifierMap) resolve(specifier string) (*url.URL, bool) {
+	for _, entry := range m {
+		switch {
+		case entry.key == specifier:
+			u := *entry.address
+			return &u, true
+		case strings.HasSuffix(entry.key, "/") && strings.HasPrefix(specifier, entry.key):
+			return resolveURL(entry.address, specifier[len(entry.key):]), true
+		}
+	}
+	return nil, false
+}
+
+// parseURLLike parses the relative or absolute URL specifier, returns false if the specifier is bare.
+// The absolute URL has the scheme, such as "https://esm.sh/react", "data:text/javascript,..." and "node:fs".
+func parseURLLike(base *url.URL, specifier string) (*url.URL, bool) {
+	if isBasePath(specifier) {
+		return resolveURL(base, specifier), true
+	}
+	if u, err := url.Parse(specifier); err == nil && u.Scheme != "" {
+		return u, true
+	}
+	return nil, false
+}
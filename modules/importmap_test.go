@@ -0,0 +1,131 @@
+package modules
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/grafana/sobek"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportMap(t *testing.T) {
+	t.Parallel()
+	base := &url.URL{Scheme: "file", Path: "/app/"}
+	im, err := ParseImportMap([]byte(`{
+  "imports": {
+    "react": "https://esm.sh/react@18",
+    "preact": "https://esm.sh/preact@10?dev",
+    "react-dom/": "https://esm.sh/react-dom@18/",
+    "lib": "./vendor/lib.js",
+    "https://esm.sh/v135/": "./vendor/esm/"
+  },
+  "scopes": {
+    "./legacy/": { "react": "https://esm.sh/react@17" },
+    "./main.js": { "react": "https://esm.sh/react@16", "node:fs": "./vendor/fs.js" },
+    "https://esm.sh/": { "lib": "https://esm.sh/lib@1" }
+  }
+}`), base)
+	require.NoError(t, err)
+
+	remote, _ := url.Parse("https://esm.sh/react@18")
+	testCases := []struct {
+		referrer  *url.URL
+		specifier string
+		expected  string
+	}{
+		{nil, "react", "https://esm.sh/react@18"},
+		{nil, "preact", "https://esm.sh/preact@10?dev"},
+		{nil, "react-dom/server", "https://esm.sh/react-dom@18/server"},
+		{nil, "lib", "file:///app/vendor/lib.js"},
+		{base.JoinPath("legacy", "page.js"), "react", "https://esm.sh/react@17"},
+		{base.JoinPath("main.js"), "react", "https://esm.sh/react@16"},
+		{base.JoinPath("main.js"), "node:fs", "file:///app/vendor/fs.js"},
+		{base.JoinPath("other.js"), "node:fs", ""},
+		{remote, "lib", "https://esm.sh/lib@1"},
+		{remote, "/v135/react.mjs", "file:///app/vendor/esm/react.mjs"},
+		{nil, "vue", ""},
+		{nil, "./react", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.specifier, func(t *testing.T) {
+			dir := base
+			if tc.referrer != nil {
+				dir = tc.referrer.JoinPath("..")
+			}
+			address, ok := im.resolve(dir, tc.referrer, tc.specifier)
+			if tc.expected == "" {
+				assert.False(t, ok)
+				return
+			}
+			require.True(t, ok)
+			assert.Equal(t, tc.expected, address.String())
+		})
+	}
+
+	t.Run("url like", func(t *testing.T) {
+		for specifier, expected := range map[string]string{
+			"./a.js":                 "file:///app/a.js",
+			"https://esm.sh/react":   "https://esm.sh/react",
+			"data:text/javascript,1": "data:text/javascript,1",
+			"node:fs":                "node:fs",
+			"react":                  "",
+			"@scope/pkg":             "",
+		} {
+			u, ok := parseURLLike(base, specifier)
+			if expected == "" {
+				assert.False(t, ok, specifier)
+				continue
+			}
+			require.True(t, ok, specifier)
+			assert.Equal(t, expected, u.String())
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, data := range []string{
+			`{"imports":{"react":"react"}}`,
+			`{"imports":{"react/":"https://esm.sh/react"}}`,
+			`{"imports":[]}`,
+		} {
+			_, err := ParseImportMap([]byte(data), base)
+			assert.Error(t, err, data)
+		}
+	})
+}
+
+func TestLoaderImportMap(t *testing.T) {
+	t.Parallel()
+	mfs := fstest.MapFS{
+		"app.js":          &fstest.MapFile{Data: []byte(`import a from "a"; import b from "pkg/b.js"; import q from "q"; export default a + b + q;`)},
+		"vendor/a.js":     &fstest.MapFile{Data: []byte(`export default "a";`)},
+		"vendor/pkg/b.js": &fstest.MapFile{Data: []byte(`export { default } from "https://cdn/c.js";`)},
+		"vendor/cdn/c.js": &fstest.MapFile{Data: []byte(`import d from "/d.js"; export default "b" + d;`)},
+		"vendor/cdn/d.js": &fstest.MapFile{Data: []byte(`export default "d";`)},
+		"vendor/d.js":     &fstest.MapFile{Data: []byte(`export default "D";`)},
+		"vendor/cdn/q.js": &fstest.MapFile{Data: []byte(`export default "q";`)},
+	}
+	var requested []string
+	fileLoader := func(specifier *url.URL, _ string) ([]byte, error) {
+		requested = append(requested, specifier.String())
+		if specifier.Scheme == "https" {
+			return mfs.ReadFile("vendor/cdn/" + strings.TrimPrefix(specifier.Path, "/"))
+		}
+		return mfs.ReadFile(strings.TrimPrefix(specifier.Path, "/"))
+	}
+	base := &url.URL{Scheme: "file", Path: "/"}
+	im, err := ParseImportMap([]byte(`{"imports":{"a":"./vendor/a.js","pkg/":"./vendor/pkg/","https://cdn/d.js":"./vendor/d.js","q":"https://cdn/q.js?dev"},"scopes":{"https://cdn/c.js":{"https://cdn/d.js":"https://cdn/d.js"}}}`), base)
+	require.NoError(t, err)
+
+	ml := NewLoader(WithBase(base), WithFileLoader(fileLoader), WithImportMap(im))
+	vm := NewTestVM(t, ml)
+	mod, err := ml.ResolveModule(nil, "./app.js")
+	require.NoError(t, err)
+	require.NoError(t, mod.Link())
+	Result(vm.CyclicModuleRecordEvaluate(mod.(sobek.CyclicModuleRecord), ml.ResolveModule))
+	assert.Equal(t, "abdq", vm.NamespaceObjectFor(mod).Get("default").String())
+	assert.Contains(t, requested, "https://cdn/q.js?dev")
+}
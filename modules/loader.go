@@ -1,7 +1,6 @@
 package modules
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -17,6 +16,7 @@ import (
 
 	"github.com/grafana/sobek"
 	"github.com/grafana/sobek/parser"
+	"github.com/shiroyk/ski/modules/transform"
 )
 
 var (
@@ -49,6 +49,11 @@ type (
 		InitGlobal(*sobek.Runtime) Loader
 		// SetFileLoader set the FileLoader.
 		SetFileLoader(fl FileLoader)
+		// Invalidate removes the module and the modules which import it from the cache,
+		// so they are loaded again. The specifier is the module URL or the path relative to the base.
+		Invalidate(specifier string)
+		// InvalidateAll removes all modules from the cache.
+		InvalidateAll()
 	}
 
 	// Option the new Loader options.
@@ -73,8 +78,33 @@ func WithSourceMapLoader(fn func(path string) ([]byte, error)) Option {
 	return func(o *loader) { o.sourceLoader = parser.WithSourceMapLoader(fn) }
 }
 
+// WithDisableSourceMaps disables the source maps of module loader.
+func WithDisableSourceMaps() Option {
+	return func(o *loader) { o.sourceLoader = parser.WithDisableSourceMaps }
+}
+
+// WithJSX the JSX options of the .jsx and .tsx modules.
+func WithJSX(jsx transform.JSX) Option {
+	return func(o *loader) { o.transform.JSX = jsx }
+}
+
+// WithImportMap the import map of module loader, the specifiers are remapped
+// before resolving the node_modules and the URL.
+func WithImportMap(im *ImportMap) Option {
+	return func(o *loader) { o.importMap = im }
+}
+
+// WithConditions the extra conditions of the package.json exports and imports,
+// such as ski or worker, the import, require and default conditions are always matched.
+func WithConditions(conditions ...string) Option {
+	return func(o *loader) { o.conditions = conditions }
+}
+
 // NewLoader returns a new module resolver
 // if the fileLoader option not provided, uses the default DefaultFileLoader.
+// The source maps of the `//# sourceMappingURL=` comment are loaded by the FileLoader,
+// or decoded from the inline data URL, so the stack traces report the original positions.
+// The .ts, .mts, .cts, .tsx and .jsx modules are transformed to JavaScript with the inline source maps.
 func NewLoader(opts ...Option) Loader {
 	ml := new(loader)
 
@@ -89,8 +119,10 @@ func NewLoader(opts ...Option) Loader {
 		ml.fileLoader = DefaultFileLoader(http.DefaultClient.Do)
 	}
 	if ml.sourceLoader == nil {
-		ml.sourceLoader = parser.WithDisableSourceMaps
+		ml.sourceLoader = parser.WithSourceMapLoader(ml.loadSourceMap)
 	}
+	ml.importConditions = append([]string{"import", "default"}, ml.conditions...)
+	ml.requireConditions = append([]string{"require", "default"}, ml.conditions...)
 	return ml
 }
 
@@ -109,6 +141,9 @@ func DefaultFileLoader(fetch func(*http.Request) (*http.Response, error)) FileLo
 				return nil, err
 			}
 			defer res.Body.Close()
+			if res.StatusCode >= http.StatusBadRequest {
+				return nil, fmt.Errorf("failed to load %s: %s", specifier, res.Status)
+			}
 			return io.ReadAll(res.Body)
 		case "file":
 			return os.ReadFile(specifier.Path)
@@ -125,6 +160,11 @@ type (
 		reverse      sync.Map
 		goModules    sync.Map
 		cacheModules sync.Map
+		names        sync.Map // the module to the cache key
+
+		mu         sync.Mutex
+		dependents map[string]map[string]struct{} // the cache key to the modules import it
+		watcher    *watcher
 
 		globalOnce sync.Once
 		globals    map[string]string
@@ -133,6 +173,12 @@ type (
 
 		base         *url.URL
 		sourceLoader parser.Option
+		transform    transform.Options
+		importMap    *ImportMap
+
+		conditions        []string
+		importConditions  []string
+		requireConditions []string
 	}
 
 	moduleCache struct {
@@ -289,7 +335,7 @@ func (ml *loader) createInstance(rt *sobek.Runtime, namespace, name string) sobe
 // require resolve the module instance.
 func (ml *loader) require(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 	name := call.Argument(0).String()
-	mod, err := ml.ResolveModule(ml.getCurrentModuleRecord(rt), name)
+	mod, err := ml.resolveModule(ml.getCurrentModuleRecord(rt), name, ml.requireConditions)
 	if err != nil {
 		throwError(rt, err)
 	}
@@ -330,6 +376,11 @@ func (ml *loader) getCurrentModuleRecord(rt *sobek.Runtime) sobek.ModuleRecord {
 
 // ResolveModule resolve the module returns the sobek.ModuleRecord.
 func (ml *loader) ResolveModule(referencingScriptOrModule any, name string) (sobek.ModuleRecord, error) {
+	return ml.resolveModule(referencingScriptOrModule, name, ml.importConditions)
+}
+
+// resolveModule resolve the module with the package.json exports and imports conditions.
+func (ml *loader) resolveModule(referencingScriptOrModule any, name string, conditions []string) (sobek.ModuleRecord, error) {
 	switch {
 	case strings.HasPrefix(name, prefix):
 		if mod, ok := ml.resolveGo(name); ok {
@@ -342,15 +393,94 @@ func (ml *loader) ResolveModule(referencingScriptOrModule any, name string) (sob
 		}
 		fallthrough
 	default:
-		return ml.resolve(ml.reversePath(referencingScriptOrModule), name)
+		mod, err := ml.resolve(ml.reversePath(referencingScriptOrModule), ml.referrer(referencingScriptOrModule), name, conditions)
+		if err == nil {
+			ml.addDependent(referencingScriptOrModule, mod)
+		}
+		return mod, err
+	}
+}
+
+// addDependent records the referencing module imports the module.
+func (ml *loader) addDependent(referencingScriptOrModule any, mod sobek.ModuleRecord) {
+	ref, ok := referencingScriptOrModule.(sobek.ModuleRecord)
+	if !ok {
+		return
+	}
+	dependent, ok := ml.names.Load(ref)
+	if !ok {
+		return
+	}
+	name, ok := ml.names.Load(mod)
+	if !ok {
+		return
+	}
+
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+	if ml.dependents == nil {
+		ml.dependents = make(map[string]map[string]struct{})
+	}
+	if ml.dependents[name.(string)] == nil {
+		ml.dependents[name.(string)] = make(map[string]struct{})
 	}
+	ml.dependents[name.(string)][dependent.(string)] = struct{}{}
 }
 
-func (ml *loader) resolve(base *url.URL, specifier string) (sobek.ModuleRecord, error) {
+// Invalidate removes the module and the modules which import it from the cache,
+// so they are loaded again. The specifier is the module URL or the path relative to the base.
+func (ml *loader) Invalidate(specifier string) {
+	name := specifier
+	if _, ok := ml.cacheModules.Load(name); !ok {
+		if u, ok := parseURLLike(ml.base, specifier); ok {
+			name = u.String()
+		} else {
+			name = resolveURL(ml.base, specifier).String()
+		}
+	}
+
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+	ml.invalidate(name)
+}
+
+func (ml *loader) invalidate(name string) {
+	if cache, ok := ml.cacheModules.LoadAndDelete(name); ok {
+		if mod := cache.(moduleCache).mod; mod != nil {
+			ml.reverse.Delete(mod)
+			ml.names.Delete(mod)
+		}
+	}
+	dependents := ml.dependents[name]
+	delete(ml.dependents, name)
+	for dependent := range dependents {
+		ml.invalidate(dependent)
+	}
+}
+
+// InvalidateAll removes all modules from the cache.
+func (ml *loader) InvalidateAll() {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+	ml.cacheModules.Clear()
+	ml.reverse.Clear()
+	ml.names.Clear()
+	clear(ml.dependents)
+}
+
+// resolve resolves the specifier imported by the referrer module URL in the base directory,
+// the referrer is nil if it is not imported by a module.
+func (ml *loader) resolve(base, referrer *url.URL, specifier string, conditions []string) (sobek.ModuleRecord, error) {
 	if specifier == "" {
 		return nil, ErrIllegalModuleName
 	}
 
+	if ml.importMap != nil {
+		if address, ok := ml.importMap.resolve(base, referrer, specifier); ok {
+			return ml.loadURL(address, "")
+		}
+	}
+
 	if isBasePath(specifier) {
 		return ml.loadAsFileOrDirectory(base, specifier)
 	}
@@ -360,10 +490,14 @@ func (ml *loader) resolve(base *url.URL, specifier string) (sobek.ModuleRecord,
 		if err != nil {
 			return nil, err
 		}
-		return ml.loadModule(uri, "")
+		return ml.loadURL(uri, "")
+	}
+
+	if strings.HasPrefix(specifier, "#") {
+		return ml.loadPackageImports(base, specifier, conditions)
 	}
 
-	return ml.loadNodeModules(base, specifier)
+	return ml.loadNodeModules(base, specifier, conditions)
 }
 
 func (ml *loader) resolveGo(specifier string) (sobek.ModuleRecord, bool) {
@@ -378,6 +512,23 @@ func (ml *loader) resolveGo(specifier string) (sobek.ModuleRecord, bool) {
 	return nil, false
 }
 
+// referrer returns the URL of the referencing module, nil if it is not a loaded module.
+func (ml *loader) referrer(referencingScriptOrModule any) *url.URL {
+	mod, ok := referencingScriptOrModule.(sobek.ModuleRecord)
+	if !ok {
+		return nil
+	}
+	name, ok := ml.names.Load(mod)
+	if !ok {
+		return nil
+	}
+	u, err := url.Parse(name.(string))
+	if err != nil || u.Scheme == "file" && u.Path == "-" {
+		return nil
+	}
+	return u
+}
+
 func (ml *loader) reversePath(referencingScriptOrModule any) *url.URL {
 	mod, ok := referencingScriptOrModule.(sobek.ModuleRecord)
 	if !ok {
@@ -407,36 +558,42 @@ func (ml *loader) loadAsFileOrDirectory(base *url.URL, specifier string) (sobek.
 	return mod, nil
 }
 
+var (
+	// extensions the extensions tried to load the file specifier.
+	extensions = []string{".js", ".ts", ".tsx", ".jsx", ".json"}
+	// remoteExtensions the extensions tried for the http(s) base,
+	// every candidate of the remote module is a request.
+	remoteExtensions = []string{".js", ".json"}
+)
+
 func (ml *loader) loadAsFile(base *url.URL, specifier string) (module sobek.ModuleRecord, err error) {
-	if module, err = ml.loadModule(base, specifier); err == nil {
-		return
-	}
-	if isSyntaxError(err) {
-		return nil, err
-	}
-	if module, err = ml.loadModule(base, specifier+".js"); err == nil {
-		return
-	}
-	if isSyntaxError(err) {
-		return nil, err
+	remote := base.Scheme == "http" || base.Scheme == "https"
+	exts := extensions
+	if remote {
+		exts = remoteExtensions
+	}
+	specifiers := []string{specifier}
+	for _, ext := range exts {
+		specifiers = append(specifiers, specifier+ext)
+	}
+	// the TypeScript imports the .ts module by the .js extension
+	if name, ok := strings.CutSuffix(specifier, ".js"); ok && !remote {
+		specifiers = append(specifiers, name+".ts", name+".tsx")
+	}
+	for _, name := range specifiers {
+		if module, err = ml.loadModule(base, name); err == nil || isSyntaxError(err) {
+			return
+		}
 	}
-	return ml.loadModule(base, specifier+".json")
+	return
 }
 
 func (ml *loader) loadAsDirectory(base *url.URL) (mod sobek.ModuleRecord, err error) {
-	buf, err := ml.fileLoader(base.JoinPath("package.json"), "package.json")
+	pkg, err := ml.readPackage(base)
 	if err != nil {
 		return ml.loadModule(base, "index.js")
 	}
 
-	var pkg struct {
-		Main   string `json:"main"`
-		Module string `json:"module"`
-	}
-	if err = json.Unmarshal(buf, &pkg); err != nil {
-		return ml.loadModule(base, "index.js")
-	}
-
 	for _, entry := range []string{pkg.Module, pkg.Main} {
 		if len(entry) > 0 {
 			if mod, err = ml.loadAsFile(base, entry); err != nil {
@@ -453,7 +610,8 @@ func (ml *loader) loadAsDirectory(base *url.URL) (mod sobek.ModuleRecord, err er
 	return ml.loadModule(base, "index.js")
 }
 
-func (ml *loader) loadNodeModules(base *url.URL, specifier string) (mod sobek.ModuleRecord, err error) {
+func (ml *loader) loadNodeModules(base *url.URL, specifier string, conditions []string) (mod sobek.ModuleRecord, err error) {
+	name, subpath := packageName(specifier)
 	start := base.Path
 	u := *base
 	nodeModules := &u
@@ -465,6 +623,12 @@ func (ml *loader) loadNodeModules(base *url.URL, specifier string) (mod sobek.Mo
 			nodeModules.Path = start
 		}
 
+		// the exports of package.json take precedence over the files
+		dir := nodeModules.JoinPath(name)
+		if pkg, err := ml.readPackage(dir); err == nil && pkg.Exports.v != nil {
+			return ml.loadPackageExports(dir, specifier, subpath, pkg.Exports.v, conditions)
+		}
+
 		mod, err = ml.loadAsFileOrDirectory(nodeModules, specifier)
 		if mod != nil || isSyntaxError(err) {
 			return mod, err
@@ -483,26 +647,63 @@ func (ml *loader) loadNodeModules(base *url.URL, specifier string) (mod sobek.Mo
 	return nil, fmt.Errorf("%w '%s'", ErrNotFoundModule, specifier)
 }
 
-func (ml *loader) loadModule(base *url.URL, specifier string) (sobek.ModuleRecord, error) {
-	var (
-		absolute *url.URL
-		query    string
-	)
-
-	if path, raw, ok := strings.Cut(specifier, "?"); ok {
-		specifier = path
-		query = raw
-	}
+// resolveURL resolves the specifier against the base URL.
+func resolveURL(base *url.URL, specifier string) *url.URL {
+	specifier, query, _ := strings.Cut(specifier, "?")
 
+	var absolute *url.URL
 	if strings.HasPrefix(specifier, "/") {
 		u := *base
 		u.Path = specifier[1:]
-		u.RawQuery = query
 		absolute = &u
 	} else {
 		absolute = base.JoinPath(specifier)
-		absolute.RawQuery = query
 	}
+	absolute.RawQuery = query
+	return absolute
+}
+
+// loadPackageExports loads the subpath of the package by the exports of package.json.
+func (ml *loader) loadPackageExports(dir *url.URL, specifier, subpath string, exports any, conditions []string) (sobek.ModuleRecord, error) {
+	target, ok := resolvePackageExports(exports, subpath, conditions)
+	if !ok || target == "" {
+		return nil, fmt.Errorf("%w '%s': subpath '%s' is not exported", ErrNotFoundModule, specifier, subpath)
+	}
+	return ml.loadModule(dir, target)
+}
+
+// loadPackageImports resolves the #specifier by the imports of the nearest package.json.
+func (ml *loader) loadPackageImports(base *url.URL, specifier string, conditions []string) (sobek.ModuleRecord, error) {
+	dir := *base
+	for {
+		if pkg, err := ml.readPackage(&dir); err == nil {
+			target, ok := resolvePackageImports(pkg.Imports.v, specifier, conditions)
+			if !ok || target == "" {
+				break
+			}
+			if strings.HasPrefix(target, "./") {
+				return ml.loadModule(&dir, target)
+			}
+			return ml.resolve(&dir, nil, target, conditions)
+		}
+
+		parent := filepath.Dir(dir.Path)
+		if parent == dir.Path {
+			break
+		}
+		dir.Path = parent
+	}
+	return nil, fmt.Errorf("%w '%s': not defined by the package imports", ErrNotFoundModule, specifier)
+}
+
+func (ml *loader) loadModule(base *url.URL, specifier string) (sobek.ModuleRecord, error) {
+	absolute := resolveURL(base, specifier)
+	specifier, _, _ = strings.Cut(specifier, "?")
+	return ml.loadURL(absolute, specifier)
+}
+
+// loadURL loads the module of the absolute URL, the query of the URL is kept.
+func (ml *loader) loadURL(absolute *url.URL, specifier string) (sobek.ModuleRecord, error) {
 	filename := absolute.String()
 
 	cache, exists := ml.cacheModules.Load(filename)
@@ -515,21 +716,50 @@ func (ml *loader) loadModule(base *url.URL, specifier string) (sobek.ModuleRecor
 	if err != nil {
 		return nil, err
 	}
+	if ml.watcher != nil && absolute.Scheme == "file" {
+		ml.watcher.add(ml, filename, absolute.Path)
+	}
 
 	mod, err := ml.CompileModule(filename, string(buf))
 	if err == nil {
 		ml.reverse.Store(mod, absolute.JoinPath(".."))
+		ml.names.Store(mod, filename)
 	}
 	ml.cacheModules.Store(filename, moduleCache{mod: mod, err: err})
 	return mod, err
 }
 
+// loadSourceMap loads the source map by the FileLoader, the source map
+// which failed to load is ignored instead of failing the module.
+func (ml *loader) loadSourceMap(path string) ([]byte, error) {
+	u, err := url.Parse(path)
+	if err != nil {
+		return nil, nil
+	}
+	if u.Scheme == "" {
+		u.Scheme = "file"
+	}
+	data, err := ml.fileLoader(u, filepath.Base(u.Path))
+	if err != nil {
+		slog.Debug("failed to load source map", "path", path, "error", err)
+		return nil, nil
+	}
+	return data, nil
+}
+
 func (ml *loader) CompileModule(name, source string) (sobek.CyclicModuleRecord, error) {
 	if filepath.Ext(name) == ".json" {
 		source = "module.exports = JSON.parse('" + template.JSEscapeString(source) + "')"
 		return ml.compileCjsModule(name, source)
 	}
 
+	if path, _, _ := strings.Cut(name, "?"); transform.Supported(path) {
+		var err error
+		if source, err = transform.Transform(path, source, ml.transform); err != nil {
+			return nil, err
+		}
+	}
+
 	ast, err := sobek.Parse(name, source, parser.IsModule, ml.sourceLoader)
 	if err != nil {
 		return nil, err
@@ -589,6 +819,9 @@ func throwError(rt *sobek.Runtime, err error) sobek.Value {
 }
 
 func isSyntaxError(err error) bool {
-	_, ok := err.(*sobek.CompilerSyntaxError)
-	return ok
+	switch err.(type) {
+	case *sobek.CompilerSyntaxError, *transform.SyntaxError:
+		return true
+	}
+	return false
 }
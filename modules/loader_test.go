@@ -1,8 +1,11 @@
 package modules
 
 import (
+	"encoding/base64"
 	"fmt"
+	"io/fs"
 	"net/url"
+	"strings"
 	"testing"
 	"testing/fstest"
 
@@ -378,3 +381,155 @@ func Result(promise *sobek.Promise) sobek.Value {
 		panic("unexpected promise state")
 	}
 }
+
+func TestSourceMap(t *testing.T) {
+	// app.ts line 10 column 4 is mapped to app.js line 2 column 2
+	sourceMap := `{"version":3,"sources":["app.ts"],"names":[],"mappings":"AAAA;EASI"}`
+	source := "export default function fail() {\n  throw new Error(\"boom\");\n}\n"
+	inline := "data:application/json;base64," + base64.StdEncoding.EncodeToString([]byte(sourceMap))
+
+	mfs := fstest.MapFS{
+		"src/app.js":     &fstest.MapFile{Data: []byte(source + "//# sourceMappingURL=app.js.map")},
+		"src/app.js.map": &fstest.MapFile{Data: []byte(sourceMap)},
+		"src/inline.js":  &fstest.MapFile{Data: []byte(source + "//# sourceMappingURL=" + inline)},
+		"src/missing.js": &fstest.MapFile{Data: []byte(source + "//# sourceMappingURL=missing.js.map")},
+	}
+	fileLoader := func(specifier *url.URL, _ string) ([]byte, error) {
+		return mfs.ReadFile(strings.TrimPrefix(specifier.Path, "/"))
+	}
+
+	stack := func(t *testing.T, ml Loader, name string) string {
+		vm := NewTestVM(t, ml)
+		mod, err := ml.ResolveModule(nil, name)
+		require.NoError(t, err)
+		require.NoError(t, mod.Link())
+		Result(vm.CyclicModuleRecordEvaluate(mod.(sobek.CyclicModuleRecord), ml.ResolveModule))
+		fail, ok := sobek.AssertFunction(vm.NamespaceObjectFor(mod).Get("default"))
+		require.True(t, ok)
+		_, err = fail(sobek.Undefined())
+		var ex *sobek.Exception
+		require.ErrorAs(t, err, &ex)
+		return ex.Value().ToObject(vm).Get("stack").String()
+	}
+
+	base := &url.URL{Scheme: "file", Path: "/"}
+
+	t.Run("file", func(t *testing.T) {
+		ml := NewLoader(WithBase(base), WithFileLoader(fileLoader))
+		assert.Contains(t, stack(t, ml, "./src/app.js"), "/src/app.ts:10:")
+	})
+
+	t.Run("inline", func(t *testing.T) {
+		ml := NewLoader(WithBase(base), WithFileLoader(fileLoader))
+		assert.Contains(t, stack(t, ml, "./src/inline.js"), "/src/app.ts:10:")
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		ml := NewLoader(WithBase(base), WithFileLoader(fileLoader))
+		assert.Contains(t, stack(t, ml, "./src/missing.js"), "/src/missing.js:2:")
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		ml := NewLoader(WithBase(base), WithFileLoader(fileLoader), WithDisableSourceMaps())
+		assert.Contains(t, stack(t, ml, "./src/app.js"), "/src/app.js:2:")
+	})
+}
+
+func TestTransform(t *testing.T) {
+	mfs := fstest.MapFS{
+		"src/app.ts": &fstest.MapFile{Data: []byte(`import type { Item } from "./item.js";
+import { render } from "./view";
+
+enum Level { Info = 1, Error }
+
+export default function fail(items: Item[] = []): never {
+  throw new Error(render(Level.Error));
+}
+`)},
+		"src/item.ts": &fstest.MapFile{Data: []byte(`export interface Item { name: string }`)},
+		"src/view.tsx": &fstest.MapFile{Data: []byte(`/** @jsx h */
+const h = (type: string, props: any, ...children: any[]) => ` + "`<${type}>${children.join(\"\")}</${type}>`" + `;
+export const render = (level: number) => <b>level {level}</b>;
+`)},
+		"src/broken.ts": &fstest.MapFile{Data: []byte(`namespace A {}`)},
+	}
+	fileLoader := func(specifier *url.URL, _ string) ([]byte, error) {
+		return mfs.ReadFile(strings.TrimPrefix(specifier.Path, "/"))
+	}
+	ml := NewLoader(WithBase(&url.URL{Scheme: "file", Path: "/"}), WithFileLoader(fileLoader))
+
+	t.Run("stack", func(t *testing.T) {
+		vm := NewTestVM(t, ml)
+		mod, err := ml.ResolveModule(nil, "./src/app")
+		require.NoError(t, err)
+		require.NoError(t, mod.Link())
+		Result(vm.CyclicModuleRecordEvaluate(mod.(sobek.CyclicModuleRecord), ml.ResolveModule))
+		fail, ok := sobek.AssertFunction(vm.NamespaceObjectFor(mod).Get("default"))
+		require.True(t, ok)
+		_, err = fail(sobek.Undefined())
+		var ex *sobek.Exception
+		require.ErrorAs(t, err, &ex)
+		obj := ex.Value().ToObject(vm)
+		assert.Equal(t, "<b>level 2</b>", obj.Get("message").String())
+		assert.Contains(t, obj.Get("stack").String(), "/src/app.ts:7:")
+	})
+
+	t.Run("syntax error", func(t *testing.T) {
+		_, err := ml.ResolveModule(nil, "./src/broken")
+		assert.ErrorContains(t, err, "Namespaces are not supported")
+	})
+
+	t.Run("remote", func(t *testing.T) {
+		var requested []string
+		ml := NewLoader(WithBase(&url.URL{Scheme: "https", Host: "example.com", Path: "/"}),
+			WithFileLoader(func(specifier *url.URL, _ string) ([]byte, error) {
+				requested = append(requested, specifier.Path)
+				return nil, fs.ErrNotExist
+			}))
+		_, err := ml.ResolveModule(nil, "./lib.js")
+		require.Error(t, err)
+		assert.NotContains(t, requested, "/lib.ts")
+		assert.NotContains(t, requested, "/lib.js.tsx")
+		assert.Subset(t, requested, []string{"/lib.js", "/lib.js.js", "/lib.js.json"})
+	})
+}
+
+func TestInvalidate(t *testing.T) {
+	t.Parallel()
+	mfs := fstest.MapFS{
+		"main.js":   &fstest.MapFile{Data: []byte(`import { a } from "./a.js"; export default a;`)},
+		"a.js":      &fstest.MapFile{Data: []byte(`export { b as a } from "./b.js";`)},
+		"b.js":      &fstest.MapFile{Data: []byte(`export const b = 1;`)},
+		"broken.js": &fstest.MapFile{Data: []byte(`export default {`)},
+	}
+	ml := NewLoader(WithBase(&url.URL{Scheme: "file", Path: "/"}),
+		WithFileLoader(func(specifier *url.URL, _ string) ([]byte, error) {
+			return mfs.ReadFile(strings.TrimPrefix(specifier.Path, "/"))
+		}))
+	value := func(t *testing.T, name string) any {
+		vm := NewTestVM(t, ml)
+		mod, err := ml.ResolveModule(nil, name)
+		require.NoError(t, err)
+		require.NoError(t, mod.Link())
+		Result(vm.CyclicModuleRecordEvaluate(mod.(sobek.CyclicModuleRecord), ml.ResolveModule))
+		return vm.NamespaceObjectFor(mod).Get("default").Export()
+	}
+
+	assert.EqualValues(t, 1, value(t, "./main.js"))
+	mfs["b.js"].Data = []byte(`export const b = 2;`)
+	assert.EqualValues(t, 1, value(t, "./main.js"))
+
+	// the dependents of b.js are invalidated
+	ml.Invalidate("./b.js")
+	assert.EqualValues(t, 2, value(t, "./main.js"))
+
+	mfs["a.js"].Data = []byte(`export const a = 3;`)
+	ml.InvalidateAll()
+	assert.EqualValues(t, 3, value(t, "./main.js"))
+
+	_, err := ml.ResolveModule(nil, "./broken.js")
+	require.Error(t, err)
+	mfs["broken.js"].Data = []byte(`export default 4;`)
+	ml.Invalidate("file:///broken.js")
+	assert.EqualValues(t, 4, value(t, "./broken.js"))
+}
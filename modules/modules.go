@@ -2,6 +2,7 @@ package modules
 
 import (
 	"maps"
+	"strings"
 	"sync"
 
 	"github.com/grafana/sobek"
@@ -55,7 +56,8 @@ func (m ModuleFunc) Instantiate(rt *sobek.Runtime) (sobek.Value, error) {
 }
 
 // Register registers a Module with the given name and implementation.
-// If the module is not a Global module, the name will be prefixed with "ski/".
+// If the module is not a Global module, the name will be prefixed with "ski/",
+// except the Node.js compatible modules which names start with "node:".
 // The registered modules can later be imported in JavaScript code by name.
 //
 // Example:
@@ -63,6 +65,9 @@ func (m ModuleFunc) Instantiate(rt *sobek.Runtime) (sobek.Value, error) {
 //	// Register a regular module that must be imported as "ski/mymodule"
 //	modules.Register("mymodule", new(MyModule))
 //
+//	// Register a Node.js compatible module that must be imported as "node:mymodule"
+//	modules.Register("node:mymodule", new(MyModule))
+//
 //	// Register a global module that can be lazily instantiated
 //	modules.Register("sleep", modules.Global{
 //		"sleep": modules.ModuleFunc(func(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
@@ -74,7 +79,9 @@ func Register(name string, mod Module) {
 	switch mod.(type) {
 	case Global:
 	default:
-		name = prefix + name
+		if !strings.HasPrefix(name, nodePrefix) {
+			name = prefix + name
+		}
 	}
 	registry.Lock()
 	registry.native[name] = mod
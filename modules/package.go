@@ -0,0 +1,199 @@
+package modules
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+type (
+	// packageJSON the package.json fields used to resolve the module.
+	packageJSON struct {
+		Main    string    `json:"main"`
+		Module  string    `json:"module"`
+		Exports jsonValue `json:"exports"`
+		Imports jsonValue `json:"imports"`
+	}
+
+	// jsonValue the JSON value keeps the keys order of the object,
+	// since the conditions are matched in the object order.
+	jsonValue struct{ v any }
+
+	jsonObject struct {
+		keys   []string
+		values map[string]any
+	}
+)
+
+func (j *jsonValue) UnmarshalJSON(data []byte) (err error) {
+	j.v, err = decodeJSON(json.NewDecoder(bytes.NewReader(data)))
+	return
+}
+
+func decodeJSON(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		obj := &jsonObject{values: make(map[string]any)}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := obj.values[key.(string)]; !ok {
+				obj.keys = append(obj.keys, key.(string))
+			}
+			obj.values[key.(string)] = value
+		}
+		_, err = dec.Token()
+		return obj, err
+	case json.Delim('['):
+		arr := make([]any, 0)
+		for dec.More() {
+			value, err := decodeJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, value)
+		}
+		_, err = dec.Token()
+		return arr, err
+	default:
+		return tok, nil
+	}
+}
+
+// readPackage reads the package.json in the directory.
+func (ml *loader) readPackage(dir *url.URL) (*packageJSON, error) {
+	buf, err := ml.fileLoader(dir.JoinPath("package.json"), "package.json")
+	if err != nil {
+		return nil, err
+	}
+	pkg := new(packageJSON)
+	if err = json.Unmarshal(buf, pkg); err != nil {
+		return nil, err
+	}
+	return pkg, nil
+}
+
+// packageName splits the bare specifier to the package name and the subpath.
+func packageName(specifier string) (name, subpath string) {
+	n := 1
+	if strings.HasPrefix(specifier, "@") {
+		n = 2
+	}
+	parts := strings.SplitN(specifier, "/", n+1)
+	if len(parts) <= n {
+		return specifier, "."
+	}
+	return strings.Join(parts[:n], "/"), "./" + parts[n]
+}
+
+// resolvePackageExports resolves the subpath by the package.json exports,
+// see https://nodejs.org/api/packages.html#subpath-exports.
+func resolvePackageExports(exports any, subpath string, conditions []string) (string, bool) {
+	if obj, ok := exports.(*jsonObject); !ok || len(obj.keys) == 0 || !strings.HasPrefix(obj.keys[0], ".") {
+		// the exports is the main entry or the conditions of the main entry
+		if subpath != "." {
+			return "", false
+		}
+		return resolvePackageTarget(exports, "", conditions, false)
+	}
+	return resolvePackageMap(exports.(*jsonObject), subpath, conditions, false)
+}
+
+// resolvePackageImports resolves the #specifier by the package.json imports,
+// see https://nodejs.org/api/packages.html#subpath-imports.
+func resolvePackageImports(imports any, specifier string, conditions []string) (string, bool) {
+	obj, ok := imports.(*jsonObject)
+	if !ok {
+		return "", false
+	}
+	return resolvePackageMap(obj, specifier, conditions, true)
+}
+
+// resolvePackageMap matches the key exactly, or the pattern key with the longest prefix.
+func resolvePackageMap(obj *jsonObject, subpath string, conditions []string, imports bool) (string, bool) {
+	if target, ok := obj.values[subpath]; ok && !strings.Contains(subpath, "*") {
+		return resolvePackageTarget(target, "", conditions, imports)
+	}
+
+	var bestKey, bestMatch string
+	for _, key := range obj.keys {
+		prefix, suffix, ok := strings.Cut(key, "*")
+		if !ok || strings.Contains(suffix, "*") {
+			continue
+		}
+		if len(subpath) < len(key)-1 || subpath == prefix ||
+			!strings.HasPrefix(subpath, prefix) || !strings.HasSuffix(subpath, suffix) {
+			continue
+		}
+		if bestKey == "" || len(prefix) > strings.Index(bestKey, "*") ||
+			len(prefix) == strings.Index(bestKey, "*") && len(key) > len(bestKey) {
+			bestKey = key
+			bestMatch = subpath[len(prefix) : len(subpath)-len(suffix)]
+		}
+	}
+	if bestKey == "" || invalidSegment(bestMatch) {
+		return "", false
+	}
+	return resolvePackageTarget(obj.values[bestKey], bestMatch, conditions, imports)
+}
+
+// resolvePackageTarget resolves the target with the pattern match, the empty target
+// with true means the subpath is excluded by the null target.
+func resolvePackageTarget(target any, match string, conditions []string, imports bool) (string, bool) {
+	switch t := target.(type) {
+	case nil:
+		return "", true
+	case string:
+		if !strings.HasPrefix(t, "./") {
+			// the imports target may be a package
+			if !imports || isBasePath(t) || strings.HasPrefix(t, "#") {
+				return "", false
+			}
+		} else if invalidSegment(t[2:]) {
+			// the target must stay inside the package
+			return "", false
+		}
+		return strings.ReplaceAll(t, "*", match), true
+	case []any:
+		for _, v := range t {
+			if resolved, ok := resolvePackageTarget(v, match, conditions, imports); ok && resolved != "" {
+				return resolved, true
+			}
+		}
+	case *jsonObject:
+		for _, key := range t.keys {
+			if slices.Contains(conditions, key) {
+				if resolved, ok := resolvePackageTarget(t.values[key], match, conditions, imports); ok {
+					return resolved, true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// invalidSegment returns true if the path has the ".", ".." or "node_modules" segment,
+// case insensitive and including the percent encoded variants.
+func invalidSegment(path string) bool {
+	for _, seg := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if unescaped, err := url.PathUnescape(seg); err == nil {
+			seg = unescaped
+		}
+		if seg == "." || seg == ".." || strings.EqualFold(seg, "node_modules") {
+			return true
+		}
+	}
+	return false
+}
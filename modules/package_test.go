@@ -0,0 +1,146 @@
+package modules
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackageExports(t *testing.T) {
+	t.Parallel()
+	var pkg packageJSON
+	require.NoError(t, json.Unmarshal([]byte(`{
+  "exports": {
+    ".": { "require": "./index.cjs", "import": { "ski": "./index.ski.js", "default": "./index.js" } },
+    "./feature": [{ "worker": "./feature.worker.js" }, "./feature.js"],
+    "./utils/*": "./lib/utils/*.js",
+    "./utils/internal/*": null,
+    "./utils/*.css": "./style/*.css",
+    "./invalid": "../outside.js",
+    "./escape": "./lib/../../outside.js",
+    "./nested": "./node_modules/dep/index.js",
+    "./encoded": "./lib/%2e%2e/index.js"
+  },
+  "imports": {
+    "#dep": { "ski": "dep-ski", "default": "./dep.js" },
+    "#src/*": "./src/*.js"
+  }
+}`), &pkg))
+
+	testCases := []struct {
+		subpath    string
+		conditions []string
+		expected   string
+	}{
+		{".", []string{"import", "default"}, "./index.js"},
+		{".", []string{"import", "default", "ski"}, "./index.ski.js"},
+		{".", []string{"require", "default", "ski"}, "./index.cjs"},
+		{"./feature", []string{"import", "default"}, "./feature.js"},
+		{"./feature", []string{"import", "default", "worker"}, "./feature.worker.js"},
+		{"./utils/a/b", []string{"default"}, "./lib/utils/a/b.js"},
+		{"./utils/a.css", []string{"default"}, "./style/a.css"},
+		{"./utils/internal/a", []string{"default"}, ""},
+		{"./invalid", []string{"default"}, ""},
+		{"./escape", []string{"default"}, ""},
+		{"./nested", []string{"default"}, ""},
+		{"./encoded", []string{"default"}, ""},
+		{"./utils/../../outside", []string{"default"}, ""},
+		{"./utils/./a", []string{"default"}, ""},
+		{"./utils/Node_Modules/dep/index", []string{"default"}, ""},
+		{"./utils/a\\..\\..\\b", []string{"default"}, ""},
+		{"./missing", []string{"default"}, ""},
+	}
+	for _, tc := range testCases {
+		target, _ := resolvePackageExports(pkg.Exports.v, tc.subpath, tc.conditions)
+		assert.Equal(t, tc.expected, target, tc.subpath)
+	}
+
+	for specifier, expected := range map[string]string{
+		"#dep":      "./dep.js",
+		"#src/a/b":  "./src/a/b.js",
+		"#src/../x": "",
+		"#missing":  "",
+	} {
+		target, _ := resolvePackageImports(pkg.Imports.v, specifier, []string{"import", "default"})
+		assert.Equal(t, expected, target, specifier)
+	}
+	target, _ := resolvePackageImports(pkg.Imports.v, "#dep", []string{"import", "default", "ski"})
+	assert.Equal(t, "dep-ski", target)
+
+	t.Run("sugar", func(t *testing.T) {
+		require.NoError(t, json.Unmarshal([]byte(`{"exports":{"import":"./index.js","default":"./index.cjs"}}`), &pkg))
+		target, _ := resolvePackageExports(pkg.Exports.v, ".", []string{"require", "default"})
+		assert.Equal(t, "./index.cjs", target)
+		_, ok := resolvePackageExports(pkg.Exports.v, "./sub", []string{"require", "default"})
+		assert.False(t, ok)
+	})
+}
+
+func TestLoaderPackage(t *testing.T) {
+	t.Parallel()
+	mfs := fstest.MapFS{
+		"node_modules/pkg/package.json": &fstest.MapFile{Data: []byte(`{
+  "main": "./main.js",
+  "exports": {
+    ".": { "import": "./esm/index.js", "require": "./cjs/index.js" },
+    "./feature": { "ski": "./feature.ski.js", "default": "./feature.js" },
+    "./utils/*": "./lib/utils/*.js"
+  },
+  "imports": { "#dep": { "ski": "dep-ski", "default": "./dep.js" } }
+}`)},
+		"node_modules/pkg/main.js":          &fstest.MapFile{Data: []byte(`module.exports = "main";`)},
+		"node_modules/pkg/esm/index.js":     &fstest.MapFile{Data: []byte(`import dep from "#dep"; export default "esm/" + dep;`)},
+		"node_modules/pkg/cjs/index.js":     &fstest.MapFile{Data: []byte(`module.exports = "cjs";`)},
+		"node_modules/pkg/feature.js":       &fstest.MapFile{Data: []byte(`export default "feature";`)},
+		"node_modules/pkg/feature.ski.js":   &fstest.MapFile{Data: []byte(`export default "feature.ski";`)},
+		"node_modules/pkg/lib/utils/a.js":   &fstest.MapFile{Data: []byte(`export default "utils/a";`)},
+		"node_modules/pkg/lib/internal.js":  &fstest.MapFile{Data: []byte(`export default "internal";`)},
+		"node_modules/pkg/dep.js":           &fstest.MapFile{Data: []byte(`export default "dep";`)},
+		"node_modules/dep-ski/package.json": &fstest.MapFile{Data: []byte(`{"exports":"./index.js"}`)},
+		"node_modules/dep-ski/index.js":     &fstest.MapFile{Data: []byte(`export default "dep-ski";`)},
+	}
+	fileLoader := WithFileLoader(func(specifier *url.URL, _ string) ([]byte, error) {
+		return mfs.ReadFile(specifier.Path)
+	})
+
+	run := func(t *testing.T, ml Loader, source string) {
+		vm := NewTestVM(t, ml)
+		mod, err := ml.CompileModule("", source)
+		require.NoError(t, err)
+		require.NoError(t, mod.Link())
+		Result(vm.CyclicModuleRecordEvaluate(mod, ml.ResolveModule))
+	}
+
+	t.Run("default", func(t *testing.T) {
+		run(t, NewLoader(fileLoader), `
+		import pkg from "pkg";
+		import feature from "pkg/feature";
+		import a from "pkg/utils/a";
+		assert.equal(pkg, "esm/dep");
+		assert.equal(require("pkg"), "cjs");
+		assert.equal(feature, "feature");
+		assert.equal(a, "utils/a");
+		`)
+	})
+
+	t.Run("conditions", func(t *testing.T) {
+		run(t, NewLoader(fileLoader, WithConditions("ski")), `
+		import pkg from "pkg";
+		import feature from "pkg/feature";
+		assert.equal(pkg, "esm/dep-ski");
+		assert.equal(feature, "feature.ski");
+		`)
+	})
+
+	t.Run("not exported", func(t *testing.T) {
+		ml := NewLoader(fileLoader)
+		for _, specifier := range []string{"pkg/lib/internal.js", "pkg/main.js", "#dep"} {
+			_, err := ml.ResolveModule(nil, specifier)
+			assert.ErrorIs(t, err, ErrNotFoundModule, specifier)
+		}
+	})
+}
@@ -125,14 +125,20 @@ func (a *AbortSignal) abort(_ sobek.FunctionCall, rt *sobek.Runtime) sobek.Value
 	return object
 }
 
+// timeout returns an AbortSignal that will automatically abort after the milliseconds,
+// the timer reads the VM js.Clock and does not keep the VM running.
 func (a *AbortSignal) timeout(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
 	timeout := call.Argument(0).ToInteger()
 	signal := new(abortSignal)
-	var cancel context.CancelFunc
-	signal.ctx, cancel = context.WithTimeout(js.Context(rt), time.Duration(timeout))
-	signal.cancel = func(cause error) { cancel() }
+	signal.ctx, signal.cancel = context.WithCancelCause(js.Context(rt))
 	if timeout <= 0 {
-		signal.abort(nil)
+		signal.abort(context.DeadlineExceeded)
+	} else {
+		timer := js.GetEventLoop(rt).AddTimer(time.Duration(timeout)*time.Millisecond, false, func() error {
+			signal.abort(context.DeadlineExceeded)
+			return nil
+		})
+		timer.Unref()
 	}
 	object := rt.ToValue(signal).ToObject(rt)
 	_ = object.SetPrototype(a.prototype(rt))
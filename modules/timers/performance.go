@@ -0,0 +1,31 @@
+package timers
+
+import (
+	"time"
+
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+	"github.com/shiroyk/ski/modules"
+)
+
+func init() {
+	modules.Register("performance", modules.Global{
+		"performance": new(Performance),
+	})
+}
+
+// Performance provides the high resolution time relative to the time origin,
+// the time is read from the VM js.Clock.
+// https://developer.mozilla.org/en-US/docs/Web/API/Performance
+type Performance struct{}
+
+func (p *Performance) Instantiate(rt *sobek.Runtime) (sobek.Value, error) {
+	origin := js.Now(rt)
+	ret := rt.NewObject()
+	_ = ret.Set("now", func(sobek.FunctionCall) sobek.Value {
+		return rt.ToValue(float64(js.Now(rt).Sub(origin)) / float64(time.Millisecond))
+	})
+	_ = ret.Set("timeOrigin", float64(origin.UnixMicro())/1e3)
+	_ = ret.SetSymbol(sobek.SymToStringTag, func(sobek.FunctionCall) sobek.Value { return rt.ToValue("Performance") })
+	return ret, nil
+}
@@ -0,0 +1,232 @@
+package timers
+
+import (
+	"context"
+
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+	"github.com/shiroyk/ski/js/types"
+	"github.com/shiroyk/ski/modules"
+	"github.com/shiroyk/ski/modules/signal"
+)
+
+func init() {
+	modules.Register("node:timers/promises", new(Promises))
+}
+
+// Promises implements the promise based timer functions of node:timers/promises.
+// https://nodejs.org/api/timers.html#timers-promises-api
+type Promises struct{}
+
+func (p *Promises) Instantiate(rt *sobek.Runtime) (sobek.Value, error) {
+	ret := rt.NewObject()
+	_ = ret.Set("setTimeout", p.setTimeout)
+	_ = ret.Set("setImmediate", p.setImmediate)
+	_ = ret.Set("setInterval", p.setInterval)
+	return ret, nil
+}
+
+// setTimeout returns a promise which is fulfilled with the value after the delay.
+func (*Promises) setTimeout(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	delay := toDelay(call.Argument(0))
+	value := call.Argument(1)
+	ctx, reason := toSignal(rt, call.Argument(2))
+
+	promise, resolve, reject := rt.NewPromise()
+	if aborted(ctx) {
+		_ = reject(abortError(rt, reason))
+		return rt.ToValue(promise)
+	}
+
+	loop := js.GetEventLoop(rt)
+	unregister := func() bool { return false }
+	timer := loop.AddTimer(delay, false, func() error {
+		unregister()
+		return resolve(value)
+	})
+	if ctx != nil {
+		unregister = context.AfterFunc(ctx, func() {
+			enqueue := loop.EnqueueJob()
+			if timer.Stop() {
+				enqueue(func() error { return reject(abortError(rt, reason)) })
+			} else {
+				enqueue(nothing)
+			}
+		})
+		js.Cleanup(rt, func() { unregister() })
+	}
+
+	return rt.ToValue(promise)
+}
+
+// setImmediate returns a promise which is fulfilled with the value on the next EventLoop iteration.
+func (*Promises) setImmediate(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	value := call.Argument(0)
+	ctx, reason := toSignal(rt, call.Argument(1))
+
+	promise, resolve, reject := rt.NewPromise()
+	if aborted(ctx) {
+		_ = reject(abortError(rt, reason))
+		return rt.ToValue(promise)
+	}
+
+	js.EnqueueJob(rt)(func() error {
+		if aborted(ctx) {
+			return reject(abortError(rt, reason))
+		}
+		return resolve(value)
+	})
+
+	return rt.ToValue(promise)
+}
+
+// setInterval returns an async iterator that generates the value at every interval.
+// The ticks are accumulated when the iterator is not consumed in time.
+func (*Promises) setInterval(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	ctx, reason := toSignal(rt, call.Argument(2))
+	it := &interval{
+		rt:         rt,
+		value:      call.Argument(1),
+		ctx:        ctx,
+		reason:     reason,
+		unregister: func() bool { return false },
+	}
+
+	if aborted(ctx) {
+		_ = it.abort()
+	} else {
+		loop := js.GetEventLoop(rt)
+		it.timer = loop.AddTimer(toDelay(call.Argument(0)), true, it.tick)
+		if ctx != nil {
+			it.unregister = context.AfterFunc(ctx, func() { loop.EnqueueJob()(it.abort) })
+			js.Cleanup(rt, func() { it.unregister() })
+		}
+	}
+
+	ret := rt.NewObject()
+	_ = ret.Set("next", it.next)
+	_ = ret.Set("return", it.finish)
+	_ = ret.SetSymbol(sobek.SymAsyncIterator, func(call sobek.FunctionCall) sobek.Value { return call.This })
+	return ret
+}
+
+// interval the state of the setInterval async iterator, only used on the runtime goroutine.
+type interval struct {
+	rt         *sobek.Runtime
+	value      sobek.Value
+	ctx        context.Context
+	reason     sobek.Value
+	timer      *js.Timer
+	unregister func() bool
+	ticks      int
+	waiting    []waiter
+	done       bool
+	err        sobek.Value
+}
+
+type waiter struct {
+	resolve, reject func(any) error
+}
+
+func (it *interval) result(done bool) *sobek.Object {
+	ret := it.rt.NewObject()
+	if done {
+		_ = ret.Set("value", sobek.Undefined())
+	} else {
+		_ = ret.Set("value", it.value)
+	}
+	_ = ret.Set("done", done)
+	return ret
+}
+
+func (it *interval) next(sobek.FunctionCall) sobek.Value {
+	promise, resolve, reject := it.rt.NewPromise()
+	if it.err == nil && aborted(it.ctx) {
+		_ = it.abort()
+	}
+	switch {
+	case it.err != nil:
+		_ = reject(it.err)
+	case it.done:
+		_ = resolve(it.result(true))
+	case it.ticks > 0:
+		it.ticks--
+		_ = resolve(it.result(false))
+	default:
+		it.waiting = append(it.waiting, waiter{resolve, reject})
+	}
+	return it.rt.ToValue(promise)
+}
+
+func (it *interval) finish(sobek.FunctionCall) sobek.Value {
+	it.stop()
+	for _, w := range it.waiting {
+		_ = w.resolve(it.result(true))
+	}
+	it.waiting = nil
+	promise, resolve, _ := it.rt.NewPromise()
+	_ = resolve(it.result(true))
+	return it.rt.ToValue(promise)
+}
+
+func (it *interval) tick() error {
+	if it.done {
+		return nil
+	}
+	if len(it.waiting) == 0 {
+		it.ticks++
+		return nil
+	}
+	w := it.waiting[0]
+	it.waiting = it.waiting[1:]
+	return w.resolve(it.result(false))
+}
+
+func (it *interval) abort() error {
+	if it.err != nil {
+		return nil
+	}
+	it.stop()
+	it.err = abortError(it.rt, it.reason)
+	for _, w := range it.waiting {
+		if err := w.reject(it.err); err != nil {
+			return err
+		}
+	}
+	it.waiting = nil
+	return nil
+}
+
+func (it *interval) stop() {
+	it.done = true
+	it.unregister()
+	if it.timer != nil {
+		it.timer.Stop()
+	}
+}
+
+// toSignal returns the context and the AbortSignal of the options,
+// the context is nil if the options has no signal.
+func toSignal(rt *sobek.Runtime, options sobek.Value) (context.Context, sobek.Value) {
+	if sobek.IsUndefined(options) || sobek.IsNull(options) {
+		return nil, nil
+	}
+	value := options.ToObject(rt).Get("signal")
+	if value == nil || sobek.IsUndefined(value) {
+		return nil, nil
+	}
+	return signal.Context(rt, value), value
+}
+
+func aborted(ctx context.Context) bool { return ctx != nil && ctx.Err() != nil }
+
+// abortError returns the AbortError with the signal reason as the cause.
+func abortError(rt *sobek.Runtime, reason sobek.Value) sobek.Value {
+	err := types.New(rt, "Error", rt.ToValue("The operation was aborted"))
+	_ = err.Set("name", "AbortError")
+	_ = err.Set("code", "ABORT_ERR")
+	if reason != nil {
+		_ = err.Set("cause", reason.ToObject(rt).Get("reason"))
+	}
+	return err
+}
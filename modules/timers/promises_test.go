@@ -0,0 +1,110 @@
+package timers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shiroyk/ski/js/modulestest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromises(t *testing.T) {
+	t.Parallel()
+	vm := modulestest.New(t)
+	ctx := context.Background()
+
+	t.Run("setTimeout", func(t *testing.T) {
+		result, err := vm.RunModule(ctx, `
+		import { setTimeout } from "node:timers/promises";
+		export default async () => {
+			const start = Date.now();
+			const value = await setTimeout(50, "done");
+			return { value, elapsed: Date.now() - start };
+		}
+		`)
+		require.NoError(t, err)
+		obj := modulestest.PromiseResult(result).ToObject(vm.Runtime())
+		assert.Equal(t, "done", obj.Get("value").String())
+		assert.GreaterOrEqual(t, obj.Get("elapsed").ToInteger(), int64(50))
+	})
+
+	t.Run("setTimeout abort", func(t *testing.T) {
+		result, err := vm.RunModule(ctx, `
+		import { setTimeout } from "node:timers/promises";
+		export default async () => {
+			const controller = new AbortController();
+			const promise = setTimeout(1000, "done", { signal: controller.signal });
+			controller.abort("stop");
+			try {
+				await promise;
+			} catch (e) {
+				return e.name + ":" + e.code + ":" + e.cause;
+			}
+		}
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, "AbortError:ABORT_ERR:stop", modulestest.PromiseResult(result).String())
+	})
+
+	t.Run("setTimeout aborted", func(t *testing.T) {
+		result, err := vm.RunModule(ctx, `
+		import { setTimeout } from "node:timers/promises";
+		export default async () => {
+			try {
+				await setTimeout(1000, "done", { signal: AbortSignal.abort() });
+			} catch (e) {
+				return e.name;
+			}
+		}
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, "AbortError", modulestest.PromiseResult(result).String())
+	})
+
+	t.Run("setImmediate", func(t *testing.T) {
+		result, err := vm.RunModule(ctx, `
+		import { setImmediate } from "node:timers/promises";
+		export default async () => {
+			return await setImmediate("immediate");
+		}
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, "immediate", modulestest.PromiseResult(result).String())
+	})
+
+	t.Run("setInterval", func(t *testing.T) {
+		result, err := vm.RunModule(ctx, `
+		import { setInterval } from "node:timers/promises";
+		export default async () => {
+			const values = [];
+			for await (const value of setInterval(10, "tick")) {
+				values.push(value);
+				if (values.length === 3) break;
+			}
+			return values.join(",");
+		}
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, "tick,tick,tick", modulestest.PromiseResult(result).String())
+	})
+
+	t.Run("setInterval abort", func(t *testing.T) {
+		result, err := vm.RunModule(ctx, `
+		import { setInterval } from "node:timers/promises";
+		export default async () => {
+			const controller = new AbortController();
+			let count = 0;
+			try {
+				for await (const _ of setInterval(10, null, { signal: controller.signal })) {
+					if (++count === 2) controller.abort();
+				}
+			} catch (e) {
+				return e.name + ":" + count;
+			}
+		}
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, "AbortError:2", modulestest.PromiseResult(result).String())
+	})
+}
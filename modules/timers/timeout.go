@@ -0,0 +1,98 @@
+package timers
+
+import (
+	"time"
+
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+)
+
+// Timeout the object returned by setTimeout and setInterval.
+// https://nodejs.org/api/timers.html#class-timeout
+type Timeout struct{}
+
+func (t *Timeout) prototype(rt *sobek.Runtime) *sobek.Object {
+	p := rt.NewObject()
+	_ = p.Set("ref", t.ref)
+	_ = p.Set("unref", t.unref)
+	_ = p.Set("hasRef", t.hasRef)
+	_ = p.Set("refresh", t.refresh)
+	_ = p.Set("close", t.close)
+	_ = p.SetSymbol(sobek.SymToPrimitive, t.toPrimitive)
+	_ = p.SetSymbol(sobek.SymToStringTag, func(sobek.FunctionCall) sobek.Value { return rt.ToValue("Timeout") })
+	return p
+}
+
+// ref requests the EventLoop not to exit so long as the timer is active.
+func (*Timeout) ref(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	toTimer(rt, call.This).timer.Ref()
+	return call.This
+}
+
+// unref allows the EventLoop to exit while the timer is still active.
+func (*Timeout) unref(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	toTimer(rt, call.This).timer.Unref()
+	return call.This
+}
+
+// hasRef returns true if the timer will keep the EventLoop active.
+func (*Timeout) hasRef(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	return rt.ToValue(toTimer(rt, call.This).timer.HasRef())
+}
+
+// refresh restarts the timer with the same delay and callback.
+func (*Timeout) refresh(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	t := toTimer(rt, call.This)
+	if _, ok := t.timers.timer[t.id]; !ok {
+		t.timers.timer[t.id] = t
+		js.Cleanup(rt, t.stop)
+	}
+	t.timer.Reset(t.delay)
+	return call.This
+}
+
+// close cancels the timer.
+func (*Timeout) close(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	toTimer(rt, call.This).stop()
+	return call.This
+}
+
+// toPrimitive returns the timer id, which can be passed to clearTimeout.
+func (*Timeout) toPrimitive(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	return rt.ToValue(toTimer(rt, call.This).id)
+}
+
+var symTimeout = sobek.NewSymbol("Symbol.Timeout")
+
+func toTimer(rt *sobek.Runtime, value sobek.Value) *timer {
+	if o, ok := value.(*sobek.Object); ok {
+		if v := o.GetSymbol(symTimeout); v != nil {
+			return v.Export().(*timer)
+		}
+	}
+	panic(rt.NewTypeError(`Value of "this" must be of type Timeout`))
+}
+
+// timer the Timeout scheduled on the EventLoop.
+type timer struct {
+	id     int64
+	delay  time.Duration
+	timer  *js.Timer
+	timers *timers
+}
+
+func (t *timer) toValue(rt *sobek.Runtime) sobek.Value {
+	if t.timers.prototype == nil {
+		t.timers.prototype = new(Timeout).prototype(rt)
+	}
+	obj := rt.NewObject()
+	_ = obj.SetSymbol(symTimeout, t)
+	_ = obj.SetPrototype(t.timers.prototype)
+	return obj
+}
+
+// stop cancels the timer.
+func (t *timer) stop() {
+	t.timer.Stop()
+	delete(t.timers.timer, t.id)
+}
@@ -17,10 +17,13 @@ func init() {
 
 func (t *Timers) Global() modules.Global {
 	return modules.Global{
-		"setTimeout":    modules.ModuleFunc(t.setTimeout),
-		"clearTimeout":  modules.ModuleFunc(t.clearTimeout),
-		"setInterval":   modules.ModuleFunc(t.setInterval),
-		"clearInterval": modules.ModuleFunc(t.clearInterval),
+		"setTimeout":     modules.ModuleFunc(t.setTimeout),
+		"clearTimeout":   modules.ModuleFunc(t.clearTimeout),
+		"setInterval":    modules.ModuleFunc(t.setInterval),
+		"clearInterval":  modules.ModuleFunc(t.clearInterval),
+		"setImmediate":   modules.ModuleFunc(t.setImmediate),
+		"clearImmediate": modules.ModuleFunc(t.clearImmediate),
+		"queueMicrotask": modules.ModuleFunc(t.queueMicrotask),
 	}
 }
 
@@ -30,41 +33,19 @@ func (*Timers) setTimeout(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Valu
 		panic(rt.NewTypeError("setTimeout: first argument must be a function"))
 	}
 
-	i := call.Argument(1).ToInteger()
-	if i < 1 || i > 2147483647 {
-		i = 1
-	}
-	delay := time.Duration(i) * time.Millisecond
+	delay := toDelay(call.Argument(1))
 
 	var args []sobek.Value
 	if len(call.Arguments) > 2 {
 		args = call.Arguments[2:]
 	}
 
-	enqueue := js.EnqueueJob(rt)
-	t := rtTimers(rt).new(delay, false)
-	js.Cleanup(rt, t.stop)
-	task := func() error {
-		defer t.stop()
-		_, err := callback(sobek.Undefined(), args...)
-		return err
-	}
-
-	go func() {
-		select {
-		case <-t.timer:
-			enqueue(task)
-		case <-t.done:
-			enqueue(nothing)
-		}
-	}()
-
-	return rt.ToValue(t.id)
+	t := rtTimers(rt).new(rt, delay, false, callback, args)
+	return t.toValue(rt)
 }
 
 func (*Timers) clearTimeout(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
-	id := call.Argument(0).ToInteger()
-	rtTimers(rt).stop(id)
+	rtTimers(rt).stop(toID(call.Argument(0)))
 	return sobek.Undefined()
 }
 
@@ -74,91 +55,97 @@ func (*Timers) setInterval(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Val
 		panic(rt.NewTypeError("setInterval: first argument must be a function"))
 	}
 
-	i := call.Argument(1).ToInteger()
-	if i < 1 || i > 2147483647 {
-		i = 1
-	}
-	delay := time.Duration(i) * time.Millisecond
+	delay := toDelay(call.Argument(1))
 
 	var args []sobek.Value
 	if len(call.Arguments) > 2 {
 		args = call.Arguments[2:]
 	}
 
-	enqueue := js.EnqueueJob(rt)
-	t := rtTimers(rt).new(delay, true)
-	js.Cleanup(rt, t.stop)
-	task := func() error { _, err := callback(sobek.Undefined(), args...); return err }
-
-	go func() {
-		for {
-			select {
-			case <-t.timer:
-				enqueue(task)
-				enqueue = js.EnqueueJob(rt)
-			case <-t.done:
-				enqueue(nothing)
-				return
-			}
-		}
-	}()
-
-	return rt.ToValue(t.id)
+	t := rtTimers(rt).new(rt, delay, true, callback, args)
+	return t.toValue(rt)
 }
 
 func (*Timers) clearInterval(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
-	id := call.Argument(0).ToInteger()
-	rtTimers(rt).stop(id)
+	rtTimers(rt).stop(toID(call.Argument(0)))
 	return sobek.Undefined()
 }
 
-type timer struct {
-	id      int64
-	timer   <-chan time.Time
-	done    chan struct{}
-	cleanup func()
-}
+// setImmediate schedules the callback to run on the next EventLoop iteration.
+func (*Timers) setImmediate(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	callback, ok := sobek.AssertFunction(call.Argument(0))
+	if !ok {
+		panic(rt.NewTypeError("setImmediate: first argument must be a function"))
+	}
 
-func (t *timer) stop() {
-	select {
-	case _, ok := <-t.done:
-		if !ok {
-			return
+	var args []sobek.Value
+	if len(call.Arguments) > 1 {
+		args = call.Arguments[1:]
+	}
+
+	t := rtTimers(rt)
+	t.id++
+	id := t.id
+	t.immediate[id] = struct{}{}
+
+	js.EnqueueJob(rt)(func() error {
+		if _, ok := t.immediate[id]; !ok {
+			return nil
 		}
-	default:
+		delete(t.immediate, id)
+		_, err := callback(sobek.Undefined(), args...)
+		return err
+	})
+
+	return rt.ToValue(id)
+}
+
+func (*Timers) clearImmediate(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	id := call.Argument(0).ToInteger()
+	delete(rtTimers(rt).immediate, id)
+	return sobek.Undefined()
+}
+
+// queueMicrotask queues the callback to the microtask queue,
+// it runs after the current job and before the next EventLoop job.
+func (*Timers) queueMicrotask(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	callback, ok := sobek.AssertFunction(call.Argument(0))
+	if !ok {
+		panic(rt.NewTypeError("queueMicrotask: first argument must be a function"))
 	}
-	close(t.done)
-	t.cleanup()
+
+	enqueue := js.EnqueueJob(rt)
+	promise, resolve, _ := rt.NewPromise()
+	then, _ := sobek.AssertFunction(rt.ToValue(promise).ToObject(rt).Get("then"))
+	_, _ = then(rt.ToValue(promise), rt.ToValue(func(sobek.FunctionCall) sobek.Value {
+		_, err := callback(sobek.Undefined())
+		enqueue(func() error { return err })
+		return sobek.Undefined()
+	}))
+	_ = resolve(sobek.Undefined())
+
+	return sobek.Undefined()
 }
 
 type timers struct {
-	id    int64
-	timer map[int64]*timer
+	id        int64
+	timer     map[int64]*timer
+	immediate map[int64]struct{}
+	prototype *sobek.Object // the Timeout prototype
 }
 
-func (t *timers) new(delay time.Duration, repeat bool) *timer {
+func (t *timers) new(rt *sobek.Runtime, delay time.Duration, repeat bool, callback sobek.Callable, args []sobek.Value) *timer {
 	t.id++
-	id := t.id
-	n := &timer{
-		id:   id,
-		done: make(chan struct{}),
-	}
-	if repeat {
-		t1 := time.NewTicker(delay)
-		n.timer = t1.C
-		n.cleanup = func() {
-			delete(t.timer, id)
-			t1.Stop()
-		}
-	} else {
-		t1 := time.NewTimer(delay)
-		n.timer = t1.C
-		n.cleanup = func() {
-			delete(t.timer, id)
-			t1.Stop()
+	n := &timer{id: t.id, delay: delay, timers: t}
+	n.timer = js.GetEventLoop(rt).AddTimer(delay, repeat, func() error {
+		if !repeat {
+			delete(t.timer, n.id)
 		}
-	}
-	t.timer[id] = n
+		_, err := callback(sobek.Undefined(), args...)
+		return err
+	})
+	t.timer[n.id] = n
+	js.Cleanup(rt, n.stop)
 	return n
 }
 
@@ -174,11 +161,30 @@ func rtTimers(rt *sobek.Runtime) *timers {
 	global := rt.GlobalObject()
 	v := global.GetSymbol(symTimers)
 	if v == nil {
-		t := &timers{timer: make(map[int64]*timer)}
+		t := &timers{timer: make(map[int64]*timer), immediate: make(map[int64]struct{})}
 		_ = global.SetSymbol(symTimers, t)
 		return t
 	}
 	return v.Export().(*timers)
 }
 
+// toDelay converts the value to the timer delay, the delay out of range is set to 1ms.
+func toDelay(value sobek.Value) time.Duration {
+	i := value.ToInteger()
+	if i < 1 || i > 2147483647 {
+		i = 1
+	}
+	return time.Duration(i) * time.Millisecond
+}
+
+// toID returns the timer id of the Timeout object or the number.
+func toID(value sobek.Value) int64 {
+	if o, ok := value.(*sobek.Object); ok {
+		if v := o.GetSymbol(symTimeout); v != nil {
+			return v.Export().(*timer).id
+		}
+	}
+	return value.ToInteger()
+}
+
 func nothing() error { return nil }
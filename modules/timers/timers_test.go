@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/shiroyk/ski/js"
 	"github.com/shiroyk/ski/js/modulestest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -177,6 +178,124 @@ func TestTimers(t *testing.T) {
 		assert.Equal(t, 0, len(rtTimers(vm.Runtime()).timer))
 	})
 
+	t.Run("setImmediate", func(t *testing.T) {
+		result, err := vm.RunModule(ctx, `
+		export default () => {
+			return new Promise((resolve) => {
+				const order = [];
+				setImmediate((a) => {
+					order.push(a);
+					resolve(order.join(","));
+				}, "immediate");
+				Promise.resolve().then(() => order.push("microtask"));
+				order.push("sync");
+			});
+		}
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, "sync,microtask,immediate", modulestest.PromiseResult(result).String())
+	})
+
+	t.Run("clearImmediate", func(t *testing.T) {
+		result, err := vm.RunModule(ctx, `
+		export default () => {
+			return new Promise((resolve) => {
+				let called = false;
+				const id = setImmediate(() => { called = true });
+				clearImmediate(id);
+				setTimeout(() => resolve(called), 10);
+			});
+		}
+		`)
+		require.NoError(t, err)
+		assert.False(t, modulestest.PromiseResult(result).ToBoolean())
+		assert.Equal(t, 0, len(rtTimers(vm.Runtime()).immediate))
+	})
+
+	t.Run("queueMicrotask", func(t *testing.T) {
+		result, err := vm.RunModule(ctx, `
+		export default () => {
+			return new Promise((resolve) => {
+				const order = [];
+				setTimeout(() => resolve(order.join(",")), 0);
+				queueMicrotask(() => order.push("microtask"));
+				order.push("sync");
+			});
+		}
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, "sync,microtask", modulestest.PromiseResult(result).String())
+	})
+
+	t.Run("queueMicrotask error", func(t *testing.T) {
+		_, err := vm.RunModule(ctx, `
+		export default () => {
+			queueMicrotask(() => { throw new Error("microtask error") });
+		}
+		`)
+		assert.ErrorContains(t, err, "microtask error")
+	})
+	t.Run("unref", func(t *testing.T) {
+		start := time.Now()
+		result, err := vm.RunModule(ctx, `
+		export default () => {
+			const heartbeat = setInterval(() => {}, 10);
+			heartbeat.unref();
+			const timeout = setTimeout(() => {}, 1000).unref();
+			return [heartbeat.hasRef(), timeout.hasRef(), timeout.ref().hasRef(), timeout.unref().hasRef()];
+		}
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, []any{false, false, true, false}, result.Export())
+		assert.Less(t, time.Since(start), time.Second)
+		assert.Equal(t, 0, len(rtTimers(vm.Runtime()).timer))
+	})
+
+	t.Run("refresh", func(t *testing.T) {
+		result, err := vm.RunModule(ctx, `
+		export default () => {
+			return new Promise((resolve) => {
+				const start = Date.now();
+				const timeout = setTimeout(() => resolve(Date.now() - start), 100);
+				setTimeout(() => timeout.refresh(), 60);
+			});
+		}
+		`)
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, modulestest.PromiseResult(result).ToInteger(), int64(160))
+	})
+
+	t.Run("refresh fired", func(t *testing.T) {
+		result, err := vm.RunModule(ctx, `
+		export default () => {
+			return new Promise((resolve) => {
+				let count = 0;
+				const timeout = setTimeout(() => {
+					if (++count === 2) resolve(count);
+					else timeout.refresh();
+				}, 10);
+			});
+		}
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), modulestest.PromiseResult(result).ToInteger())
+	})
+
+	t.Run("toPrimitive", func(t *testing.T) {
+		result, err := vm.RunModule(ctx, `
+		export default () => {
+			return new Promise((resolve) => {
+				let called = false;
+				const timeout = setTimeout(() => { called = true }, 50);
+				const id = +timeout;
+				clearTimeout(id);
+				setTimeout(() => resolve(typeof id === "number" && !called), 100);
+			});
+		}
+		`)
+		require.NoError(t, err)
+		assert.True(t, modulestest.PromiseResult(result).ToBoolean())
+	})
 	t.Run("interrupt", func(t *testing.T) {
 		ctx2, cancel := context.WithTimeout(ctx, time.Millisecond*100)
 		defer cancel()
@@ -191,3 +310,72 @@ func TestTimers(t *testing.T) {
 		assert.Equal(t, 0, len(rtTimers(vm.Runtime()).timer))
 	})
 }
+
+func TestFakeClock(t *testing.T) {
+	t.Parallel()
+	clock := js.NewFakeClock(time.UnixMilli(0))
+	vm := modulestest.New(t, js.WithClock(clock))
+	ctx := context.Background()
+
+	t.Run("advance", func(t *testing.T) {
+		result, err := vm.RunModule(ctx, `
+		export default async () => {
+			const fired = [];
+			setTimeout(() => fired.push("timeout"), 1000);
+			const id = setInterval(() => fired.push("interval"), 400);
+			await clock.advance(999);
+			fired.push(Date.now());
+			await clock.advance(1);
+			clearInterval(id);
+			return fired.join(",");
+		}
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, "interval,interval,999,timeout", modulestest.PromiseResult(result).String())
+	})
+
+	t.Run("runAllTimers", func(t *testing.T) {
+		start := time.Now()
+		result, err := vm.RunModule(ctx, `
+		export default async () => {
+			const start = performance.now();
+			let count = 0;
+			setTimeout(() => {
+				count++;
+				setTimeout(() => count++, 60 * 60 * 1000);
+			}, 60 * 1000);
+			await clock.runAllTimers();
+			return [count, performance.now() - start === 61 * 60 * 1000];
+		}
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, []any{int64(2), true}, modulestest.PromiseResult(result).Export())
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("promises", func(t *testing.T) {
+		result, err := vm.RunModule(ctx, `
+		import { setTimeout } from "node:timers/promises";
+		export default async () => {
+			const promise = setTimeout(5000, "done");
+			await clock.advance(5000);
+			return await promise;
+		}
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, "done", modulestest.PromiseResult(result).String())
+	})
+
+	t.Run("AbortSignal.timeout", func(t *testing.T) {
+		result, err := vm.RunModule(ctx, `
+		export default async () => {
+			const signal = AbortSignal.timeout(100);
+			const before = signal.aborted;
+			await clock.advance(100);
+			return [before, signal.aborted];
+		}
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, []any{false, true}, modulestest.PromiseResult(result).Export())
+	})
+}
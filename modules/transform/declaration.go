@@ -0,0 +1,624 @@
+package transform
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// declaration transforms the declaration, returns false if it is not a declaration.
+// The mark is the start of the statement which is reset if the declaration is erased.
+func (t *transformer) declaration(m mark) bool {
+	toks := t.peekN(3)
+	tok, next := toks[0], toks[1]
+	switch tok.text {
+	case "import":
+		if next.punct("(") || next.punct(".") {
+			return false
+		}
+		t.importDecl(m)
+		return true
+	case "export":
+		t.exportDecl(m)
+		return true
+	case "var", "let", "const":
+		if tok.text == "let" && next.kind != ident && !next.punct("[") && !next.punct("{") {
+			return false
+		}
+		if t.ts && tok.text == "const" && next.ident("enum") {
+			t.enum()
+			return true
+		}
+		t.emit(t.next())
+		t.declarators()
+		t.semicolon()
+		return true
+	case "function":
+		t.emit(t.next())
+		t.functionDecl(m)
+		return true
+	case "async":
+		if !next.ident("function") || next.nl {
+			return false
+		}
+		t.emit(t.next())
+		t.emit(t.next())
+		t.functionDecl(m)
+		return true
+	case "class":
+		t.emit(t.next())
+		t.class()
+		return true
+	}
+
+	if !t.ts || next.kind != ident && next.kind != str || next.nl {
+		return false
+	}
+	switch tok.text {
+	case "interface":
+		if next.kind == ident {
+			t.interfaceDecl(m)
+			return true
+		}
+	case "type":
+		if next.kind == ident && (toks[2].punct("=") || toks[2].punct("<")) {
+			t.typeAlias(m)
+			return true
+		}
+	case "enum":
+		if next.kind == ident {
+			t.enum()
+			return true
+		}
+	case "declare":
+		t.declare(m)
+		return true
+	case "abstract":
+		if next.ident("class") {
+			t.erase(func() { t.next() })
+			t.emit(t.next())
+			t.class()
+			return true
+		}
+	case "namespace", "module":
+		panic(t.errorf(tok.start, "Namespaces are not supported"))
+	}
+	return false
+}
+
+// functionDecl transforms the function declaration after the function keyword,
+// the overload signatures are erased.
+func (t *transformer) functionDecl(m mark) {
+	if t.function() {
+		return
+	}
+	t.e.reset(m)
+	if t.peek().punct(";") {
+		t.next()
+	}
+	t.e.blank(t.s.pos)
+}
+
+func (t *transformer) interfaceDecl(m mark) {
+	t.next()
+	name := t.next()
+	t.types[name.text] = true
+	if t.tpeek().punct("<") {
+		t.typeParams()
+	}
+	for {
+		tok := t.s.next(false)
+		if tok.kind == eof {
+			panic(t.unexpected(tok))
+		}
+		if tok.punct("{") {
+			break
+		}
+		if tok.punct("<") {
+			t.s.pos = tok.start
+			t.typeArgs()
+		}
+	}
+	t.balanced("}")
+	t.remove(m)
+}
+
+func (t *transformer) typeAlias(m mark) {
+	t.next()
+	name := t.next()
+	t.types[name.text] = true
+	if t.peek().punct("<") {
+		t.typeParams()
+	}
+	t.expectType("=")
+	t.typ()
+	t.remove(m)
+}
+
+// declare erases the ambient declaration.
+func (t *transformer) declare(m mark) {
+	t.next()
+	toks := t.peekN(2)
+	tok := toks[0]
+	switch tok.text {
+	case "var", "let", "const":
+		t.next()
+		for {
+			name := t.s.next(false)
+			if name.punct("{") || name.punct("[") {
+				t.balanced(map[string]string{"{": "}", "[": "]"}[name.text])
+			} else {
+				t.types[name.text] = true
+			}
+			if t.tpeek().punct(":") {
+				t.s.next(false)
+				t.typ()
+			}
+			if !t.tpeek().punct(",") {
+				break
+			}
+			t.s.next(false)
+		}
+	case "function":
+		t.next()
+		t.types[t.next().text] = true
+		if t.tpeek().punct("<") {
+			t.typeParams()
+		}
+		t.expectType("(")
+		t.balanced(")")
+		if t.tpeek().punct(":") {
+			t.s.next(false)
+			t.typ()
+		}
+	case "interface":
+		t.interfaceDecl(m)
+		return
+	case "type":
+		t.typeAlias(m)
+		return
+	default:
+		// class, enum, module, namespace and global
+		named := false
+		for {
+			tok = t.s.next(false)
+			switch {
+			case tok.kind == eof:
+				panic(t.unexpected(tok))
+			case tok.punct("{"):
+				t.balanced("}")
+			case tok.kind == str && (t.tpeek().nl || t.tpeek().punct(";")):
+				// the shorthand ambient module
+			case tok.kind == ident && !named && !ambientKeywords[tok.text]:
+				named = true
+				t.types[tok.text] = true
+				continue
+			default:
+				continue
+			}
+			break
+		}
+	}
+	t.remove(m)
+}
+
+// ambientKeywords the keywords of the ambient declarations.
+var ambientKeywords = map[string]bool{
+	"abstract": true, "class": true, "const": true, "enum": true, "global": true, "module": true, "namespace": true,
+}
+
+// skipTo skips the tokens until the token of the kind and the text.
+func (t *transformer) skipTo(kind kind, text string) {
+	for {
+		tok := t.tpeek()
+		if tok.kind == kind && (text == "" || tok.text == text) {
+			return
+		}
+		if tok.kind == eof {
+			panic(t.unexpected(tok))
+		}
+		t.s.next(false)
+	}
+}
+
+// remove erases the statement from the mark with the optional semicolon.
+func (t *transformer) remove(m mark) {
+	t.e.reset(m)
+	if t.tpeek().punct(";") {
+		t.s.next(false)
+	}
+	t.e.blank(t.s.pos)
+}
+
+// specifier the import or export specifier.
+type specifier struct {
+	name, local string
+	text        string
+	typeOnly    bool
+}
+
+// specifiers parses the named imports or exports after the {.
+func (t *transformer) specifiers() (specs []specifier) {
+	for {
+		tok := t.s.next(false)
+		if tok.punct("}") {
+			return
+		}
+		if tok.punct(",") {
+			continue
+		}
+		var spec specifier
+		if toks := t.peekN(1); t.ts && tok.ident("type") && !toks[0].punct(",") && !toks[0].punct("}") &&
+			!(toks[0].ident("as") && t.peekN(2)[1].kind != ident) {
+			spec.typeOnly = true
+			tok = t.s.next(false)
+		}
+		if tok.kind != ident && tok.kind != str {
+			panic(t.unexpected(tok))
+		}
+		spec.name, spec.local = tok.text, tok.text
+		end := tok.end
+		if t.tpeek().ident("as") {
+			t.s.next(false)
+			alias := t.s.next(false)
+			spec.local, end = alias.text, alias.end
+		}
+		spec.text = t.s.src[tok.start:end]
+		specs = append(specs, spec)
+	}
+}
+
+// moduleSource parses the module specifier and the import attributes.
+func (t *transformer) moduleSource() string {
+	tok := t.s.next(false)
+	if tok.kind != str {
+		panic(t.unexpected(tok))
+	}
+	src := tok.text
+	if p := t.tpeek(); (p.ident("with") || p.ident("assert")) && !p.nl {
+		t.s.next(false)
+		start := t.s.pos
+		t.expectType("{")
+		t.balanced("}")
+		src += " " + p.text + t.s.src[start:t.s.pos]
+	}
+	if t.tpeek().punct(";") {
+		t.s.next(false)
+	}
+	return src
+}
+
+// replace replaces the statement from the start with the code.
+func (t *transformer) replace(start int, code string) {
+	t.e.copy(start)
+	t.e.insert(code, start)
+	t.e.blank(t.s.pos)
+}
+
+// importDecl transforms the import declaration, the type only imports and the
+// imports which are only used as type are elided.
+func (t *transformer) importDecl(m mark) {
+	imp := t.next()
+	toks := t.peekN(2)
+	if t.ts && toks[0].ident("type") && !toks[1].punct(",") && !toks[1].ident("from") {
+		t.skipTo(str, "")
+		t.moduleSource()
+		t.remove(m)
+		return
+	}
+	if toks[0].kind == str {
+		t.moduleSource()
+		t.e.token(imp.start, t.s.pos)
+		return
+	}
+	if t.ts && toks[0].kind == ident && toks[1].punct("=") {
+		panic(t.errorf(imp.start, "Import assignments are not supported"))
+	}
+
+	var (
+		def, ns    string
+		specs      []specifier
+		bindings   int
+		elided     bool
+		clauseKept []string
+	)
+	if tok := t.tpeek(); tok.kind == ident {
+		t.s.next(false)
+		def = tok.text
+		bindings++
+		if t.tpeek().punct(",") {
+			t.s.next(false)
+		}
+	}
+	if t.tpeek().punct("*") {
+		t.s.next(false)
+		t.s.next(false) // as
+		ns = t.s.next(false).text
+		bindings++
+	}
+	if t.tpeek().punct("{") {
+		t.s.next(false)
+		specs = t.specifiers()
+		bindings += len(specs)
+	}
+	if tok := t.s.next(false); !tok.ident("from") {
+		panic(t.unexpected(tok))
+	}
+	source := t.moduleSource()
+
+	unused := func(local string) bool { return t.ts && !t.collect && !t.used[local] }
+	if def != "" {
+		if unused(def) {
+			elided = true
+		} else {
+			clauseKept = append(clauseKept, def)
+		}
+	}
+	if ns != "" {
+		if unused(ns) {
+			elided = true
+		} else {
+			clauseKept = append(clauseKept, "* as "+ns)
+		}
+	}
+	var kept []string
+	for _, spec := range specs {
+		if spec.typeOnly || unused(spec.local) {
+			elided = true
+		} else {
+			kept = append(kept, spec.text)
+		}
+	}
+	if !elided {
+		t.e.token(imp.start, t.s.pos)
+		return
+	}
+	if len(kept) > 0 {
+		clauseKept = append(clauseKept, "{ "+strings.Join(kept, ", ")+" }")
+	}
+	if len(clauseKept) == 0 && bindings > 0 {
+		t.remove(m)
+		return
+	}
+	t.replace(imp.start, "import "+strings.Join(clauseKept, ", ")+" from "+source+";")
+}
+
+// exportDecl transforms the export declaration, the type only exports are elided.
+func (t *transformer) exportDecl(m mark) {
+	toks := t.peekN(4)
+	exp, tok, next := toks[0], toks[1], toks[2]
+	if t.ts {
+		switch {
+		case tok.ident("type") && (next.punct("{") || next.punct("*")):
+			t.next()
+			t.next()
+			if t.s.next(false).punct("{") {
+				t.balanced("}")
+			} else {
+				t.skipTo(ident, "from")
+			}
+			if t.tpeek().ident("from") {
+				t.s.next(false)
+				t.moduleSource()
+			}
+			t.remove(m)
+			return
+		case tok.ident("interface") && next.kind == ident,
+			tok.ident("type") && next.kind == ident && (toks[3].punct("=") || toks[3].punct("<")),
+			tok.ident("declare") && !next.nl:
+			t.next()
+			t.declaration(m)
+			return
+		case tok.ident("default") && next.ident("interface"):
+			t.next()
+			t.next()
+			t.interfaceDecl(m)
+			return
+		case tok.ident("default") && next.kind == ident && t.types[next.text] &&
+			(toks[3].punct(";") || toks[3].nl || toks[3].kind == eof):
+			t.next()
+			t.next()
+			t.next()
+			t.remove(m)
+			return
+		case tok.punct("="), tok.ident("import") && next.kind == ident:
+			panic(t.errorf(exp.start, "Export assignments are not supported"))
+		case tok.ident("as") && next.ident("namespace"):
+			t.next()
+			t.next()
+			t.next()
+			t.s.next(false)
+			t.remove(m)
+			return
+		}
+	}
+
+	switch {
+	case tok.punct("{"):
+		t.next()
+		t.next()
+		specs := t.specifiers()
+		from := ""
+		if t.tpeek().ident("from") {
+			t.s.next(false)
+			from = " from " + t.moduleSource()
+		} else if t.tpeek().punct(";") {
+			t.s.next(false)
+		}
+		var kept []string
+		for _, spec := range specs {
+			if spec.typeOnly || from == "" && t.types[spec.name] {
+				continue
+			}
+			if from == "" {
+				t.use(spec.name)
+			}
+			kept = append(kept, spec.text)
+		}
+		if len(kept) == len(specs) {
+			t.e.token(exp.start, t.s.pos)
+			return
+		}
+		if len(kept) == 0 {
+			t.remove(m)
+			return
+		}
+		t.replace(exp.start, "export { "+strings.Join(kept, ", ")+" }"+from+";")
+	case tok.punct("*"):
+		t.next()
+		t.next()
+		t.skipTo(ident, "from")
+		t.s.next(false)
+		t.moduleSource()
+		t.e.token(exp.start, t.s.pos)
+	case tok.ident("default"):
+		t.emit(t.next())
+		t.emit(t.next())
+		t.prevEnd = false
+		toks = t.peekN(2)
+		switch {
+		case toks[0].ident("function"),
+			toks[0].ident("async") && toks[1].ident("function") && !toks[1].nl:
+			if toks[0].ident("async") {
+				t.emit(t.next())
+			}
+			t.emit(t.next())
+			t.functionDecl(m)
+		case toks[0].ident("class"):
+			t.emit(t.next())
+			t.class()
+		case t.ts && toks[0].ident("abstract") && toks[1].ident("class"):
+			t.erase(func() { t.next() })
+			t.emit(t.next())
+			t.class()
+		default:
+			t.expressionStatement()
+		}
+	default:
+		t.emit(t.next())
+		if !t.declaration(m) {
+			panic(t.unexpected(t.peek()))
+		}
+	}
+}
+
+// enum transforms the enum declaration to the function which initializes the object,
+// the enum members used in the initializers are referenced by the object.
+func (t *transformer) enum() {
+	start := t.peek().start
+	if t.peek().ident("const") {
+		t.next()
+	}
+	t.next()
+	name := t.next()
+	if name.kind != ident {
+		panic(t.unexpected(name))
+	}
+	t.expect("{")
+	t.replace(start, fmt.Sprintf("var %s; (function (%[1]s) {", name.text))
+
+	members := make(map[string]bool)
+	var value float64
+	prev, known := "", true
+	for {
+		tok := t.s.next(false)
+		if tok.punct("}") {
+			break
+		}
+		if tok.punct(",") {
+			continue
+		}
+		var key string
+		switch tok.kind {
+		case ident:
+			key = tok.text
+		case str:
+			key = unquote(tok.text)
+		default:
+			panic(t.unexpected(tok))
+		}
+		member := fmt.Sprintf("%s[%s]", name.text, quote(key))
+
+		var code string
+		if t.tpeek().punct("=") {
+			t.s.next(false)
+			init, ok := t.enumInit(name.text, members)
+			switch v, err := parseNumber(init); {
+			case init != "" && (init[0] == '"' || init[0] == '\'' || init[0] == '`'):
+				code = fmt.Sprintf("%s = %s;", member, init)
+				known = false
+			case ok && err == nil:
+				value, known = v+1, true
+				code = fmt.Sprintf("%s[%s = %s] = %s;", name.text, member, init, quote(key))
+			default:
+				code = fmt.Sprintf("%s[%s = %s] = %s;", name.text, member, init, quote(key))
+				known = false
+			}
+		} else if known {
+			code = fmt.Sprintf("%s[%s = %s] = %s;", name.text, member, strconv.FormatFloat(value, 'f', -1, 64), quote(key))
+			value++
+		} else {
+			code = fmt.Sprintf("%s[%s = %s + 1] = %s;", name.text, member, prev, quote(key))
+		}
+		members[key] = true
+		prev = member
+		t.e.blank(tok.start)
+		t.e.insert(" "+code, tok.start)
+		t.e.blank(t.s.pos)
+	}
+	t.e.blank(t.s.pos - 1)
+	t.e.insert(fmt.Sprintf("})(%s || (%[1]s = {}));", name.text), t.s.pos-1)
+	t.e.blank(t.s.pos)
+	t.prevEnd = false
+}
+
+// parseNumber parses the numeric literal.
+func parseNumber(s string) (float64, error) {
+	if v, err := strconv.ParseFloat(s, 64); err == nil {
+		return v, nil
+	}
+	v, err := strconv.ParseInt(s, 0, 64)
+	return float64(v), err
+}
+
+// unquote returns the value of the string literal.
+func unquote(s string) string {
+	if s[0] == '\'' {
+		s = `"` + strings.ReplaceAll(strings.ReplaceAll(s[1:len(s)-1], `\'`, `'`), `"`, `\"`) + `"`
+	}
+	if v, err := strconv.Unquote(s); err == nil {
+		return v
+	}
+	return s[1 : len(s)-1]
+}
+
+// enumInit returns the initializer of the enum member, the members are referenced by
+// the enum object, ok is true if the initializer is a single token.
+func (t *transformer) enumInit(name string, members map[string]bool) (init string, ok bool) {
+	var sb strings.Builder
+	depth, count, last := 0, 0, -1
+	for {
+		st := t.s.save()
+		tok := t.s.next(false)
+		if tok.kind == eof || depth == 0 && (tok.punct(",") || tok.punct("}")) {
+			t.s.restore(st)
+			return sb.String(), count == 1 || count == 2 && strings.HasPrefix(sb.String(), "-")
+		}
+		switch {
+		case tok.punct("("), tok.punct("["), tok.punct("{"):
+			depth++
+		case tok.punct(")"), tok.punct("]"), tok.punct("}"):
+			depth--
+		}
+		if last >= 0 {
+			sb.WriteString(t.s.src[last:tok.start])
+		}
+		if tok.kind == ident && members[tok.text] && !strings.HasSuffix(strings.TrimSpace(sb.String()), ".") {
+			sb.WriteString(fmt.Sprintf("%s[%s]", name, quote(tok.text)))
+		} else {
+			sb.WriteString(tok.text)
+		}
+		last = tok.end
+		count++
+	}
+}
@@ -0,0 +1,518 @@
+package transform
+
+import (
+	"maps"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var pragmaRegexp = regexp.MustCompile(`@(jsx|jsxFrag|jsxImportSource|jsxRuntime)\s+([^\s*]+)`)
+
+var commentRegexp = regexp.MustCompile(`(?s)/\*.*?\*/|//[^\n]*`)
+
+// pragma returns the JSX options overridden by the pragma comments.
+func pragma(src string, opts JSX) JSX {
+	for _, comment := range commentRegexp.FindAllString(src, -1) {
+		for _, m := range pragmaRegexp.FindAllStringSubmatch(comment, -1) {
+			switch m[1] {
+			case "jsx":
+				opts.Factory = m[2]
+			case "jsxFrag":
+				opts.Fragment = m[2]
+			case "jsxImportSource":
+				opts.ImportSource = m[2]
+				if opts.Runtime == "" {
+					opts.Runtime = "automatic"
+				}
+			case "jsxRuntime":
+				opts.Runtime = m[2]
+			}
+		}
+	}
+	if opts.Runtime == "" {
+		if opts.ImportSource != "" {
+			opts.Runtime = "automatic"
+		} else {
+			opts.Runtime = "classic"
+		}
+	}
+	if opts.Factory == "" {
+		opts.Factory = "React.createElement"
+	}
+	if opts.Fragment == "" {
+		opts.Fragment = "React.Fragment"
+	}
+	if opts.ImportSource == "" {
+		opts.ImportSource = "react"
+	}
+	return opts
+}
+
+func (t *transformer) automatic() bool { return t.jsxOpts.Runtime == "automatic" }
+
+// jsxImport inserts the imports of the automatic runtime at the start.
+func (t *transformer) jsxImport() {
+	var names []string
+	for _, name := range []string{"jsx", "jsxs", "Fragment"} {
+		if t.jsxImports[name] {
+			names = append(names, name+" as _"+name)
+		}
+	}
+	var imports string
+	if len(names) > 0 {
+		imports = "import { " + strings.Join(names, ", ") + " } from " + quote(t.jsxOpts.ImportSource+"/jsx-runtime") + ";"
+	}
+	if t.jsxImports["createElement"] {
+		imports += "import { createElement as _createElement } from " + quote(t.jsxOpts.ImportSource) + ";"
+	}
+	if imports != "" {
+		t.e.insertAt(mark{}, imports)
+	}
+}
+
+// jsxElement transforms the JSX element after the < token.
+func (t *transformer) jsxElement(lt token) {
+	t.e.copy(lt.start)
+	t.e.skip(lt.end)
+	t.jsxTag(lt.start)
+	t.prevEnd = true
+	t.afterDot = false
+}
+
+func (t *transformer) jsxSpace() {
+	t.s.skipSpace()
+}
+
+func (t *transformer) jsxPeek() byte {
+	if t.s.pos < len(t.s.src) {
+		return t.s.src[t.s.pos]
+	}
+	return 0
+}
+
+func (t *transformer) jsxExpect(c byte) {
+	t.jsxSpace()
+	if t.jsxPeek() != c {
+		panic(t.errorf(t.s.pos, "Unexpected token in JSX, expected %q", c))
+	}
+	t.s.pos++
+}
+
+// jsxName scans the element or attribute name.
+func (t *transformer) jsxName() string {
+	t.jsxSpace()
+	start := t.s.pos
+	if start >= len(t.s.src) || !isIdentStart(t.s.src, start) {
+		panic(t.errorf(start, "Unexpected token in JSX"))
+	}
+	for t.s.pos < len(t.s.src) {
+		if c := t.s.src[t.s.pos]; c == '-' || c == ':' || c == '.' {
+			t.s.pos++
+			continue
+		}
+		end := identEnd(t.s.src, t.s.pos)
+		if end == t.s.pos {
+			break
+		}
+		t.s.pos = end
+	}
+	return t.s.src[start:t.s.pos]
+}
+
+// jsxTag transforms the element after the <, the start is the offset of the <.
+func (t *transformer) jsxTag(start int) {
+	t.jsxSpace()
+	var name, typ string
+	if t.jsxPeek() == '>' {
+		if t.automatic() {
+			t.jsxImports["Fragment"] = true
+			typ = "_Fragment"
+		} else {
+			typ = t.jsxOpts.Fragment
+			t.use(root(typ))
+		}
+	} else {
+		name = t.jsxName()
+		if first, _ := utf8.DecodeRuneInString(name); strings.ContainsAny(name, "-:") ||
+			first >= 'a' && first <= 'z' && !strings.HasPrefix(name, "this.") && !strings.Contains(name, ".") {
+			typ = quote(name)
+		} else {
+			typ = name
+			t.use(root(name))
+		}
+		if t.jsxSpace(); t.ts && t.jsxPeek() == '<' {
+			t.typeArgs()
+		}
+	}
+
+	if !t.automatic() {
+		t.jsxBody(start, name, typ, false)
+		return
+	}
+	st, m, imports := t.s.save(), t.e.mark(), maps.Clone(t.jsxImports)
+	if !t.jsxBody(start, name, typ, true) {
+		// the key after a spread attribute falls back to createElement as Babel does
+		t.s.restore(st)
+		t.e.reset(m)
+		t.jsxImports = imports
+		t.jsxBody(start, name, typ, false)
+	}
+}
+
+// jsxBody transforms the attributes and the children of the element, the auto
+// uses the jsx function of the automatic runtime, returns false if the element
+// has the key after a spread attribute which the jsx function can not handle.
+func (t *transformer) jsxBody(start int, name, typ string, auto bool) bool {
+	var callee mark
+	switch {
+	case auto:
+		t.jsxImports["jsx"] = true
+		t.e.insert("_jsx", start)
+		callee = t.e.mark()
+		t.e.insert("("+typ+", {", start)
+	case t.automatic():
+		t.jsxImports["createElement"] = true
+		t.e.insert("_createElement("+typ, start)
+	default:
+		t.use(root(t.jsxOpts.Factory))
+		t.e.insert(t.jsxOpts.Factory+"("+typ, start)
+	}
+
+	// attributes
+	props, key, spread := 0, "", false
+	selfClosing := false
+	for {
+		t.jsxSpace()
+		t.e.skip(t.s.pos)
+		pos := t.s.pos
+		c := t.jsxPeek()
+		if c == '/' {
+			t.s.pos++
+			t.jsxExpect('>')
+			t.e.skip(t.s.pos)
+			selfClosing = true
+			break
+		}
+		if c == '>' {
+			t.s.pos++
+			break
+		}
+		if c == 0 {
+			panic(t.errorf(pos, "Unterminated JSX"))
+		}
+
+		sep := ", "
+		if props == 0 {
+			sep = ", { "
+			if auto {
+				sep = " "
+			}
+		}
+		if c == '{' {
+			// spread attribute
+			t.s.pos++
+			t.jsxSpace()
+			if !strings.HasPrefix(t.s.src[t.s.pos:], "...") {
+				panic(t.errorf(t.s.pos, "Unexpected token in JSX, expected ..."))
+			}
+			t.s.pos += 3
+			t.e.insert(sep+"...", pos)
+			t.jsxExpr()
+			props++
+			spread = true
+			continue
+		}
+
+		attr := t.jsxName()
+		m := t.e.mark()
+		isKey := auto && attr == "key"
+		if isKey && spread {
+			return false
+		}
+		if !isKey {
+			if isIdentifier(attr) {
+				t.e.insert(sep+attr+": ", pos)
+			} else {
+				t.e.insert(sep+quote(attr)+": ", pos)
+			}
+		}
+		t.jsxSpace()
+		if t.jsxPeek() != '=' {
+			t.e.skip(t.s.pos)
+			t.e.insert("true", pos)
+		} else {
+			t.s.pos++
+			t.jsxSpace()
+			t.e.skip(t.s.pos)
+			t.jsxAttrValue()
+		}
+		if isKey {
+			key = t.e.cut(m)
+			continue
+		}
+		props++
+	}
+
+	children := 0
+	var childrenMark mark
+	if !selfClosing {
+	loop:
+		for {
+			pos := t.s.pos
+			c := t.jsxPeek()
+			switch c {
+			case 0:
+				panic(t.errorf(start, "Unterminated JSX contents"))
+			case '<':
+				t.s.pos++
+				t.jsxSpace()
+				if t.jsxPeek() == '/' {
+					// closing tag
+					t.s.pos++
+					t.jsxSpace()
+					closing := ""
+					if t.jsxPeek() != '>' {
+						closing = t.jsxName()
+					}
+					if closing != name {
+						panic(t.errorf(pos, "Expected corresponding JSX closing tag for <%s>", name))
+					}
+					t.jsxExpect('>')
+					t.e.skip(t.s.pos)
+					break loop
+				}
+				t.e.skip(t.s.pos)
+				children = t.jsxChild(auto, children, props, &childrenMark, pos)
+				t.jsxTag(pos)
+			case '{':
+				t.s.pos++
+				t.jsxSpace()
+				if t.jsxPeek() == '}' {
+					t.s.pos++
+					t.e.skip(t.s.pos)
+					continue
+				}
+				spread := strings.HasPrefix(t.s.src[t.s.pos:], "...")
+				children = t.jsxChild(auto, children, props, &childrenMark, pos)
+				if spread {
+					t.s.pos += 3
+					t.e.insert("...", pos)
+					children++
+				}
+				t.jsxExpr()
+			default:
+				end := strings.IndexAny(t.s.src[pos:], "<{")
+				if end < 0 {
+					end = len(t.s.src) - pos
+				}
+				t.s.pos = pos + end
+				text := jsxText(t.s.src[pos:t.s.pos])
+				if text == "" {
+					t.e.skip(t.s.pos)
+					continue
+				}
+				lead := pos + len(t.s.src[pos:t.s.pos]) - len(strings.TrimLeft(t.s.src[pos:t.s.pos], " \t\r\n"))
+				t.e.skip(lead)
+				children = t.jsxChild(auto, children, props, &childrenMark, lead)
+				t.e.insert(quote(decodeEntities(text)), lead)
+				t.e.skip(t.s.pos)
+			}
+		}
+	}
+
+	pos := t.s.pos - 1
+	if auto {
+		if children > 1 {
+			t.e.insertAt(childrenMark, "[")
+			t.e.insert("]", pos)
+			t.e.insertAt(callee, "s")
+			t.jsxImports["jsxs"] = true
+		}
+		if props > 0 || children > 0 {
+			t.e.insert(" }", pos)
+		} else {
+			t.e.insert("}", pos)
+		}
+		if key != "" {
+			t.e.insert(", "+key, pos)
+		}
+		t.e.insert(")", pos)
+		return true
+	}
+	if children == 0 && props > 0 {
+		t.e.insert(" }", pos)
+	}
+	t.e.insert(")", pos)
+	return true
+}
+
+// jsxChild inserts the separator before the child, returns the count of the children.
+func (t *transformer) jsxChild(auto bool, children, props int, childrenMark *mark, pos int) int {
+	switch {
+	case !auto:
+		if children == 0 && props == 0 {
+			t.e.insert(", null", pos)
+		} else if children == 0 {
+			t.e.insert(" }", pos)
+		}
+		t.e.insert(", ", pos)
+	case children == 0:
+		if props > 0 {
+			t.e.insert(", children: ", pos)
+		} else {
+			t.e.insert(" children: ", pos)
+		}
+		*childrenMark = t.e.mark()
+	default:
+		t.e.insert(", ", pos)
+	}
+	return children + 1
+}
+
+// jsxAttrValue transforms the attribute value after the =.
+func (t *transformer) jsxAttrValue() {
+	pos := t.s.pos
+	switch c := t.jsxPeek(); c {
+	case '"', '\'':
+		end := strings.IndexByte(t.s.src[pos+1:], c)
+		if end < 0 {
+			panic(t.errorf(pos, "Unterminated string constant"))
+		}
+		t.s.pos = pos + end + 2
+		t.e.insert(quote(decodeEntities(t.s.src[pos+1:pos+end+1])), pos)
+		t.e.skip(t.s.pos)
+	case '{':
+		t.s.pos++
+		t.jsxExpr()
+	case '<':
+		t.s.pos++
+		t.e.skip(t.s.pos)
+		t.jsxTag(pos)
+	default:
+		panic(t.errorf(pos, "JSX value should be either an expression or a quoted JSX text"))
+	}
+}
+
+// jsxExpr transforms the expression until the }, the { has been consumed.
+func (t *transformer) jsxExpr() {
+	t.s.braces = append(t.s.braces, false)
+	t.e.skip(t.s.pos)
+	t.prevEnd = false
+	t.expr()
+	tok := t.next()
+	if !tok.punct("}") {
+		panic(t.unexpected(tok))
+	}
+	t.e.skip(tok.end)
+}
+
+// jsxText returns the JSX text with the whitespaces collapsed, the lines are trimmed
+// and the empty lines are removed.
+func jsxText(text string) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	lastNonEmpty := -1
+	for i, line := range lines {
+		if strings.TrimLeft(line, " \t") != "" {
+			lastNonEmpty = i
+		}
+	}
+	var sb strings.Builder
+	for i, line := range lines {
+		line = strings.ReplaceAll(line, "\t", " ")
+		if i != 0 {
+			line = strings.TrimLeft(line, " ")
+		}
+		if i != len(lines)-1 {
+			line = strings.TrimRight(line, " ")
+		}
+		if line == "" {
+			continue
+		}
+		sb.WriteString(line)
+		if i != lastNonEmpty {
+			sb.WriteByte(' ')
+		}
+	}
+	return sb.String()
+}
+
+var entities = map[string]string{
+	"amp": "&", "lt": "<", "gt": ">", "quot": `"`, "apos": "'", "nbsp": "\u00a0",
+	"copy": "©", "reg": "®", "trade": "™", "hellip": "…", "mdash": "—", "ndash": "–",
+	"laquo": "«", "raquo": "»", "middot": "·", "times": "×", "divide": "÷", "bull": "•",
+	"lsquo": "‘", "rsquo": "’", "ldquo": "“", "rdquo": "”", "euro": "€", "deg": "°",
+}
+
+var entityRegexp = regexp.MustCompile(`&(#x[0-9a-fA-F]+|#[0-9]+|[a-zA-Z]+);`)
+
+// decodeEntities decodes the HTML entities of the JSX text.
+func decodeEntities(text string) string {
+	if !strings.Contains(text, "&") {
+		return text
+	}
+	return entityRegexp.ReplaceAllStringFunc(text, func(s string) string {
+		name := s[1 : len(s)-1]
+		if v, ok := entities[name]; ok {
+			return v
+		}
+		var code uint64
+		var err error
+		switch {
+		case strings.HasPrefix(name, "#x"):
+			code, err = strconv.ParseUint(name[2:], 16, 32)
+		case strings.HasPrefix(name, "#"):
+			code, err = strconv.ParseUint(name[1:], 10, 32)
+		default:
+			return s
+		}
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return s
+		}
+		return string(rune(code))
+	})
+}
+
+// quote returns the double quoted JavaScript string literal.
+func quote(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			sb.WriteString(`\"`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\u2028', '\u2029':
+			sb.WriteString(`\u` + strconv.FormatInt(int64(r), 16))
+		default:
+			if r < 0x20 {
+				sb.WriteString(`\u00`)
+				sb.WriteString(strconv.FormatInt(int64(r)>>4, 16))
+				sb.WriteString(strconv.FormatInt(int64(r)&0xf, 16))
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// isIdentifier reports whether the name is a valid identifier.
+func isIdentifier(name string) bool {
+	return name != "" && isIdentStart(name, 0) && identEnd(name, 0) == len(name)
+}
+
+// root returns the first identifier of the member expression.
+func root(name string) string {
+	n, _, _ := strings.Cut(name, ".")
+	return n
+}
@@ -0,0 +1,303 @@
+package transform
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type kind uint8
+
+const (
+	eof kind = iota
+	ident
+	privateName
+	number
+	str
+	template
+	regex
+	punct
+)
+
+// token the scanned token, the text is the source slice.
+type token struct {
+	kind       kind
+	text       string
+	start, end int
+	// nl there is a line terminator before the token
+	nl bool
+	// head the template starts with the backtick, tail the template ends with the backtick
+	head, tail bool
+}
+
+func (t token) is(kind kind, text string) bool { return t.kind == kind && t.text == text }
+
+func (t token) punct(text string) bool { return t.is(punct, text) }
+
+func (t token) ident(text string) bool { return t.is(ident, text) }
+
+// scanner scans the tokens on demand, the regex is scanned if allowed by the caller.
+type scanner struct {
+	src    string
+	pos    int
+	braces []bool // the open braces, true if it is a template substitution
+}
+
+// state the scanner state for the lookahead.
+type state struct {
+	pos    int
+	braces []bool
+}
+
+func (s *scanner) save() state { return state{s.pos, slices.Clone(s.braces)} }
+
+func (s *scanner) restore(st state) { s.pos, s.braces = st.pos, st.braces }
+
+// puncts the punctuators ordered by the length.
+var puncts = []string{
+	">>>=",
+	"...", "===", "!==", "**=", "<<=", ">>=", ">>>", "&&=", "||=", "??=",
+	"=>", "==", "!=", "<=", ">=", "&&", "||", "??", "?.", "++", "--", "+=", "-=", "*=", "/=",
+	"%=", "&=", "|=", "^=", "<<", ">>", "**",
+}
+
+// skipSpace skips the whitespaces and comments, returns true if there is a line terminator.
+func (s *scanner) skipSpace() (nl bool) {
+	for s.pos < len(s.src) {
+		c := s.src[s.pos]
+		switch {
+		case c == '\n' || c == '\r':
+			nl = true
+			s.pos++
+		case c == ' ' || c == '\t' || c == '\f' || c == '\v':
+			s.pos++
+		case c == '/' && strings.HasPrefix(s.src[s.pos:], "//"),
+			c == '#' && s.pos == 0 && strings.HasPrefix(s.src, "#!"):
+			end := strings.IndexAny(s.src[s.pos:], "\r\n")
+			if end < 0 {
+				s.pos = len(s.src)
+			} else {
+				s.pos += end
+			}
+		case c == '/' && strings.HasPrefix(s.src[s.pos:], "/*"):
+			end := strings.Index(s.src[s.pos+2:], "*/")
+			if end < 0 {
+				panic(s.errorf(s.pos, "unterminated comment"))
+			}
+			if strings.ContainsAny(s.src[s.pos:s.pos+2+end], "\r\n\u2028\u2029") {
+				nl = true
+			}
+			s.pos += end + 4
+		case c >= utf8.RuneSelf:
+			r, size := utf8.DecodeRuneInString(s.src[s.pos:])
+			if r == '\u2028' || r == '\u2029' {
+				nl = true
+			} else if !unicode.IsSpace(r) && r != '\uFEFF' {
+				return
+			}
+			s.pos += size
+		default:
+			return
+		}
+	}
+	return
+}
+
+// next scans the next token.
+func (s *scanner) next(regexAllowed bool) token {
+	nl := s.skipSpace()
+	start := s.pos
+	tok := token{start: start, nl: nl}
+	if s.pos >= len(s.src) {
+		tok.kind = eof
+		tok.end = s.pos
+		return tok
+	}
+
+	c := s.src[s.pos]
+	switch {
+	case isIdentStart(s.src, s.pos):
+		s.pos = identEnd(s.src, s.pos)
+		tok.kind = ident
+	case c == '#' && s.pos+1 < len(s.src) && isIdentStart(s.src, s.pos+1):
+		s.pos = identEnd(s.src, s.pos+1)
+		tok.kind = privateName
+	case isDigit(c) || c == '.' && s.pos+1 < len(s.src) && isDigit(s.src[s.pos+1]):
+		s.scanNumber()
+		tok.kind = number
+	case c == '"' || c == '\'':
+		s.scanString(c)
+		tok.kind = str
+	case c == '`':
+		s.pos++
+		tok.kind = template
+		tok.head = true
+		tok.tail = s.scanTemplate()
+	case c == '}' && len(s.braces) > 0 && s.braces[len(s.braces)-1]:
+		s.braces = s.braces[:len(s.braces)-1]
+		s.pos++
+		tok.kind = template
+		tok.tail = s.scanTemplate()
+	case c == '/' && regexAllowed:
+		s.scanRegex()
+		tok.kind = regex
+	default:
+		tok.kind = punct
+		s.pos++
+		for _, p := range puncts {
+			if strings.HasPrefix(s.src[start:], p) {
+				// the optional chaining is not followed by a digit, a?.5:1
+				if p == "?." && start+2 < len(s.src) && isDigit(s.src[start+2]) {
+					continue
+				}
+				s.pos = start + len(p)
+				break
+			}
+		}
+		switch c {
+		case '{':
+			s.braces = append(s.braces, false)
+		case '}':
+			if len(s.braces) > 0 {
+				s.braces = s.braces[:len(s.braces)-1]
+			}
+		}
+	}
+	tok.end = s.pos
+	tok.text = s.src[start:s.pos]
+	return tok
+}
+
+func (s *scanner) scanNumber() {
+	src := s.src
+	hex := strings.HasPrefix(src[s.pos:], "0x") || strings.HasPrefix(src[s.pos:], "0X")
+	dot := false
+	for s.pos < len(src) {
+		c := src[s.pos]
+		switch {
+		case isDigit(c) || isLetter(c) || c == '_':
+			s.pos++
+			if !hex && (c == 'e' || c == 'E') && s.pos < len(src) && (src[s.pos] == '+' || src[s.pos] == '-') {
+				s.pos++
+			}
+		case c == '.' && !dot && !hex:
+			dot = true
+			s.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (s *scanner) scanString(quote byte) {
+	start := s.pos
+	s.pos++
+	for s.pos < len(s.src) {
+		switch s.src[s.pos] {
+		case '\\':
+			s.pos += 2
+		case quote:
+			s.pos++
+			return
+		case '\n', '\r':
+			panic(s.errorf(start, "unterminated string"))
+		default:
+			s.pos++
+		}
+	}
+	panic(s.errorf(start, "unterminated string"))
+}
+
+// scanTemplate scans the template characters after the backtick or the substitution,
+// returns true if the template ends, false if a substitution starts.
+func (s *scanner) scanTemplate() bool {
+	start := s.pos
+	for s.pos < len(s.src) {
+		switch s.src[s.pos] {
+		case '\\':
+			s.pos += 2
+		case '`':
+			s.pos++
+			return true
+		case '$':
+			if strings.HasPrefix(s.src[s.pos:], "${") {
+				s.pos += 2
+				s.braces = append(s.braces, true)
+				return false
+			}
+			s.pos++
+		default:
+			s.pos++
+		}
+	}
+	panic(s.errorf(start, "unterminated template"))
+}
+
+func (s *scanner) scanRegex() {
+	start := s.pos
+	s.pos++
+	class := false
+	for {
+		if s.pos >= len(s.src) {
+			panic(s.errorf(start, "unterminated regular expression"))
+		}
+		switch s.src[s.pos] {
+		case '\\':
+			s.pos++
+		case '[':
+			class = true
+		case ']':
+			class = false
+		case '\n', '\r':
+			panic(s.errorf(start, "unterminated regular expression"))
+		case '/':
+			if !class {
+				s.pos++
+				s.pos = identEnd(s.src, s.pos)
+				return
+			}
+		}
+		s.pos++
+	}
+}
+
+func (s *scanner) errorf(pos int, format string, args ...any) *SyntaxError {
+	return newSyntaxError(s.src, pos, format, args...)
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isLetter(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+
+func isIdentStart(src string, pos int) bool {
+	c := src[pos]
+	if c < utf8.RuneSelf {
+		return isLetter(c) || c == '$' || c == '_' || c == '\\'
+	}
+	r, _ := utf8.DecodeRuneInString(src[pos:])
+	return unicode.IsLetter(r)
+}
+
+func identEnd(src string, pos int) int {
+	for pos < len(src) {
+		c := src[pos]
+		if c < utf8.RuneSelf {
+			if !isLetter(c) && !isDigit(c) && c != '$' && c != '_' && c != '\\' {
+				return pos
+			}
+			if c == '\\' {
+				pos++
+			}
+			pos++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(src[pos:])
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.Is(unicode.Mn, r) && !unicode.Is(unicode.Mc, r) &&
+			r != '\u200C' && r != '\u200D' {
+			return pos
+		}
+		pos += size
+	}
+	return pos
+}
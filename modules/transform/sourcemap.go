@@ -0,0 +1,201 @@
+package transform
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// position returns the zero based line and column of the offset.
+func position(src string, pos int) (line, col int) {
+	line = strings.Count(src[:pos], "\n")
+	return line, pos - strings.LastIndexByte(src[:pos], '\n') - 1
+}
+
+// mapping maps the generated position to the source offset.
+type mapping struct {
+	line, col int
+	src       int
+}
+
+// emitter writes the output and the mappings.
+type emitter struct {
+	src       string
+	out       []byte
+	last      int // the source offset copied up to
+	line, col int // the generated position
+	mappings  []mapping
+}
+
+// mark the emitter state to reset.
+type mark struct {
+	out, last, line, col, mappings int
+}
+
+func (e *emitter) mark() mark {
+	return mark{len(e.out), e.last, e.line, e.col, len(e.mappings)}
+}
+
+func (e *emitter) reset(m mark) {
+	e.out, e.last, e.line, e.col, e.mappings = e.out[:m.out], m.last, m.line, m.col, e.mappings[:m.mappings]
+}
+
+func (e *emitter) write(s string) {
+	e.out = append(e.out, s...)
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		e.line += strings.Count(s, "\n")
+		e.col = len(s) - i - 1
+	} else {
+		e.col += len(s)
+	}
+}
+
+// copy copies the source up to the offset.
+func (e *emitter) copy(to int) {
+	if to > e.last {
+		e.write(e.src[e.last:to])
+		e.last = to
+	}
+}
+
+func (e *emitter) addMapping(src int) {
+	if n := len(e.mappings); n > 0 && e.mappings[n-1].line == e.line && e.mappings[n-1].col == e.col {
+		e.mappings[n-1].src = src
+		return
+	}
+	e.mappings = append(e.mappings, mapping{e.line, e.col, src})
+}
+
+// token copies the source token.
+func (e *emitter) token(start, end int) {
+	e.copy(start)
+	e.addMapping(start)
+	e.copy(end)
+}
+
+// blank replaces the source up to the offset with spaces, the line terminators are kept.
+func (e *emitter) blank(to int) {
+	if to <= e.last {
+		return
+	}
+	b := []byte(e.src[e.last:to])
+	for i, c := range b {
+		if c != '\n' && c != '\r' {
+			b[i] = ' '
+		}
+	}
+	e.write(string(b))
+	e.last = to
+}
+
+// skip skips the source up to the offset, only the line terminators are written.
+func (e *emitter) skip(to int) {
+	if to <= e.last {
+		return
+	}
+	e.write(strings.Repeat("\n", strings.Count(e.src[e.last:to], "\n")))
+	e.last = to
+}
+
+// insert writes the generated code which maps to the source offset.
+func (e *emitter) insert(s string, src int) {
+	e.addMapping(src)
+	e.write(s)
+}
+
+// insertAt inserts the code without line terminators at the mark,
+// the mappings after the mark on the same line are shifted.
+func (e *emitter) insertAt(m mark, s string) {
+	e.out = append(e.out[:m.out], append([]byte(s), e.out[m.out:]...)...)
+	for i := m.mappings; i < len(e.mappings); i++ {
+		if e.mappings[i].line == m.line && e.mappings[i].col >= m.col {
+			e.mappings[i].col += len(s)
+		}
+	}
+	if e.line == m.line {
+		e.col += len(s)
+	}
+}
+
+// cut removes the output after the mark and returns it, the source offset is kept.
+func (e *emitter) cut(m mark) string {
+	s := string(e.out[m.out:])
+	e.out, e.line, e.col, e.mappings = e.out[:m.out], m.line, m.col, e.mappings[:m.mappings]
+	return s
+}
+
+// sourceMap returns the source map v3 of the mappings.
+func (e *emitter) sourceMap(name string) []byte {
+	sort.SliceStable(e.mappings, func(i, j int) bool {
+		a, b := e.mappings[i], e.mappings[j]
+		return a.line < b.line || a.line == b.line && a.col < b.col
+	})
+
+	lines := []int{0}
+	for i := range len(e.src) {
+		if e.src[i] == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+
+	var sb strings.Builder
+	var line, prevCol, prevLine, prevSrcCol int
+	first := true
+	for _, m := range e.mappings {
+		for line < m.line {
+			sb.WriteByte(';')
+			line++
+			prevCol = 0
+			first = true
+		}
+		if !first {
+			sb.WriteByte(',')
+		}
+		first = false
+		srcLine := sort.SearchInts(lines, m.src+1) - 1
+		srcCol := m.src - lines[srcLine]
+		vlq(&sb, m.col-prevCol)
+		vlq(&sb, 0)
+		vlq(&sb, srcLine-prevLine)
+		vlq(&sb, srcCol-prevSrcCol)
+		prevCol, prevLine, prevSrcCol = m.col, srcLine, srcCol
+	}
+
+	data, _ := json.Marshal(map[string]any{
+		"version":        3,
+		"sources":        []string{filepath.Base(name)},
+		"sourcesContent": []string{e.src},
+		"names":          []string{},
+		"mappings":       sb.String(),
+	})
+	return data
+}
+
+// inline returns the output with the inline source map.
+func (e *emitter) inline(name string) string {
+	return string(e.out) + "\n//# sourceMappingURL=data:application/json;base64," +
+		base64.StdEncoding.EncodeToString(e.sourceMap(name))
+}
+
+const base64Chars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+// vlq writes the base64 VLQ of the value.
+func vlq(sb *strings.Builder, value int) {
+	v := value << 1
+	if value < 0 {
+		v = -value<<1 | 1
+	}
+	for {
+		digit := v & 31
+		v >>= 5
+		if v > 0 {
+			digit |= 32
+		}
+		sb.WriteByte(base64Chars[digit])
+		if v == 0 {
+			return
+		}
+	}
+}
@@ -0,0 +1,1021 @@
+// Package transform transforms the TypeScript and JSX to JavaScript.
+//
+// The types are erased in place, the declarations which only exist in
+// the type system (interface, type, declare, overloads) are removed, the
+// enums and the parameter properties are compiled to JavaScript. The JSX
+// elements are compiled to the factory calls of the classic runtime or the
+// jsx calls of the automatic runtime. The output keeps the lines of the
+// source and has the inline source map which maps to the source.
+package transform
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Options the transform options.
+type Options struct {
+	// JSX the options of the JSX transform
+	JSX JSX
+}
+
+// JSX the options of the JSX transform, the pragma comments @jsx, @jsxFrag,
+// @jsxImportSource and @jsxRuntime of the source override the options.
+type JSX struct {
+	// Runtime "classic" calls the Factory, "automatic" imports the jsx functions
+	// from the ImportSource/jsx-runtime. Default "classic", or "automatic" if the
+	// ImportSource is set.
+	Runtime string
+	// Factory the element factory of the classic runtime, default React.createElement
+	Factory string
+	// Fragment the fragment of the classic runtime, default React.Fragment
+	Fragment string
+	// ImportSource the module prefix of the automatic runtime, default react
+	ImportSource string
+}
+
+// Extensions the file extensions to transform.
+var Extensions = []string{".ts", ".mts", ".cts", ".tsx", ".jsx"}
+
+// Supported reports whether the file is transformed by the extension.
+func Supported(name string) bool {
+	return slices.Contains(Extensions, strings.ToLower(filepath.Ext(name)))
+}
+
+// SyntaxError the syntax error of the source.
+type SyntaxError struct {
+	File         string
+	Line, Column int
+	Message      string
+}
+
+func newSyntaxError(src string, pos int, format string, args ...any) *SyntaxError {
+	line, col := position(src, pos)
+	return &SyntaxError{Line: line + 1, Column: col + 1, Message: fmt.Sprintf(format, args...)}
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("SyntaxError: %s: Line %d:%d %s", e.File, e.Line, e.Column, e.Message)
+}
+
+// Transform transforms the TypeScript or JSX source to JavaScript by the file extension,
+// the source of other extensions is returned as is.
+func Transform(name, source string, opts Options) (code string, err error) {
+	ext := strings.ToLower(filepath.Ext(name))
+	if !slices.Contains(Extensions, ext) {
+		return source, nil
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			var se *SyntaxError
+			switch e := r.(type) {
+			case *SyntaxError:
+				se = e
+			case typeError:
+				se = newSyntaxError(source, e.pos, "type expected")
+			default:
+				panic(r)
+			}
+			se.File = name
+			err = se
+		}
+	}()
+
+	ts, jsx := ext != ".jsx", ext == ".tsx" || ext == ".jsx"
+	t := newTransformer(source, ts, jsx, opts.JSX)
+	if ts {
+		// the first pass collects the identifiers used as value and the type names,
+		// the second pass elides the imports which are only used as type.
+		t.collect = true
+		t.run()
+		used, types := t.used, t.types
+		t = newTransformer(source, ts, jsx, opts.JSX)
+		t.used, t.types = used, types
+	}
+	t.run()
+	return t.e.inline(name), nil
+}
+
+type transformer struct {
+	s       scanner
+	e       emitter
+	ts, jsx bool
+	jsxOpts JSX
+	// prevEnd the previous token ends an expression, the next / is a division
+	prevEnd  bool
+	afterDot bool
+	// heritage the class heritage, the type arguments can be followed by {
+	heritage bool
+	// collect the first pass collects the used identifiers and the type names
+	collect bool
+	used    map[string]bool
+	types   map[string]bool
+	// jsxImports the used imports of the automatic runtime
+	jsxImports map[string]bool
+}
+
+func newTransformer(src string, ts, jsx bool, opts JSX) *transformer {
+	t := &transformer{
+		s:          scanner{src: src},
+		e:          emitter{src: src},
+		ts:         ts,
+		jsx:        jsx,
+		used:       make(map[string]bool),
+		types:      make(map[string]bool),
+		jsxImports: make(map[string]bool),
+	}
+	if jsx {
+		t.jsxOpts = pragma(src, opts)
+	}
+	return t
+}
+
+func (t *transformer) run() {
+	t.statements(false, nil)
+	t.e.copy(len(t.s.src))
+	t.jsxImport()
+}
+
+func (t *transformer) errorf(pos int, format string, args ...any) *SyntaxError {
+	return newSyntaxError(t.s.src, pos, format, args...)
+}
+
+func (t *transformer) unexpected(tok token) *SyntaxError {
+	if tok.kind == eof {
+		return t.errorf(tok.start, "Unexpected end of input")
+	}
+	return t.errorf(tok.start, "Unexpected token %s", tok.text)
+}
+
+func (t *transformer) next() token { return t.s.next(!t.prevEnd) }
+
+// peekN returns the next n tokens without consuming.
+func (t *transformer) peekN(n int) []token {
+	st := t.s.save()
+	prevEnd := t.prevEnd
+	toks := make([]token, n)
+	for i := range toks {
+		toks[i] = t.s.next(!prevEnd)
+		prevEnd = endsExpr(toks[i])
+	}
+	t.s.restore(st)
+	return toks
+}
+
+func (t *transformer) peek() token { return t.peekN(1)[0] }
+
+func (t *transformer) expect(text string) token {
+	tok := t.next()
+	if !tok.punct(text) {
+		panic(t.unexpected(tok))
+	}
+	return tok
+}
+
+// emit copies the source token.
+func (t *transformer) emit(tok token) {
+	t.e.token(tok.start, tok.end)
+	t.prevEnd = endsExpr(tok)
+	if tok.kind == ident && !t.afterDot {
+		t.use(tok.text)
+	}
+	t.afterDot = tok.punct(".") || tok.punct("?.")
+}
+
+// use marks the identifier used as value.
+func (t *transformer) use(name string) {
+	if t.collect {
+		t.used[name] = true
+	}
+}
+
+// erase erases the tokens consumed by the fn.
+func (t *transformer) erase(fn func()) {
+	fn()
+	t.e.blank(t.s.pos)
+}
+
+// semicolon emits the optional semicolon.
+func (t *transformer) semicolon() {
+	if t.peek().punct(";") {
+		t.emit(t.next())
+	}
+}
+
+// operatorKeywords the keywords which do not end an expression.
+var operatorKeywords = map[string]bool{
+	"await": true, "case": true, "delete": true, "do": true, "else": true, "extends": true,
+	"in": true, "instanceof": true, "new": true, "of": true, "return": true, "throw": true,
+	"typeof": true, "void": true, "yield": true,
+}
+
+func endsExpr(tok token) bool {
+	switch tok.kind {
+	case ident:
+		return !operatorKeywords[tok.text]
+	case number, str, regex, privateName:
+		return true
+	case template:
+		return tok.tail
+	case punct:
+		switch tok.text {
+		case ")", "]", "}", "++", "--":
+			return true
+		}
+	}
+	return false
+}
+
+// continues reports whether the token on the next line continues the expression.
+func continues(tok token) bool {
+	switch tok.kind {
+	case punct:
+		switch tok.text {
+		case "++", "--", "!", "~", "{", "@":
+			return false
+		}
+		return true
+	case template:
+		return true
+	case ident:
+		return tok.text == "in" || tok.text == "instanceof"
+	}
+	return false
+}
+
+// statements transforms the statements until the } of the block.
+func (t *transformer) statements(block bool, inj *injection) {
+	for {
+		t.prevEnd = false
+		tok := t.peek()
+		if tok.kind == eof {
+			if block {
+				panic(t.unexpected(tok))
+			}
+			return
+		}
+		if tok.punct("}") {
+			if !block {
+				panic(t.unexpected(tok))
+			}
+			if inj != nil {
+				t.e.insert(" "+inj.code, inj.pos)
+			}
+			return
+		}
+		isSuper := inj != nil && tok.ident("super")
+		t.statement()
+		if isSuper {
+			t.e.insert(" "+inj.code, inj.pos)
+			inj = nil
+		}
+	}
+}
+
+func (t *transformer) statement() {
+	t.prevEnd = false
+	m := t.e.mark()
+	toks := t.peekN(2)
+	tok, next := toks[0], toks[1]
+	switch tok.kind {
+	case punct:
+		switch tok.text {
+		case "{":
+			t.block(nil)
+			return
+		case ";":
+			t.emit(t.next())
+			return
+		case "@":
+			panic(t.errorf(tok.start, "Decorators are not supported"))
+		}
+	case ident:
+		if t.declaration(m) {
+			return
+		}
+		switch tok.text {
+		case "if", "while", "with", "switch":
+			t.emit(t.next())
+			t.parens()
+			return
+		case "for":
+			t.emit(t.next())
+			if t.peek().ident("await") {
+				t.emit(t.next())
+			}
+			t.forHead()
+			return
+		case "catch":
+			t.emit(t.next())
+			if t.peek().punct("(") {
+				t.emit(t.next())
+				t.binding()
+				t.annotation()
+				t.emit(t.expect(")"))
+			}
+			return
+		case "do", "else", "try", "finally":
+			t.emit(t.next())
+			return
+		case "return", "throw":
+			t.emit(t.next())
+			if p := t.peek(); p.nl || p.punct(";") || p.punct("}") || p.kind == eof {
+				t.semicolon()
+				return
+			}
+		case "break", "continue":
+			t.emit(t.next())
+			if p := t.peek(); p.kind == ident && !p.nl {
+				t.emit(t.next())
+			}
+			t.semicolon()
+			return
+		case "case":
+			t.emit(t.next())
+			t.expr()
+			t.emit(t.expect(":"))
+			return
+		case "default":
+			if next.punct(":") {
+				t.emit(t.next())
+				t.emit(t.next())
+				return
+			}
+		default:
+			if next.punct(":") && !operatorKeywords[tok.text] {
+				// label
+				t.emit(t.next())
+				t.emit(t.next())
+				return
+			}
+		}
+	}
+	t.expressionStatement()
+}
+
+func (t *transformer) expressionStatement() {
+	start := t.s.pos
+	for {
+		t.expr()
+		if !t.peek().punct(",") {
+			break
+		}
+		t.emit(t.next())
+		t.prevEnd = false
+	}
+	tok := t.peek()
+	switch {
+	case tok.punct(";"):
+		t.emit(t.next())
+	case tok.punct("}"), tok.kind == eof:
+	default:
+		if t.s.pos == start || !tok.nl && !t.prevEnd {
+			panic(t.unexpected(tok))
+		}
+	}
+}
+
+// block transforms the block, the injection is inserted at the start of the block
+// or after the super call.
+func (t *transformer) block(inj *injection) {
+	t.emit(t.expect("{"))
+	if inj != nil && !inj.afterSuper {
+		t.e.insert(" "+inj.code, inj.pos)
+		inj = nil
+	}
+	t.statements(true, inj)
+	t.emit(t.expect("}"))
+}
+
+// parens transforms the parenthesized expression.
+func (t *transformer) parens() {
+	t.emit(t.expect("("))
+	t.list(")")
+	t.emit(t.expect(")"))
+	t.prevEnd = false
+}
+
+func (t *transformer) forHead() {
+	t.emit(t.expect("("))
+	t.prevEnd = false
+	toks := t.peekN(2)
+	if tok := toks[0]; tok.ident("var") || tok.ident("const") ||
+		tok.ident("let") && (toks[1].kind == ident || toks[1].punct("[") || toks[1].punct("{")) {
+		t.emit(t.next())
+		t.declarators()
+	}
+	t.list(")")
+	t.emit(t.expect(")"))
+	t.prevEnd = false
+}
+
+// list transforms the comma separated expressions until the close token.
+func (t *transformer) list(close string) {
+	for {
+		t.expr()
+		tok := t.peek()
+		switch {
+		case tok.punct(close):
+			return
+		case tok.kind == eof:
+			panic(t.unexpected(tok))
+		case tok.punct(")"), tok.punct("]"), tok.punct("}"):
+			panic(t.unexpected(tok))
+		default:
+			// the separators and the keywords of the for head
+			t.emit(t.next())
+			t.prevEnd = false
+		}
+	}
+}
+
+func (t *transformer) declarators() {
+	for {
+		t.prevEnd = false
+		t.binding()
+		if p := t.peek(); p.punct("!") && t.ts {
+			t.erase(func() { t.next() })
+		}
+		t.annotation()
+		if t.peek().punct("=") {
+			t.emit(t.next())
+			t.expr()
+		}
+		if !t.peek().punct(",") {
+			return
+		}
+		t.emit(t.next())
+	}
+}
+
+// binding transforms the binding identifier or pattern.
+func (t *transformer) binding() {
+	t.prevEnd = false
+	tok := t.next()
+	switch {
+	case tok.punct("{"):
+		t.emit(tok)
+		t.object()
+		t.emit(t.expect("}"))
+	case tok.punct("["):
+		t.emit(tok)
+		t.list("]")
+		t.emit(t.expect("]"))
+	case tok.kind == ident:
+		t.emit(tok)
+	default:
+		panic(t.unexpected(tok))
+	}
+}
+
+// annotation erases the optional type annotation.
+func (t *transformer) annotation() {
+	if t.ts && t.peek().punct(":") {
+		t.erase(func() {
+			t.s.next(false)
+			t.typ()
+		})
+	}
+}
+
+// expr transforms the expression until the , ; ) ] } or the unmatched :
+func (t *transformer) expr() {
+	ternary := 0
+	for {
+		tok := t.peek()
+		if tok.kind == eof {
+			return
+		}
+		if t.prevEnd {
+			switch {
+			case t.ts && (tok.ident("as") || tok.ident("satisfies")) && !tok.nl:
+				t.next()
+				t.typ()
+				// the statement ends after the type, the next line must not continue the expression
+				if next := t.peek(); next.nl && (next.punct("(") || next.punct("[") || next.kind == template && next.head) {
+					t.e.insert(";", tok.start)
+					t.e.blank(t.s.pos)
+					return
+				}
+				t.e.blank(t.s.pos)
+				continue
+			case t.ts && tok.punct("!") && !tok.nl:
+				t.erase(func() { t.next() })
+				continue
+			case t.ts && tok.punct("<") && t.typeArguments():
+				continue
+			case tok.nl && !continues(tok):
+				return
+			case tok.kind == ident && tok.text != "in" && tok.text != "instanceof":
+				return
+			case tok.punct("{"):
+				return
+			}
+		}
+
+		switch tok.kind {
+		case template:
+			if !tok.head {
+				return
+			}
+			t.template()
+			continue
+		case punct:
+			switch tok.text {
+			case ",", ";", ")", "]", "}":
+				return
+			case ":":
+				if ternary == 0 {
+					return
+				}
+				ternary--
+			case "?":
+				ternary++
+			case "=>":
+				t.emit(t.next())
+				t.arrowBody()
+				continue
+			case "(":
+				if !t.prevEnd && t.isArrow() {
+					t.arrow()
+					continue
+				}
+				t.emit(t.next())
+				t.list(")")
+				t.emit(t.expect(")"))
+				continue
+			case "[":
+				t.emit(t.next())
+				t.list("]")
+				t.emit(t.expect("]"))
+				continue
+			case "{":
+				t.emit(t.next())
+				t.object()
+				t.emit(t.expect("}"))
+				continue
+			case "<", "<<", "<=":
+				if t.prevEnd || tok.text != "<" {
+					break
+				}
+				if t.jsx {
+					if toks := t.peekN(3); !t.ts || toks[1].kind != ident ||
+						!toks[2].punct(",") && !toks[2].ident("extends") || !t.genericArrow() {
+						t.jsxElement(t.next())
+						continue
+					}
+				} else if t.ts && !t.genericArrow() {
+					// type assertion
+					t.erase(func() {
+						t.s.next(false)
+						t.typ()
+						if !t.closeAngle() {
+							t.typeFail(t.s.next(false))
+						}
+					})
+					continue
+				}
+				t.arrow()
+				continue
+			}
+		case ident:
+			switch tok.text {
+			case "function":
+				if !t.prevEnd {
+					t.emit(t.next())
+					if !t.function() {
+						panic(t.unexpected(t.peek()))
+					}
+					t.prevEnd = true
+					continue
+				}
+			case "class":
+				if !t.prevEnd {
+					t.emit(t.next())
+					t.class()
+					t.prevEnd = true
+					continue
+				}
+			case "async":
+				if next := t.peekN(2)[1]; !t.prevEnd && !next.nl &&
+					(next.kind == ident || next.punct("(") || next.punct("<")) {
+					t.emit(t.next())
+					t.prevEnd = false
+					continue
+				}
+			}
+		}
+		t.emit(t.next())
+	}
+}
+
+// template transforms the template literal.
+func (t *transformer) template() {
+	tok := t.next()
+	t.emit(tok)
+	for !tok.tail {
+		t.prevEnd = false
+		t.expr()
+		tok = t.next()
+		if tok.kind != template || tok.head {
+			panic(t.unexpected(tok))
+		}
+		t.emit(tok)
+	}
+}
+
+// typeArguments erases the type arguments of the call, returns false if the < is an operator.
+func (t *transformer) typeArguments() bool {
+	ok := t.try(func() {
+		t.typeArgs()
+		st := t.s.save()
+		p := t.s.next(false)
+		t.s.restore(st)
+		if !p.punct("(") && !(p.kind == template && p.head) &&
+			!(t.heritage && (p.punct("{") || p.ident("implements"))) {
+			t.typeFail(p)
+		}
+	})
+	if ok {
+		t.e.blank(t.s.pos)
+	}
+	return ok
+}
+
+// isArrow reports whether the parenthesized tokens are the arrow function parameters.
+func (t *transformer) isArrow() bool {
+	st := t.s.save()
+	defer t.s.restore(st)
+	return t.try(func() {
+		if tok := t.s.next(false); !tok.punct("(") {
+			t.typeFail(tok)
+		}
+		t.balanced(")")
+		p := t.s.next(false)
+		if p.punct(":") && t.ts {
+			t.typ()
+			p = t.s.next(false)
+		}
+		if !p.punct("=>") || p.nl {
+			t.typeFail(p)
+		}
+	})
+}
+
+// genericArrow erases the type parameters of the arrow function, returns false
+// if it is not an arrow function.
+func (t *transformer) genericArrow() bool {
+	ok := t.try(func() {
+		t.typeParams()
+		if !t.isArrow() {
+			t.typeFail(t.peek())
+		}
+	})
+	if ok {
+		t.e.blank(t.s.pos)
+	}
+	return ok
+}
+
+func (t *transformer) arrow() {
+	t.params(nil)
+	t.annotation()
+	t.emit(t.expect("=>"))
+	t.arrowBody()
+}
+
+func (t *transformer) arrowBody() {
+	if t.peek().punct("{") {
+		t.block(nil)
+		t.prevEnd = true
+		return
+	}
+	t.prevEnd = false
+	t.expr()
+}
+
+// paramModifiers the modifiers of the parameter properties.
+var paramModifiers = map[string]bool{
+	"public": true, "private": true, "protected": true, "readonly": true, "override": true,
+}
+
+// param the parameter property.
+type param struct {
+	name string
+	pos  int
+}
+
+// params transforms the parameters, the parameter properties are appended to the props.
+func (t *transformer) params(props *[]param) {
+	t.emit(t.expect("("))
+	for {
+		t.prevEnd = false
+		toks := t.peekN(2)
+		tok := toks[0]
+		switch {
+		case tok.punct(")"):
+			t.emit(t.next())
+			return
+		case tok.punct("@"):
+			panic(t.errorf(tok.start, "Decorators are not supported"))
+		case tok.punct(","):
+			t.emit(t.next())
+			continue
+		case t.ts && tok.ident("this") && (toks[1].punct(":") || toks[1].punct(",") || toks[1].punct(")")):
+			t.erase(func() {
+				t.next()
+				if t.peek().punct(":") {
+					t.s.next(false)
+					t.typ()
+				}
+				if t.peek().punct(",") {
+					t.next()
+				}
+			})
+			continue
+		}
+
+		modifier := false
+		for t.ts {
+			toks = t.peekN(2)
+			if !paramModifiers[toks[0].text] || toks[0].kind != ident ||
+				toks[1].kind != ident && !toks[1].punct("{") && !toks[1].punct("[") {
+				break
+			}
+			modifier = true
+			t.erase(func() { t.next() })
+		}
+		if t.peek().punct("...") {
+			t.emit(t.next())
+		}
+		if name := t.peek(); modifier && props != nil && name.kind == ident {
+			*props = append(*props, param{name.text, name.start})
+		}
+		t.binding()
+		if t.ts && t.peek().punct("?") {
+			t.erase(func() { t.next() })
+		}
+		t.annotation()
+		if t.peek().punct("=") {
+			t.emit(t.next())
+			t.prevEnd = false
+			t.expr()
+		}
+	}
+}
+
+// object transforms the members of the object literal or pattern until the }.
+func (t *transformer) object() {
+	for {
+		t.prevEnd = false
+		tok := t.peek()
+		switch {
+		case tok.punct("}"):
+			return
+		case tok.kind == eof:
+			panic(t.unexpected(tok))
+		case tok.punct(","):
+			t.emit(t.next())
+			continue
+		case tok.punct("..."):
+			t.emit(t.next())
+			t.expr()
+			continue
+		}
+
+		for {
+			tok = t.peek()
+			if (tok.ident("async") || tok.ident("get") || tok.ident("set")) && t.memberNameFollows() || tok.punct("*") {
+				t.emit(t.next())
+				continue
+			}
+			break
+		}
+		t.propertyName()
+		switch tok = t.peek(); {
+		case tok.punct("(") || tok.punct("<"):
+			if !t.signature(nil) {
+				panic(t.unexpected(t.peek()))
+			}
+			t.block(nil)
+		case tok.punct(":"), tok.punct("="):
+			t.emit(t.next())
+			t.prevEnd = false
+			t.expr()
+		}
+	}
+}
+
+// memberNameFollows reports whether the modifier is followed by the member name.
+func (t *transformer) memberNameFollows() bool {
+	next := t.peekN(2)[1]
+	if next.nl {
+		return false
+	}
+	switch next.kind {
+	case ident, str, number, privateName:
+		return true
+	case punct:
+		return next.punct("[") || next.punct("*")
+	}
+	return false
+}
+
+func (t *transformer) propertyName() {
+	tok := t.next()
+	switch tok.kind {
+	case ident, str, number, privateName:
+		t.emit(tok)
+	case punct:
+		if !tok.punct("[") {
+			panic(t.unexpected(tok))
+		}
+		t.emit(tok)
+		t.prevEnd = false
+		t.expr()
+		t.emit(t.expect("]"))
+	default:
+		panic(t.unexpected(tok))
+	}
+}
+
+// signature transforms the type parameters, parameters and the return type,
+// returns false if it is not followed by the body.
+func (t *transformer) signature(props *[]param) bool {
+	if t.ts && t.peek().punct("<") {
+		t.erase(t.typeParams)
+	}
+	t.params(props)
+	t.annotation()
+	return t.peek().punct("{")
+}
+
+// function transforms the function after the function keyword,
+// returns false if it is an overload signature without body.
+func (t *transformer) function() bool {
+	if t.peek().punct("*") {
+		t.emit(t.next())
+	}
+	if tok := t.peek(); tok.kind == ident {
+		t.emit(t.next())
+	}
+	if !t.signature(nil) {
+		return false
+	}
+	t.block(nil)
+	return true
+}
+
+// class transforms the class after the class keyword.
+func (t *transformer) class() {
+	if tok := t.peek(); tok.kind == ident && !tok.ident("extends") && !tok.ident("implements") {
+		t.emit(t.next())
+	}
+	if t.ts && t.peek().punct("<") {
+		t.erase(t.typeParams)
+	}
+	extends := false
+	if t.peek().ident("extends") {
+		extends = true
+		t.emit(t.next())
+		t.heritage = true
+		t.expr()
+		t.heritage = false
+	}
+	if t.ts && t.peek().ident("implements") {
+		t.erase(func() {
+			t.next()
+			for {
+				t.typ()
+				if !t.peek().punct(",") {
+					break
+				}
+				t.next()
+			}
+		})
+	}
+
+	t.emit(t.expect("{"))
+	for {
+		t.prevEnd = false
+		toks := t.peekN(2)
+		switch tok := toks[0]; {
+		case tok.punct("}"):
+			t.emit(t.next())
+			return
+		case tok.punct(";"):
+			t.emit(t.next())
+		case tok.punct("@"):
+			panic(t.errorf(tok.start, "Decorators are not supported"))
+		case tok.ident("static") && toks[1].punct("{"):
+			t.emit(t.next())
+			t.block(nil)
+		default:
+			t.member(extends)
+		}
+	}
+}
+
+// memberModifiers the modifiers of the class members, the TypeScript modifiers are erased.
+var memberModifiers = map[string]bool{
+	"static": false, "async": false, "get": false, "set": false, "accessor": false,
+	"public": true, "private": true, "protected": true, "readonly": true, "override": true,
+	"declare": true, "abstract": true,
+}
+
+// injection the code of the parameter properties inserted to the constructor.
+type injection struct {
+	code       string
+	pos        int
+	afterSuper bool
+}
+
+// member transforms the class member.
+func (t *transformer) member(extends bool) {
+	m := t.e.mark()
+	ambient := false
+	for {
+		tok := t.peek()
+		erased, ok := memberModifiers[tok.text]
+		if tok.kind != ident || !ok || !t.memberNameFollows() {
+			break
+		}
+		if erased && t.ts {
+			ambient = ambient || tok.text == "declare" || tok.text == "abstract"
+			t.erase(func() { t.next() })
+		} else {
+			t.emit(t.next())
+		}
+	}
+	if t.peek().punct("*") {
+		t.emit(t.next())
+	}
+
+	remove := func() {
+		t.e.reset(m)
+		if t.peek().punct(";") {
+			t.next()
+		}
+		t.e.blank(t.s.pos)
+	}
+
+	if t.ts && t.isIndexSignature() {
+		t.s.next(false)
+		t.balanced("]")
+		if t.peek().punct("?") {
+			t.next()
+		}
+		if t.peek().punct(":") {
+			t.s.next(false)
+			t.typ()
+		}
+		remove()
+		return
+	}
+
+	name := t.peek()
+	t.propertyName()
+	if tok := t.peek(); t.ts && (tok.punct("?") || tok.punct("!")) {
+		t.erase(func() { t.next() })
+	}
+
+	if tok := t.peek(); tok.punct("(") || tok.punct("<") {
+		var props []param
+		ctor := name.ident("constructor") || name.kind == str && name.text[1:len(name.text)-1] == "constructor"
+		if !t.signature(&props) || ambient {
+			remove()
+			return
+		}
+		var inj *injection
+		if ctor && len(props) > 0 {
+			inj = &injection{pos: props[0].pos, afterSuper: extends}
+			for _, p := range props {
+				inj.code += fmt.Sprintf("this.%s = %s;", p.name, p.name)
+			}
+		}
+		t.block(inj)
+		return
+	}
+
+	t.annotation()
+	if t.peek().punct("=") {
+		t.emit(t.next())
+		t.prevEnd = false
+		t.expr()
+	}
+	if ambient {
+		remove()
+		return
+	}
+	t.semicolon()
+}
+
+// isIndexSignature reports whether the member is an index signature, such as [key: string]: any
+func (t *transformer) isIndexSignature() bool {
+	st := t.s.save()
+	defer t.s.restore(st)
+	return t.s.next(false).punct("[") && t.s.next(false).kind == ident && t.s.next(false).punct(":")
+}
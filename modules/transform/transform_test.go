@@ -0,0 +1,203 @@
+package transform
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// code returns the output without the source map, the whitespaces are collapsed.
+func code(t *testing.T, name, source string, opts Options) string {
+	out, err := Transform(name, source, opts)
+	require.NoError(t, err)
+	i := strings.LastIndex(out, "\n//# sourceMappingURL=")
+	require.Positive(t, i)
+	return strings.Join(strings.Fields(out[:i]), " ")
+}
+
+func TestTransform(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name, source, expected string
+	}{
+		{
+			"annotations",
+			`let x: number = f<string>(1 as any)!, y!: string; const g = <T,>(a: T, b?: number): T => a;`,
+			`let x = f (1 ) , y ; const g = (a , b ) => a;`,
+		},
+		{
+			"declarations",
+			"interface A<T = {}> extends B<T> { a: T }\ntype C = A<string> | null;\ndeclare const d: number;\nexport type { A };\nlet e;",
+			`let e;`,
+		},
+		{
+			"function overloads",
+			`export function f(a: string): string; export function f(a: any) { return a }`,
+			`export function f(a ) { return a }`,
+		},
+		{
+			"class",
+			`abstract class A<T> extends B<T> implements C { private x?: number = 1; declare y: string; abstract z(): void; constructor(public a: T, readonly b = 2) { super(); } }`,
+			`class A extends B { x = 1; constructor( a , b = 2) { super(); this.a = a;this.b = b; } }`,
+		},
+		{
+			"enum",
+			`export enum E { A, B = 5, C, D = "d", F = B << 1 }`,
+			`export var E; (function (E) { E[E["A"] = 0] = "A"; E[E["B"] = 5] = "B"; E[E["C"] = 6] = "C"; E["D"] = "d"; E[E["F"] = E["B"] << 1] = "F"; })(E || (E = {}));`,
+		},
+		{
+			"import elision",
+			`import type T from "t"; import D, { type A, B, C } from "m"; import * as ns from "ns"; export { C }; let b: B = ns;`,
+			`import { C } from "m"; import * as ns from "ns"; export { C }; let b = ns;`,
+		},
+		{
+			"type assertion",
+			`const a = <any>b, c = (d satisfies E) as unknown as F;`,
+			`const a = b, c = (d ) ;`,
+		},
+		{
+			"type assertion line end",
+			"let g = x satisfies Y\n(foo)()\nconst h = y as T[]\n[1, 2].forEach(f)\nlet i = z as T\n`t`\nlet j = w as T\n+ 1",
+			"let g = x; (foo)() const h = y; [1, 2].forEach(f) let i = z; `t` let j = w + 1",
+		},
+		{
+			"type query",
+			`let u: typeof import("x"); let v: typeof import("x").y<string>[], w: typeof a.b = 1;`,
+			`let u ; let v , w = 1;`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, code(t, "test.ts", tc.source, Options{}))
+		})
+	}
+}
+
+func TestJSX(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name, source, expected string
+		opts                   Options
+	}{
+		{
+			"classic",
+			`const a = <div className="a" data-id={1} {...p} hidden>Hi &amp; {name}<br /></div>;`,
+			`const a = React.createElement("div", { className: "a", "data-id": 1, ...p, hidden: true }, "Hi & ", name, React.createElement("br"));`,
+			Options{},
+		},
+		{
+			"text",
+			"const a = <p>\n  first line\n  second   line\n  {/* comment */}\n</p>;",
+			`const a = React.createElement("p" , null, "first line second line" );`,
+			Options{},
+		},
+		{
+			"fragment",
+			`const a = <><A.B /></>;`,
+			`const a = React.createElement(React.Fragment, null, React.createElement(A.B));`,
+			Options{},
+		},
+		{
+			"pragma",
+			"/** @jsx h */\n/** @jsxFrag Frag */\nconst a = <><i /></>;",
+			`/** @jsx h */ /** @jsxFrag Frag */ const a = h(Frag, null, h("i"));`,
+			Options{},
+		},
+		{
+			"self closing",
+			"render(<App />); const E = <br/>; const c = <div>{cond ? <a /> : null}</div>;",
+			`render(React.createElement(App)); const E = React.createElement("br"); const c = React.createElement("div", null, cond ? React.createElement("a") : null);`,
+			Options{},
+		},
+		{
+			"self closing automatic",
+			"render(<App />); const c = cond ? <a href={u} /> : null;",
+			`import { jsx as _jsx } from "react/jsx-runtime";render(_jsx(App, {})); const c = cond ? _jsx("a", { href: u }) : null;`,
+			Options{JSX: JSX{Runtime: "automatic"}},
+		},
+		{
+			"automatic",
+			`const a = <ul key="k">{items.map(i => <li key={i}>{i}</li>)}<li /></ul>;`,
+			`import { jsx as _jsx, jsxs as _jsxs } from "preact/jsx-runtime";const a = _jsxs("ul", { children: [items.map(i => _jsx("li", { children: i }, i)), _jsx("li", {})] }, "k");`,
+			Options{JSX: JSX{ImportSource: "preact"}},
+		},
+		{
+			"key after spread",
+			`const a = <div {...rest} key={1}><b {...p} /><i key="i" {...p} /></div>;`,
+			`import { jsx as _jsx } from "react/jsx-runtime";import { createElement as _createElement } from "react";const a = _createElement("div", { ...rest, key: 1 }, _jsx("b", { ...p }), _jsx("i", { ...p }, "i"));`,
+			Options{JSX: JSX{Runtime: "automatic"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, code(t, "test.jsx", tc.source, tc.opts))
+		})
+	}
+
+	t.Run("tsx", func(t *testing.T) {
+		source := `import React from "react"; const A = ({ name }: { name: string }) => <b title={name as string}>{name}</b>;`
+		assert.Equal(t,
+			`import React from "react"; const A = ({ name } ) => React.createElement("b", { title: name }, name);`,
+			code(t, "test.tsx", source, Options{}))
+	})
+
+	t.Run("tsx type arguments", func(t *testing.T) {
+		source := `const a = <C<string> p={1} />, b = <List<Array<number>>>{x}</List>;`
+		assert.Equal(t,
+			`const a = React.createElement(C, { p: 1 }), b = React.createElement(List, null, x);`,
+			code(t, "test.tsx", source, Options{}))
+	})
+}
+
+func TestSourceMap(t *testing.T) {
+	t.Parallel()
+
+	source := "interface A { a: number }\nconst a = <T,>(x: T): T => x;\nthrow new Error(`${a}`);\n"
+	out, err := Transform("/src/app.ts", source, Options{})
+	require.NoError(t, err)
+
+	generated, url, ok := strings.Cut(out, "\n//# sourceMappingURL=data:application/json;base64,")
+	require.True(t, ok)
+	// the lines and the columns of the source are kept
+	assert.Equal(t, strings.Count(source, "\n"), strings.Count(generated, "\n"))
+	assert.Equal(t, strings.Index(source, "throw"), strings.Index(generated, "throw"))
+
+	data, err := base64.StdEncoding.DecodeString(url)
+	require.NoError(t, err)
+	var sourceMap struct {
+		Version        int      `json:"version"`
+		Sources        []string `json:"sources"`
+		SourcesContent []string `json:"sourcesContent"`
+		Mappings       string   `json:"mappings"`
+	}
+	require.NoError(t, json.Unmarshal(data, &sourceMap))
+	assert.Equal(t, 3, sourceMap.Version)
+	assert.Equal(t, []string{"app.ts"}, sourceMap.Sources)
+	assert.Equal(t, []string{source}, sourceMap.SourcesContent)
+	assert.Len(t, strings.Split(sourceMap.Mappings, ";"), 3)
+}
+
+func TestSyntaxError(t *testing.T) {
+	t.Parallel()
+
+	for source, message := range map[string]string{
+		"let a: = 1;":              "Line 1:8 type expected",
+		"namespace A {}":           "Line 1:1 Namespaces are not supported",
+		"const a = <div></span>;":  "Line 1:16 Expected corresponding JSX closing tag for <div>",
+		"@dec class A {}":          "Line 1:1 Decorators are not supported",
+		"const a = \"unterminated": "Line 1:11 unterminated string",
+	} {
+		_, err := Transform("test.tsx", source, Options{})
+		var syntaxError *SyntaxError
+		require.ErrorAs(t, err, &syntaxError)
+		assert.ErrorContains(t, err, "test.tsx: "+message)
+	}
+}
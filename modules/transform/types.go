@@ -0,0 +1,276 @@
+package transform
+
+import "strings"
+
+// typeError the type can not be parsed at the offset.
+type typeError struct{ pos int }
+
+func (t *transformer) typeFail(tok token) { panic(typeError{tok.start}) }
+
+// try runs the fn, the scanner is restored if the fn fails to parse.
+func (t *transformer) try(fn func()) (ok bool) {
+	st := t.s.save()
+	defer func() {
+		if r := recover(); r != nil {
+			switch r.(type) {
+			case typeError, *SyntaxError:
+			default:
+				panic(r)
+			}
+			t.s.restore(st)
+			ok = false
+		}
+	}()
+	fn()
+	return true
+}
+
+func (t *transformer) tpeek() token {
+	st := t.s.save()
+	defer t.s.restore(st)
+	return t.s.next(false)
+}
+
+func (t *transformer) expectType(text string) {
+	if tok := t.s.next(false); !tok.punct(text) {
+		t.typeFail(tok)
+	}
+}
+
+// typ skips the type.
+func (t *transformer) typ() {
+	tok := t.tpeek()
+	switch {
+	case tok.punct("<"):
+		t.typeParams()
+		t.functionType()
+		return
+	case tok.ident("new"):
+		t.s.next(false)
+		if t.tpeek().punct("<") {
+			t.typeParams()
+		}
+		t.functionType()
+		return
+	case tok.ident("abstract"):
+		t.s.next(false)
+		t.typ()
+		return
+	}
+
+	t.unionType()
+	if p := t.tpeek(); p.ident("extends") && !p.nl {
+		// conditional type
+		t.s.next(false)
+		t.unionType()
+		t.expectType("?")
+		t.typ()
+		t.expectType(":")
+		t.typ()
+	}
+}
+
+// functionType skips the parameters and the return type of the function type.
+func (t *transformer) functionType() {
+	t.expectType("(")
+	t.balanced(")")
+	t.expectType("=>")
+	t.typ()
+}
+
+func (t *transformer) unionType() {
+	if p := t.tpeek(); p.punct("|") || p.punct("&") {
+		t.s.next(false)
+	}
+	t.primaryType()
+	for {
+		if p := t.tpeek(); !p.punct("|") && !p.punct("&") {
+			return
+		}
+		t.s.next(false)
+		t.primaryType()
+	}
+}
+
+func (t *transformer) primaryType() {
+	tok := t.s.next(false)
+	switch tok.kind {
+	case punct:
+		switch tok.text {
+		case "(":
+			t.balanced(")")
+			if t.tpeek().punct("=>") {
+				t.s.next(false)
+				t.typ()
+				return
+			}
+		case "{":
+			t.balanced("}")
+		case "[":
+			t.balanced("]")
+		case "-":
+			if n := t.s.next(false); n.kind != number {
+				t.typeFail(n)
+			}
+		case "<":
+			t.s.pos = tok.start
+			t.typ()
+			return
+		default:
+			t.typeFail(tok)
+		}
+	case str, number:
+	case template:
+		for !tok.tail {
+			t.typ()
+			tok = t.s.next(false)
+			if tok.kind != template || tok.head {
+				t.typeFail(tok)
+			}
+		}
+	case ident:
+		switch tok.text {
+		case "keyof", "unique", "readonly", "infer":
+			if p := t.tpeek(); p.kind == ident || p.punct("(") || p.punct("[") || p.punct("{") {
+				t.primaryType()
+				return
+			}
+		case "typeof":
+			if t.tpeek().ident("import") {
+				t.primaryType()
+				return
+			}
+			t.entityName()
+		case "asserts":
+			if p := t.tpeek(); p.kind == ident && !p.nl {
+				t.s.next(false)
+				if p := t.tpeek(); p.ident("is") && !p.nl {
+					t.s.next(false)
+					t.typ()
+				}
+				return
+			}
+		case "import":
+			t.expectType("(")
+			t.balanced(")")
+			for t.tpeek().punct(".") {
+				t.s.next(false)
+				t.s.next(false)
+			}
+			if t.tpeek().punct("<") {
+				t.typeArgs()
+			}
+		default:
+			t.s.pos = tok.start
+			t.entityName()
+			if p := t.tpeek(); p.ident("is") && !p.nl {
+				// type predicate
+				t.s.next(false)
+				t.typ()
+				return
+			}
+		}
+	default:
+		t.typeFail(tok)
+	}
+
+	// array and indexed access types
+	for {
+		if p := t.tpeek(); !p.punct("[") || p.nl {
+			return
+		}
+		t.s.next(false)
+		t.balanced("]")
+	}
+}
+
+// entityName skips the qualified name and the type arguments.
+func (t *transformer) entityName() {
+	for {
+		if tok := t.s.next(false); tok.kind != ident {
+			t.typeFail(tok)
+		}
+		if !t.tpeek().punct(".") {
+			break
+		}
+		t.s.next(false)
+	}
+	if p := t.tpeek(); p.punct("<") && !p.nl {
+		t.typeArgs()
+	}
+}
+
+// balanced skips the tokens until the close bracket.
+func (t *transformer) balanced(close string) {
+	depth := 0
+	for {
+		tok := t.s.next(false)
+		switch {
+		case tok.kind == eof:
+			t.typeFail(tok)
+		case tok.kind != punct:
+		case tok.text == "(" || tok.text == "[" || tok.text == "{":
+			depth++
+		case tok.text == ")" || tok.text == "]" || tok.text == "}":
+			if depth == 0 {
+				if tok.text != close {
+					t.typeFail(tok)
+				}
+				return
+			}
+			depth--
+		}
+	}
+}
+
+// typeArgs skips the type arguments.
+func (t *transformer) typeArgs() {
+	t.expectType("<")
+	for {
+		t.typ()
+		if t.closeAngle() {
+			return
+		}
+		t.expectType(",")
+	}
+}
+
+// typeParams skips the type parameters.
+func (t *transformer) typeParams() {
+	t.expectType("<")
+	for {
+		if t.closeAngle() {
+			return
+		}
+		tok := t.s.next(false)
+		for (tok.ident("const") || tok.ident("in") || tok.ident("out")) && t.tpeek().kind == ident {
+			tok = t.s.next(false)
+		}
+		if tok.kind != ident {
+			t.typeFail(tok)
+		}
+		if t.tpeek().ident("extends") {
+			t.s.next(false)
+			t.typ()
+		}
+		if t.tpeek().punct("=") {
+			t.s.next(false)
+			t.typ()
+		}
+		if t.closeAngle() {
+			return
+		}
+		t.expectType(",")
+	}
+}
+
+// closeAngle consumes the > which may be the first character of the >> or >=.
+func (t *transformer) closeAngle() bool {
+	st := t.s.save()
+	if tok := t.s.next(false); tok.kind == punct && strings.HasPrefix(tok.text, ">") {
+		t.s.pos = tok.start + 1
+		return true
+	}
+	t.s.restore(st)
+	return false
+}
@@ -0,0 +1,98 @@
+package modules
+
+import (
+	"context"
+	"maps"
+	"os"
+	"sync"
+	"time"
+)
+
+type (
+	// watcher polls the modification time of the file modules.
+	watcher struct {
+		ctx      context.Context
+		interval time.Duration
+		onChange func(specifier string)
+
+		once  sync.Once
+		mu    sync.Mutex
+		files map[string]watchFile // the module URL to the file
+	}
+
+	watchFile struct {
+		path    string
+		modTime time.Time
+		size    int64
+	}
+)
+
+// WithWatch watches the file modules by polling in the interval until the ctx is done,
+// the changed modules and the modules which import them are invalidated,
+// then the onChange is called with the module URL.
+func WithWatch(ctx context.Context, interval time.Duration, onChange func(specifier string)) Option {
+	return func(o *loader) {
+		o.watcher = &watcher{
+			ctx:      ctx,
+			interval: interval,
+			onChange: onChange,
+			files:    make(map[string]watchFile),
+		}
+	}
+}
+
+// add watches the file of the module.
+func (w *watcher) add(ml *loader, specifier, path string) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	w.mu.Lock()
+	w.files[specifier] = watchFile{path, stat.ModTime(), stat.Size()}
+	w.mu.Unlock()
+	w.once.Do(func() { go w.run(ml) })
+}
+
+func (w *watcher) run(ml *loader) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, specifier := range w.changed() {
+			ml.Invalidate(specifier)
+			if w.onChange != nil {
+				w.onChange(specifier)
+			}
+		}
+	}
+}
+
+// changed returns the changed or removed files, they are watched again when loaded.
+func (w *watcher) changed() (changed []string) {
+	w.mu.Lock()
+	files := maps.Clone(w.files)
+	w.mu.Unlock()
+
+	for specifier, file := range files {
+		stat, err := os.Stat(file.path)
+		if err == nil && stat.ModTime().Equal(file.modTime) && stat.Size() == file.size {
+			continue
+		}
+		changed = append(changed, specifier)
+	}
+
+	w.mu.Lock()
+	for _, specifier := range changed {
+		// the file may be loaded again
+		if w.files[specifier] == files[specifier] {
+			delete(w.files, specifier)
+		}
+	}
+	w.mu.Unlock()
+	return
+}
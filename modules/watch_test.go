@@ -0,0 +1,48 @@
+package modules
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grafana/sobek"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	write := func(name, data string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(data), 0o600))
+	}
+	write("main.js", `import value from "./value.js"; export default value;`)
+	write("value.js", `export default 1;`)
+
+	changed := make(chan string, 1)
+	ml := NewLoader(WithBase(&url.URL{Scheme: "file", Path: dir}),
+		WithWatch(t.Context(), 10*time.Millisecond, func(specifier string) { changed <- specifier }))
+	value := func() any {
+		vm := NewTestVM(t, ml)
+		mod, err := ml.ResolveModule(nil, "./main.js")
+		require.NoError(t, err)
+		require.NoError(t, mod.Link())
+		Result(vm.CyclicModuleRecordEvaluate(mod.(sobek.CyclicModuleRecord), ml.ResolveModule))
+		return vm.NamespaceObjectFor(mod).Get("default").Export()
+	}
+	assert.EqualValues(t, 1, value())
+
+	write("value.js", `export default 2;`)
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "value.js"), later, later))
+
+	select {
+	case specifier := <-changed:
+		assert.Equal(t, (&url.URL{Scheme: "file", Path: filepath.Join(dir, "value.js")}).String(), specifier)
+	case <-time.After(time.Second):
+		t.Fatal("change not detected")
+	}
+	assert.EqualValues(t, 2, value())
+}
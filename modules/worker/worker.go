@@ -0,0 +1,401 @@
+// Package worker the Worker implementation
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/grafana/sobek"
+	"github.com/shiroyk/ski/js"
+	"github.com/shiroyk/ski/js/types"
+	"github.com/shiroyk/ski/modules"
+	"github.com/shiroyk/ski/modules/clone"
+	_ "github.com/shiroyk/ski/modules/dom"
+)
+
+func init() {
+	modules.Register("worker", modules.Global{
+		"Worker": new(Worker),
+	})
+}
+
+// Worker runs a module on a separate VM and communicates with it by messages.
+// https://developer.mozilla.org/en-US/docs/Web/API/Worker
+type Worker struct{}
+
+func (w *Worker) Instantiate(rt *sobek.Runtime) (sobek.Value, error) {
+	ctor := rt.ToValue(w.constructor).ToObject(rt)
+	p := w.prototype(rt)
+	_ = p.DefineDataProperty("constructor", ctor, sobek.FLAG_FALSE, sobek.FLAG_FALSE, sobek.FLAG_FALSE)
+	_ = ctor.SetPrototype(p)
+	_ = ctor.Set("prototype", p)
+	return ctor, nil
+}
+
+func (w *Worker) prototype(rt *sobek.Runtime) *sobek.Object {
+	p := rt.NewObject()
+	e := rt.Get("EventTarget")
+	if e == nil {
+		panic(rt.NewTypeError("EventTarget is undefined"))
+	}
+	_ = p.SetPrototype(e.ToObject(rt).Get("prototype").ToObject(rt))
+	_ = p.Set("postMessage", w.postMessage)
+	_ = p.Set("terminate", w.terminate)
+	_ = p.DefineAccessorProperty("onmessage", rt.ToValue(w.getOnmessage), rt.ToValue(w.setOnmessage), sobek.FLAG_FALSE, sobek.FLAG_TRUE)
+	_ = p.DefineAccessorProperty("onerror", rt.ToValue(w.getOnerror), rt.ToValue(w.setOnerror), sobek.FLAG_FALSE, sobek.FLAG_TRUE)
+	_ = p.SetSymbol(sobek.SymToStringTag, func(sobek.FunctionCall) sobek.Value { return rt.ToValue("Worker") })
+	return p
+}
+
+func (w *Worker) constructor(call sobek.ConstructorCall, rt *sobek.Runtime) *sobek.Object {
+	if len(call.Arguments) < 1 {
+		panic(rt.NewTypeError("Failed to construct 'Worker': 1 argument required, but only 0 present."))
+	}
+
+	specifier := call.Argument(0).String()
+	var name string
+	if options := call.Argument(1); !sobek.IsUndefined(options) && !sobek.IsNull(options) {
+		if v := options.ToObject(rt).Get("name"); v != nil && !sobek.IsUndefined(v) {
+			name = v.String()
+		}
+	}
+
+	obj := types.New(rt, "EventTarget")
+	ctx, cancel := context.WithCancel(js.Context(rt))
+	this := &worker{
+		rt:        rt,
+		this:      obj,
+		name:      name,
+		onmessage: newEventHandler("message"),
+		onerror:   newEventHandler("error"),
+		parent:    js.GetEventLoop(rt),
+		ref:       js.EnqueueJob(rt),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+	_ = obj.SetSymbol(symWorker, this)
+	_ = obj.SetPrototype(call.This.Prototype())
+
+	go this.run(specifier)
+
+	return obj
+}
+
+// postMessage sends a message to the worker.
+func (*Worker) postMessage(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	this := toWorker(rt, call.This)
+	data, err := clone.Serialize(rt, call.Argument(0), clone.Transfer(rt, call.Argument(1))...)
+	if err != nil {
+		clone.Throw(rt, err)
+	}
+	this.postMessage(data)
+	return sobek.Undefined()
+}
+
+// terminate immediately terminates the worker.
+func (*Worker) terminate(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	toWorker(rt, call.This).terminate()
+	return sobek.Undefined()
+}
+
+func (*Worker) getOnmessage(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	return toWorker(rt, call.This).onmessage.value
+}
+
+func (*Worker) setOnmessage(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	this := toWorker(rt, call.This)
+	this.onmessage.set(rt, this.this, call.Argument(0))
+	return sobek.Undefined()
+}
+
+func (*Worker) getOnerror(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	return toWorker(rt, call.This).onerror.value
+}
+
+func (*Worker) setOnerror(call sobek.FunctionCall, rt *sobek.Runtime) sobek.Value {
+	this := toWorker(rt, call.This)
+	this.onerror.set(rt, this.this, call.Argument(0))
+	return sobek.Undefined()
+}
+
+var symWorker = sobek.NewSymbol("Symbol.Worker")
+
+func toWorker(rt *sobek.Runtime, value sobek.Value) *worker {
+	if o, ok := value.(*sobek.Object); ok {
+		if v := o.GetSymbol(symWorker); v != nil {
+			return v.Export().(*worker)
+		}
+	}
+	panic(rt.NewTypeError(`Value of "this" must be of type Worker`))
+}
+
+type worker struct {
+	// the parent side, only used on the parent goroutine
+	rt        *sobek.Runtime
+	this      *sobek.Object
+	name      string
+	onmessage *eventHandler
+	onerror   *eventHandler
+
+	parent *js.EventLoop
+	ref    js.Enqueue // keeps the parent EventLoop alive while the worker running
+	unref  sync.Once
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu         sync.Mutex
+	closed     bool           // the worker no longer accepts messages
+	terminated bool           // the parent no longer receives messages
+	pending    []any          // messages posted before the worker is ready
+	loop       *js.EventLoop  // the worker EventLoop, nil before the worker is ready
+	runtime    *sobek.Runtime // the worker runtime
+	keep       js.Enqueue     // keeps the worker EventLoop alive until closed
+	release    sync.Once
+}
+
+// run the worker module on a new VM, the worker VM is stopped when
+// the parent context is done or the worker is terminated.
+func (w *worker) run(specifier string) {
+	vm := js.NewVM()
+	err := vm.Run(w.ctx, func() error {
+		err := w.start(vm.Runtime(), specifier)
+		if err != nil {
+			w.close()
+		}
+		return err
+	})
+	w.exit(err)
+}
+
+// start setups the worker global scope and evaluates the module.
+func (w *worker) start(rt *sobek.Runtime, specifier string) error {
+	module, err := js.Loader().ResolveModule(nil, specifier)
+	if err != nil {
+		return err
+	}
+	cm, ok := module.(sobek.CyclicModuleRecord)
+	if !ok {
+		return modules.ErrInvalidModule
+	}
+
+	// the global addEventListener of the dom module listens to self
+	global := rt.GlobalObject()
+	onmessage := newEventHandler("message")
+	_ = global.DefineAccessorProperty("onmessage",
+		rt.ToValue(func(sobek.FunctionCall) sobek.Value { return onmessage.value }),
+		rt.ToValue(func(call sobek.FunctionCall) sobek.Value {
+			onmessage.set(rt, global, call.Argument(0))
+			return sobek.Undefined()
+		}), sobek.FLAG_FALSE, sobek.FLAG_TRUE)
+	_ = rt.Set("self", global)
+	_ = rt.Set("name", w.name)
+	_ = rt.Set("postMessage", func(call sobek.FunctionCall) sobek.Value {
+		data, err := clone.Serialize(rt, call.Argument(0), clone.Transfer(rt, call.Argument(1))...)
+		if err != nil {
+			clone.Throw(rt, err)
+		}
+		w.postParent(data)
+		return sobek.Undefined()
+	})
+	_ = rt.Set("close", func(sobek.FunctionCall) sobek.Value {
+		w.close()
+		return sobek.Undefined()
+	})
+
+	loop := js.GetEventLoop(rt)
+	w.mu.Lock()
+	w.runtime = rt
+	w.keep = loop.EnqueueJob()
+	w.mu.Unlock()
+
+	promise, err := js.EvaluateModule(rt, cm)
+	if err != nil {
+		return err
+	}
+
+	// the messages are delivered after the top-level await finished
+	enqueue := loop.EnqueueJob()
+	then, _ := sobek.AssertFunction(rt.ToValue(promise).ToObject(rt).Get("then"))
+	_, err = then(rt.ToValue(promise),
+		rt.ToValue(func(sobek.FunctionCall) sobek.Value {
+			enqueue(func() error { return w.ready(rt, loop) })
+			return sobek.Undefined()
+		}),
+		rt.ToValue(func(sobek.FunctionCall) sobek.Value {
+			enqueue(func() error {
+				w.close()
+				// returns the rejection of the evaluation
+				_, err := js.ModuleInstance(rt, cm)
+				return err
+			})
+			return sobek.Undefined()
+		}))
+	return err
+}
+
+// ready delivers the messages posted before the worker module evaluated.
+func (w *worker) ready(rt *sobek.Runtime, loop *js.EventLoop) error {
+	w.mu.Lock()
+	w.loop = loop
+	pending := w.pending
+	w.pending = nil
+	w.mu.Unlock()
+
+	for _, data := range pending {
+		if err := dispatch(rt, rt.GlobalObject(), data); err != nil {
+			w.close()
+			return err
+		}
+	}
+	return nil
+}
+
+// postMessage sends the message to the worker, the messages posted
+// before the worker is ready are delivered after the module evaluated.
+func (w *worker) postMessage(data any) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	switch {
+	case w.closed:
+	case w.loop == nil:
+		w.pending = append(w.pending, data)
+	default:
+		rt := w.runtime
+		w.loop.EnqueueJob()(func() error {
+			return dispatch(rt, rt.GlobalObject(), data)
+		})
+	}
+}
+
+// postParent sends the message from the worker to the parent.
+func (w *worker) postParent(data any) {
+	if w.isTerminated() {
+		return
+	}
+	w.parent.EnqueueJob()(func() error {
+		if w.isTerminated() {
+			return nil
+		}
+		return dispatch(w.rt, w.this, data)
+	})
+}
+
+// close stops the worker accepting messages, the worker EventLoop
+// exits after the pending jobs are done.
+func (w *worker) close() {
+	w.mu.Lock()
+	w.closed = true
+	keep := w.keep
+	w.mu.Unlock()
+	if keep != nil {
+		w.release.Do(func() { keep(func() error { return nil }) })
+	}
+}
+
+func (w *worker) isTerminated() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.terminated
+}
+
+// terminate stops the worker VM immediately.
+func (w *worker) terminate() {
+	w.mu.Lock()
+	w.terminated = true
+	w.mu.Unlock()
+	w.close()
+	w.cancel()
+	w.unref.Do(func() { w.ref(func() error { return nil }) })
+}
+
+// exit releases the parent EventLoop when the worker VM finished,
+// the error is dispatched to the parent as an error event.
+func (w *worker) exit(err error) {
+	w.close()
+	w.cancel()
+	w.unref.Do(func() {
+		w.ref(func() error {
+			if err == nil || errors.Is(err, context.Canceled) {
+				return nil
+			}
+			event := types.New(w.rt, "Event", w.rt.ToValue("error"))
+			_ = event.Set("message", err.Error())
+			_ = event.Set("error", err)
+			return dispatchEvent(w.this, event)
+		})
+	})
+}
+
+// dispatch the message event to the target, the data is deserialized in the target runtime.
+func dispatch(rt *sobek.Runtime, target *sobek.Object, data any) error {
+	value, err := clone.Deserialize(rt, data)
+	if err != nil {
+		return err
+	}
+	init := rt.NewObject()
+	_ = init.Set("data", value)
+	var event *sobek.Object
+	if ex := rt.Try(func() {
+		event = types.New(rt, "MessageEvent", rt.ToValue("message"), init)
+	}); ex != nil {
+		return ex
+	}
+	return dispatchEvent(target, event)
+}
+
+// dispatchEvent dispatches the event to the target listeners.
+func dispatchEvent(target, event *sobek.Object) error {
+	if fn, ok := sobek.AssertFunction(target.Get("dispatchEvent")); ok {
+		_, err := fn(target, event)
+		return err
+	}
+	return nil
+}
+
+// eventHandler the event handler property like onmessage. The handler is added
+// as an event listener when set, so it is called in the registration order.
+type eventHandler struct {
+	typ      string
+	value    sobek.Value
+	listener sobek.Value // calls the current value
+}
+
+func newEventHandler(typ string) *eventHandler {
+	return &eventHandler{typ: typ, value: sobek.Null()}
+}
+
+// set the handler, the listener of the handler is added to the target.
+func (h *eventHandler) set(rt *sobek.Runtime, target *sobek.Object, value sobek.Value) {
+	if _, ok := sobek.AssertFunction(value); !ok {
+		value = sobek.Null()
+	}
+	h.value = value
+
+	method := "addEventListener"
+	switch {
+	case sobek.IsNull(value) && h.listener != nil:
+		method = "removeEventListener"
+	case !sobek.IsNull(value) && h.listener == nil:
+		h.listener = rt.ToValue(func(call sobek.FunctionCall) sobek.Value {
+			fn, ok := sobek.AssertFunction(h.value)
+			if !ok {
+				return sobek.Undefined()
+			}
+			ret, err := fn(target, call.Arguments...)
+			if err != nil {
+				js.Throw(rt, err)
+			}
+			return ret
+		})
+	default:
+		return
+	}
+	fn, _ := sobek.AssertFunction(target.Get(method))
+	if _, err := fn(target, rt.ToValue(h.typ), h.listener); err != nil {
+		js.Throw(rt, err)
+	}
+	if method == "removeEventListener" {
+		h.listener = nil
+	}
+}
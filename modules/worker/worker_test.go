@@ -0,0 +1,175 @@
+package worker
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shiroyk/ski/js/modulestest"
+	_ "github.com/shiroyk/ski/modules/timers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorker(t *testing.T) {
+	t.Parallel()
+	vm := modulestest.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	script := func(t *testing.T, source string) string {
+		path := filepath.Join(dir, t.Name()[len("TestWorker/"):]+".js")
+		require.NoError(t, os.WriteFile(path, []byte(source), 0o600))
+		return "file://" + filepath.ToSlash(path)
+	}
+
+	t.Run("postMessage", func(t *testing.T) {
+		url := script(t, `
+			onmessage = (e) => {
+				postMessage(e.data * 2);
+				close();
+			};
+		`)
+		result, err := vm.RunModule(ctx, `
+			export default (url) => new Promise((resolve) => {
+				const worker = new Worker(url);
+				worker.onmessage = (e) => resolve(e.data);
+				worker.postMessage(21);
+			});
+		`, url)
+		require.NoError(t, err)
+		assert.EqualValues(t, 42, modulestest.PromiseResult(result).ToInteger())
+	})
+
+	t.Run("addEventListener", func(t *testing.T) {
+		url := script(t, `
+			self.addEventListener("message", (e) => {
+				postMessage({ name, echo: e.data });
+			});
+		`)
+		result, err := vm.RunModule(ctx, `
+			export default (url) => new Promise((resolve) => {
+				const worker = new Worker(url, { name: "echo" });
+				assert.true(worker instanceof EventTarget);
+				worker.addEventListener("message", (e) => {
+					assert.true(e instanceof MessageEvent);
+					worker.terminate();
+					resolve(e.data.name + ":" + e.data.echo.join(","));
+				});
+				worker.postMessage(["a", "b"]);
+			});
+		`, url)
+		require.NoError(t, err)
+		assert.Equal(t, "echo:a,b", modulestest.PromiseResult(result).String())
+	})
+
+	t.Run("listener order", func(t *testing.T) {
+		url := script(t, `
+			const calls = [];
+			addEventListener("message", (e) => calls.push("first:" + (e.target === self)));
+			onmessage = function (e) { calls.push("onmessage:" + (this === self)) };
+			addEventListener("message", () => postMessage(calls.concat("last")));
+		`)
+		result, err := vm.RunModule(ctx, `
+			export default (url) => new Promise((resolve) => {
+				const worker = new Worker(url);
+				worker.onmessage = (e) => {
+					worker.terminate();
+					resolve(e.data.join(",") + ":" + (e.target === worker));
+				};
+				worker.postMessage(null);
+			});
+		`, url)
+		require.NoError(t, err)
+		assert.Equal(t, "first:true,onmessage:true,last:true", modulestest.PromiseResult(result).String())
+	})
+
+	t.Run("top-level await", func(t *testing.T) {
+		url := script(t, `
+			const prefix = await new Promise((resolve) => setTimeout(() => resolve("ready:"), 10));
+			onmessage = (e) => {
+				postMessage(prefix + e.data);
+				close();
+			};
+		`)
+		result, err := vm.RunModule(ctx, `
+			export default (url) => new Promise((resolve) => {
+				const worker = new Worker(url);
+				worker.onmessage = (e) => resolve(e.data);
+				worker.postMessage("a");
+			});
+		`, url)
+		require.NoError(t, err)
+		assert.Equal(t, "ready:a", modulestest.PromiseResult(result).String())
+	})
+
+	t.Run("structured clone", func(t *testing.T) {
+		url := script(t, `
+			onmessage = (e) => {
+				const { map, buffer } = e.data;
+				postMessage({ size: map.size, bytes: buffer.byteLength, self: e.data.self === e.data });
+				close();
+			};
+		`)
+		result, err := vm.RunModule(ctx, `
+			export default (url) => new Promise((resolve) => {
+				const worker = new Worker(url);
+				worker.onmessage = (e) => resolve(e.data);
+				const buffer = new ArrayBuffer(8);
+				const data = { map: new Map([[1, 2]]), buffer };
+				data.self = data;
+				worker.postMessage(data, [buffer]);
+				assert.equal(buffer.byteLength, 0);
+			});
+		`, url)
+		require.NoError(t, err)
+		obj := modulestest.PromiseResult(result).ToObject(vm.Runtime())
+		assert.EqualValues(t, 1, obj.Get("size").ToInteger())
+		assert.EqualValues(t, 8, obj.Get("bytes").ToInteger())
+		assert.True(t, obj.Get("self").ToBoolean())
+	})
+
+	t.Run("onerror", func(t *testing.T) {
+		url := script(t, `throw new Error("worker failed");`)
+		result, err := vm.RunModule(ctx, `
+			export default (url) => new Promise((resolve) => {
+				const worker = new Worker(url);
+				worker.onerror = (e) => resolve(e.message);
+			});
+		`, url)
+		require.NoError(t, err)
+		assert.Contains(t, modulestest.PromiseResult(result).String(), "worker failed")
+	})
+
+	t.Run("terminate", func(t *testing.T) {
+		url := script(t, `setInterval(() => postMessage("tick"), 10);`)
+		ctx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		result, err := vm.RunModule(ctx, `
+			export default (url) => new Promise((resolve) => {
+				const worker = new Worker(url);
+				let count = 0;
+				worker.onmessage = () => {
+					if (++count === 2) {
+						worker.terminate();
+						resolve(count);
+					}
+				};
+			});
+		`, url)
+		require.NoError(t, err)
+		assert.EqualValues(t, 2, modulestest.PromiseResult(result).ToInteger())
+	})
+
+	t.Run("parent context", func(t *testing.T) {
+		url := script(t, `setInterval(() => {}, 10);`)
+		ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+		_, err := vm.RunModule(ctx, `
+			export default (url) => { new Worker(url); }
+		`, url)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
@@ -1,13 +1,18 @@
 package ski
 
 import (
+	"container/list"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"runtime"
+	"slices"
+	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/grafana/sobek"
 	"github.com/shiroyk/ski/js"
 )
 
@@ -37,7 +42,24 @@ type SchedulerOptions struct {
 	MaxVMs        uint          `yaml:"max-vms" json:"maxVMs"`
 	GetMaxRetries uint          `yaml:"get-max-retries" json:"maxRetries"`
 	GetTimeout    time.Duration `yaml:"get-timeout" json:"timeout"`
-	VMOptions     []js.Option   `yaml:"-"` // options for NewVM
+	// MaxRunsPerVM discard the VM after it has run the given times, zero means unlimited
+	MaxRunsPerVM uint `yaml:"max-runs-per-vm" json:"maxRunsPerVM"`
+	// MaxVMAge discard the VM after it has been created for the given duration, zero means unlimited
+	MaxVMAge time.Duration `yaml:"max-vm-age" json:"maxVMAge"`
+	// DiscardOnError discard the VM when its run returns an error or panics
+	DiscardOnError bool `yaml:"discard-on-error" json:"discardOnError"`
+	// IdleTimeout evict the VM which has been idle for the given duration,
+	// keeps at least InitialVMs, zero means never evict
+	IdleTimeout time.Duration `yaml:"idle-timeout" json:"idleTimeout"`
+	// ShrinkInterval the interval of checking the idle VMs, default is IdleTimeout
+	ShrinkInterval time.Duration `yaml:"shrink-interval" json:"shrinkInterval"`
+	// Preload the modules evaluated when each VM created, later runs reuse the live instances.
+	// The module can be a specifier resolved by the js.Loader or a compiled sobek.CyclicModuleRecord.
+	Preload []any `yaml:"preload" json:"preload"`
+	// OnPreloadError call when the preload module failed, default logs the error
+	OnPreloadError func(module any, err error) `yaml:"-"`
+	MetricsSink    MetricsSink                 `yaml:"-"` // receives the telemetry, default discards it
+	VMOptions      []js.Option                 `yaml:"-"` // options for NewVM
 }
 
 func init() {
@@ -52,6 +74,10 @@ func GetScheduler() Scheduler { return scheduler.Load().(Scheduler) }
 
 // Scheduler the js.VM scheduler
 type Scheduler interface {
+	// Get a VM, the VM is released back to the Scheduler when its run finish.
+	// Get blocks until a VM is available, the ctx is done or GetTimeout * GetMaxRetries elapsed,
+	// the waiters are served in FIFO order.
+	Get(ctx context.Context) (js.VM, error)
 	// Shrink the idle VM to initial VM size
 	Shrink()
 	// Metrics Scheduler metrics
@@ -76,109 +102,319 @@ func NewScheduler(opt SchedulerOptions) Scheduler {
 	}
 
 	s := &schedulerImpl{
-		vms:        make(chan js.VM, opt.MaxVMs),
-		maxVMs:     int32(opt.MaxVMs),
-		maxRetries: opt.GetMaxRetries,
-		timeout:    opt.GetTimeout,
+		idle:           make([]*pooledVM, 0, opt.MaxVMs),
+		maxVMs:         int32(opt.MaxVMs),
+		timeout:        opt.GetTimeout * time.Duration(opt.GetMaxRetries),
+		maxRuns:        opt.MaxRunsPerVM,
+		maxAge:         opt.MaxVMAge,
+		discardOnError: opt.DiscardOnError,
+		sink:           opt.MetricsSink,
+	}
+	if s.sink == nil {
+		s.sink = noopSink{}
+	}
+	s.opt = append(slices.Clip(opt.VMOptions), js.WithRunStats(s.sink.ObserveRun))
+	if len(opt.Preload) > 0 {
+		s.opt = append(s.opt, js.WithPreload(opt.Preload...))
+	}
+	if opt.OnPreloadError != nil {
+		s.opt = append(s.opt, js.WithPreloadError(opt.OnPreloadError))
 	}
-	s.opt = append(opt.VMOptions, js.WithRelease(s.release))
 
 	s.initial = opt.InitialVMs
 	if s.initial > opt.MaxVMs {
 		s.initial = opt.MaxVMs
 	}
 
-	s.active.Store(int32(s.initial))
+	s.active = int32(s.initial)
 	for range s.initial {
-		s.vms <- js.NewVM(s.opt...)
+		vm := s.newVM()
+		vm.idleSince = vm.created
+		s.idle = append(s.idle, vm)
+	}
+
+	if opt.IdleTimeout > 0 {
+		if opt.ShrinkInterval <= 0 {
+			opt.ShrinkInterval = opt.IdleTimeout
+		}
+		s.idleTimeout = opt.IdleTimeout
+		s.done = make(chan struct{})
+		go s.autoShrink(opt.ShrinkInterval)
 	}
 
 	return s
 }
 
 type schedulerImpl struct {
-	vms        chan js.VM
-	active     atomic.Int32
-	maxVMs     int32
-	maxRetries uint
-	initial    uint
-	timeout    time.Duration
-	closed     atomic.Bool
-	opt        []js.Option
-}
-
-func (s *schedulerImpl) get() (js.VM, error) {
-	for range s.maxRetries {
+	mu      sync.Mutex
+	idle    []*pooledVM // idle VMs, the most recently used at the end
+	waiters list.List   // pending Get, each element is a chan *pooledVM
+	active  int32
+	closed  bool
+
+	maxVMs         int32
+	initial        uint
+	timeout        time.Duration
+	maxRuns        uint
+	maxAge         time.Duration
+	discardOnError bool
+	idleTimeout    time.Duration
+	done           chan struct{} // closed when the scheduler closed, stop the auto shrink
+	sink           MetricsSink
+	opt            []js.Option
+}
+
+// Get a VM, the VM is released back to the Scheduler when its run finish.
+// Get blocks until a VM is available, the ctx is done or GetTimeout * GetMaxRetries elapsed,
+// the waiters are served in FIFO order.
+func (s *schedulerImpl) Get(ctx context.Context) (js.VM, error) {
+	start := time.Now()
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil, ErrSchedulerClosed
+	}
+	if n := len(s.idle); n > 0 && s.waiters.Len() == 0 {
+		vm := s.idle[n-1]
+		s.idle = s.idle[:n-1]
+		s.mu.Unlock()
+		s.sink.ObserveWait(time.Since(start))
+		return s.renew(vm), nil
+	}
+	if s.active < s.maxVMs {
+		s.active++
+		s.mu.Unlock()
+		s.sink.ObserveWait(time.Since(start))
+		return s.newVM(), nil // create new
+	}
+	wait := make(chan *pooledVM, 1)
+	elem := s.waiters.PushBack(wait)
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	select {
+	case vm, ok := <-wait:
+		if !ok {
+			return nil, ErrSchedulerClosed
+		}
+		s.sink.ObserveWait(time.Since(start))
+		return s.renew(vm), nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		s.waiters.Remove(elem)
+		s.mu.Unlock()
+		// the VM may be handed over before removed
 		select {
-		case vm, ok := <-s.vms:
-			if !ok {
-				return nil, ErrSchedulerClosed
+		case vm, ok := <-wait:
+			if ok {
+				s.put(vm)
 			}
-			return vm, nil
 		default:
-			if s.active.Add(1) > s.maxVMs {
-				s.active.Add(-1) // rollback count
-				select {
-				case vm, ok := <-s.vms:
-					if !ok {
-						return nil, ErrSchedulerClosed
-					}
-					return vm, nil
-				case <-time.After(s.timeout):
-					continue
-				}
-			}
-			return js.NewVM(s.opt...), nil // create new
 		}
+		s.sink.ObserveWait(time.Since(start))
+		return nil, fmt.Errorf("could not get VM: %w", ctx.Err())
 	}
+}
 
-	return nil, fmt.Errorf("could not get VM in %v", time.Duration(s.maxRetries)*s.timeout)
+func (s *schedulerImpl) get() (js.VM, error) { return s.Get(context.Background()) }
+
+func (s *schedulerImpl) newVM() *pooledVM {
+	s.sink.VMCreated()
+	return &pooledVM{VM: js.NewVM(s.opt...), s: s, created: time.Now()}
 }
 
-func (s *schedulerImpl) release(vm js.VM) {
-	if s.closed.Load() {
-		return
+// renew replaces the idle VM which exceeded the max age.
+func (s *schedulerImpl) renew(vm *pooledVM) *pooledVM {
+	if s.expired(vm) {
+		s.sink.VMDiscarded()
+		return s.newVM()
 	}
-	select {
-	case s.vms <- vm:
+	return vm
+}
+
+func (s *schedulerImpl) expired(vm *pooledVM) bool {
+	return s.maxAge > 0 && time.Since(vm.created) >= s.maxAge
+}
+
+// retire reports whether the VM should be discarded instead of reused.
+func (s *schedulerImpl) retire(vm *pooledVM, err error) bool {
+	switch {
+	case errors.Is(err, js.ErrQuotaExceeded):
+		return true
+	case err != nil && s.discardOnError:
+		return true
+	case s.maxRuns > 0 && vm.runs >= s.maxRuns:
+		return true
 	default:
+		return s.expired(vm)
+	}
+}
+
+func (s *schedulerImpl) release(vm *pooledVM, err error) {
+	vm.runs++
+	if s.retire(vm, err) {
+		s.discard()
+		return
+	}
+	s.put(vm)
+}
+
+// put hands the VM over to the first waiter, or back to the idle VMs.
+func (s *schedulerImpl) put(vm *pooledVM) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		s.active--
+		s.sink.VMDiscarded()
+		return
+	}
+	if elem := s.waiters.Front(); elem != nil {
+		s.waiters.Remove(elem)
+		elem.Value.(chan *pooledVM) <- vm
+		return
+	}
+	vm.idleSince = time.Now()
+	s.idle = append(s.idle, vm)
+}
+
+// discard drops a VM, creates a new one if there are waiters
+// or to keep the initial VMs warm.
+func (s *schedulerImpl) discard() {
+	s.sink.VMDiscarded()
+	s.mu.Lock()
+	s.active--
+	replace := !s.closed && (s.waiters.Len() > 0 || s.active < int32(s.initial))
+	if replace {
+		s.active++
+	}
+	s.mu.Unlock()
+	if replace {
+		s.put(s.newVM())
 	}
 }
 
 func (s *schedulerImpl) Metrics() Metrics {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	m := Metrics{
 		Max:  int(s.maxVMs),
-		Idle: len(s.vms),
+		Idle: len(s.idle),
 	}
-	m.Remaining = m.Max - int(s.active.Load())
+	m.Remaining = m.Max - int(s.active)
 	return m
 }
 
 func (s *schedulerImpl) Shrink() {
-	if len(s.vms) == 0 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := max(len(s.idle)-int(s.initial), 0)
+	if n == 0 {
 		return
 	}
+	// drop the least recently used
+	clear(s.idle[:n])
+	s.idle = append(s.idle[:0], s.idle[n:]...)
+	s.active -= int32(n)
+	for range n {
+		s.sink.VMDiscarded()
+	}
+}
 
-	for range max(len(s.vms)-int(s.initial), 0) {
+// autoShrink evicts the idle VMs periodically until the scheduler closed.
+func (s *schedulerImpl) autoShrink(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
 		select {
-		case _ = <-s.vms:
-			s.active.Add(-1)
-		default:
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.evict()
 		}
 	}
 }
 
+// evict drops the VMs which have been idle longer than the idle timeout,
+// keeps at least the initial VMs.
+func (s *schedulerImpl) evict() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	limit := max(len(s.idle)-int(s.initial), 0)
+	for n < limit && time.Since(s.idle[n].idleSince) >= s.idleTimeout {
+		n++
+	}
+	if n == 0 {
+		return
+	}
+	clear(s.idle[:n])
+	s.idle = append(s.idle[:0], s.idle[n:]...)
+	s.active -= int32(n)
+	for range n {
+		s.sink.VMDiscarded()
+	}
+}
+
 func (s *schedulerImpl) Close() error {
-	if s.closed.Swap(true) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
 		return ErrSchedulerClosed
 	}
-	close(s.vms)
-	// clear buffered
-	for range s.vms {
+	s.closed = true
+	if s.done != nil {
+		close(s.done)
+	}
+	for elem := s.waiters.Front(); elem != nil; elem = elem.Next() {
+		close(elem.Value.(chan *pooledVM))
 	}
+	s.waiters.Init()
+	s.active -= int32(len(s.idle))
+	for range s.idle {
+		s.sink.VMDiscarded()
+	}
+	s.idle = nil
 	return nil
 }
 
+// pooledVM the js.VM managed by the scheduler,
+// release the VM back to the scheduler when run finish.
+type pooledVM struct {
+	js.VM
+	s         *schedulerImpl
+	created   time.Time
+	idleSince time.Time
+	runs      uint
+}
+
+func (vm *pooledVM) RunModule(ctx context.Context, module sobek.CyclicModuleRecord, args ...any) (sobek.Value, error) {
+	ret, err := vm.VM.RunModule(ctx, module, args...)
+	vm.s.release(vm, err)
+	return ret, err
+}
+
+func (vm *pooledVM) RunString(ctx context.Context, str string) (sobek.Value, error) {
+	ret, err := vm.VM.RunString(ctx, str)
+	vm.s.release(vm, err)
+	return ret, err
+}
+
+func (vm *pooledVM) RunProgram(ctx context.Context, program *sobek.Program) (sobek.Value, error) {
+	ret, err := vm.VM.RunProgram(ctx, program)
+	vm.s.release(vm, err)
+	return ret, err
+}
+
+func (vm *pooledVM) Run(ctx context.Context, task func() error) error {
+	err := vm.VM.Run(ctx, task)
+	vm.s.release(vm, err)
+	return err
+}
+
 func (s *schedulerImpl) String() string {
 	text, _ := s.MarshalText()
 	return string(text)
@@ -2,6 +2,7 @@ package ski
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"testing"
 	"time"
@@ -218,4 +219,241 @@ func TestScheduler(t *testing.T) {
 		assert.Equal(t, 3, metrics.Idle)
 		assert.Equal(t, 2, metrics.Remaining)
 	})
+
+	t.Run("discard quota exceeded", func(t *testing.T) {
+		s := NewScheduler(SchedulerOptions{
+			InitialVMs: 1,
+			MaxVMs:     2,
+			VMOptions:  []js.Option{js.WithMaxRunTime(50 * time.Millisecond)},
+		})
+		defer s.Close()
+
+		vm, err := s.get()
+		require.NoError(t, err)
+
+		_, err = vm.RunString(context.Background(), "{while(true){}}")
+		assert.ErrorIs(t, err, js.ErrQuotaExceeded)
+
+		metrics := s.Metrics()
+		assert.Equal(t, 1, metrics.Idle)
+		assert.Equal(t, 1, metrics.Remaining)
+
+		vm, err = s.get()
+		require.NoError(t, err)
+		_, err = vm.RunString(context.Background(), "1 + 1")
+		assert.NoError(t, err)
+
+		metrics = s.Metrics()
+		assert.Equal(t, 1, metrics.Idle)
+		assert.Equal(t, 1, metrics.Remaining)
+	})
+
+	t.Run("max runs per VM", func(t *testing.T) {
+		s := NewScheduler(SchedulerOptions{
+			MaxVMs:       1,
+			MaxRunsPerVM: 2,
+		})
+		defer s.Close()
+
+		run := func() js.VM {
+			vm, err := s.get()
+			require.NoError(t, err)
+			_, err = vm.RunString(context.Background(), "globalThis.count = (globalThis.count ?? 0) + 1")
+			require.NoError(t, err)
+			return vm
+		}
+
+		vm1 := run()
+		vm2 := run()
+		assert.Same(t, vm1, vm2)
+
+		metrics := s.Metrics()
+		assert.Equal(t, 0, metrics.Idle)
+		assert.Equal(t, 1, metrics.Remaining)
+
+		vm3 := run()
+		assert.NotSame(t, vm1, vm3)
+		assert.Equal(t, int64(1), vm3.Runtime().Get("count").ToInteger())
+	})
+
+	t.Run("max VM age", func(t *testing.T) {
+		s := NewScheduler(SchedulerOptions{
+			InitialVMs: 1,
+			MaxVMs:     1,
+			MaxVMAge:   50 * time.Millisecond,
+		})
+		defer s.Close()
+
+		vm1, err := s.get()
+		require.NoError(t, err)
+		require.NoError(t, vm1.Run(context.Background(), func() error { return nil }))
+
+		time.Sleep(60 * time.Millisecond)
+
+		vm2, err := s.get()
+		require.NoError(t, err)
+		assert.NotSame(t, vm1, vm2)
+		require.NoError(t, vm2.Run(context.Background(), func() error { return nil }))
+
+		metrics := s.Metrics()
+		assert.Equal(t, 1, metrics.Idle)
+		assert.Equal(t, 0, metrics.Remaining)
+	})
+
+	t.Run("discard on error", func(t *testing.T) {
+		s := NewScheduler(SchedulerOptions{
+			MaxVMs:         2,
+			DiscardOnError: true,
+		})
+		defer s.Close()
+
+		vm1, err := s.get()
+		require.NoError(t, err)
+		err = vm1.Run(context.Background(), func() error { panic("something wrong") })
+		assert.Error(t, err)
+
+		metrics := s.Metrics()
+		assert.Equal(t, 0, metrics.Idle)
+		assert.Equal(t, 2, metrics.Remaining)
+
+		vm2, err := s.get()
+		require.NoError(t, err)
+		assert.NotSame(t, vm1, vm2)
+		_, err = vm2.RunString(context.Background(), "undefined.method()")
+		assert.Error(t, err)
+
+		metrics = s.Metrics()
+		assert.Equal(t, 0, metrics.Idle)
+		assert.Equal(t, 2, metrics.Remaining)
+	})
+
+	t.Run("get context", func(t *testing.T) {
+		s := NewScheduler(SchedulerOptions{
+			MaxVMs:     1,
+			GetTimeout: time.Second,
+		})
+		defer s.Close()
+
+		vm, err := s.Get(context.Background())
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err = s.Get(ctx)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+
+		ctx, cancel = context.WithCancel(context.Background())
+		cancel()
+		_, err = s.Get(ctx)
+		assert.ErrorIs(t, err, context.Canceled)
+
+		require.NoError(t, vm.Run(context.Background(), func() error { return nil }))
+		metrics := s.Metrics()
+		assert.Equal(t, 1, metrics.Idle)
+		assert.Equal(t, 0, metrics.Remaining)
+	})
+
+	t.Run("get fifo", func(t *testing.T) {
+		s := NewScheduler(SchedulerOptions{
+			MaxVMs:     1,
+			GetTimeout: time.Second,
+		})
+		defer s.Close()
+
+		vm, err := s.Get(context.Background())
+		require.NoError(t, err)
+
+		var (
+			mu    sync.Mutex
+			order []int
+			wg    sync.WaitGroup
+		)
+		for i := range 3 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				vm, err := s.Get(context.Background())
+				if !assert.NoError(t, err) {
+					return
+				}
+				mu.Lock()
+				order = append(order, i)
+				mu.Unlock()
+				_ = vm.Run(context.Background(), func() error { return nil })
+			}()
+			// make sure the waiters are queued in order
+			time.Sleep(20 * time.Millisecond)
+		}
+
+		require.NoError(t, vm.Run(context.Background(), func() error { return nil }))
+		wg.Wait()
+		assert.Equal(t, []int{0, 1, 2}, order)
+	})
+
+	t.Run("discard wakes waiter", func(t *testing.T) {
+		s := NewScheduler(SchedulerOptions{
+			MaxVMs:         1,
+			GetTimeout:     time.Second,
+			DiscardOnError: true,
+		})
+		defer s.Close()
+
+		vm, err := s.Get(context.Background())
+		require.NoError(t, err)
+
+		time.AfterFunc(50*time.Millisecond, func() {
+			_ = vm.Run(context.Background(), func() error { return errors.New("failed") })
+		})
+
+		vm2, err := s.Get(context.Background())
+		require.NoError(t, err)
+		assert.NotSame(t, vm, vm2)
+		require.NoError(t, vm2.Run(context.Background(), func() error { return nil }))
+	})
+
+	t.Run("idle timeout", func(t *testing.T) {
+		s := NewScheduler(SchedulerOptions{
+			InitialVMs:     1,
+			MaxVMs:         3,
+			IdleTimeout:    50 * time.Millisecond,
+			ShrinkInterval: 10 * time.Millisecond,
+		})
+		defer s.Close()
+
+		vms := make([]js.VM, 3)
+		for i := range vms {
+			vm, err := s.Get(context.Background())
+			require.NoError(t, err)
+			vms[i] = vm
+		}
+		for _, vm := range vms {
+			require.NoError(t, vm.Run(context.Background(), func() error { return nil }))
+		}
+
+		metrics := s.Metrics()
+		assert.Equal(t, 3, metrics.Idle)
+		assert.Equal(t, 0, metrics.Remaining)
+
+		assert.Eventually(t, func() bool {
+			metrics := s.Metrics()
+			return metrics.Idle == 1 && metrics.Remaining == 2
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("idle timeout stop on close", func(t *testing.T) {
+		s := NewScheduler(SchedulerOptions{
+			MaxVMs:      1,
+			IdleTimeout: 10 * time.Millisecond,
+		})
+		impl := s.(*schedulerImpl)
+		require.NoError(t, s.Close())
+
+		select {
+		case <-impl.done:
+		default:
+			t.Fatal("auto shrink should be stopped")
+		}
+	})
 }
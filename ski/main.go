@@ -2,23 +2,33 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log/slog"
+	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/grafana/sobek"
 	"github.com/shiroyk/ski/js"
+	"github.com/shiroyk/ski/modules"
 
 	_ "github.com/shiroyk/ski/modules/buffer"
+	_ "github.com/shiroyk/ski/modules/channel"
+	_ "github.com/shiroyk/ski/modules/clone"
 	_ "github.com/shiroyk/ski/modules/encoding"
 	_ "github.com/shiroyk/ski/modules/fetch"
+	_ "github.com/shiroyk/ski/modules/http"
 	_ "github.com/shiroyk/ski/modules/signal"
 	_ "github.com/shiroyk/ski/modules/stream"
 	_ "github.com/shiroyk/ski/modules/timers"
 	_ "github.com/shiroyk/ski/modules/url"
+	_ "github.com/shiroyk/ski/modules/worker"
 
 	_ "github.com/shiroyk/ski/modules/cache"
 	_ "github.com/shiroyk/ski/modules/crypto"
@@ -27,35 +37,29 @@ import (
 )
 
 var (
-	timeoutFlag = flag.Duration("t", 0, "run timeout")
-	outputFlag  = flag.String("o", "", "write to file instead of stdout")
-	versionFlag = flag.Bool("v", false, "output version")
-	logger      = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	timeoutFlag   = flag.Duration("t", 0, "run timeout")
+	outputFlag    = flag.String("o", "", "write to file instead of stdout")
+	versionFlag   = flag.Bool("v", false, "output version")
+	importMapFlag = flag.String("import-map", "", "load the import map from the file")
+	cacheDirFlag  = flag.String("cache-dir", "", "remote modules cache directory (default the user cache directory)")
+	lockFlag      = flag.String("lock", "", "verify the remote modules with the lockfile")
+	offlineFlag   = flag.Bool("offline", false, "load the remote modules only from the cache")
+	reloadFlag    = flag.Bool("reload", false, "download the remote modules again and update the lockfile")
+	watchFlag     = flag.Bool("watch", false, "restart the script when the modules changed")
+	logger        = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
 )
 
-func run() (err error) {
-	var bytes []byte
-	path := flag.Arg(0)
-	if path == "-" {
-		bytes, err = io.ReadAll(os.Stdin)
-		if err != nil {
-			return fmt.Errorf("read stdin: %w", err)
-		}
-	} else {
-		bytes, err = os.ReadFile(path) //nolint:gosec
-		if err != nil {
-			return fmt.Errorf("read script file: %w", err)
-		}
-	}
+// watchInterval the interval of polling the changed modules.
+const watchInterval = 500 * time.Millisecond
 
-	ctx := context.Background()
+func run(ctx context.Context) (err error) {
 	if timeoutFlag != nil && *timeoutFlag > 0 {
 		var cancel context.CancelFunc
 		ctx, cancel = context.WithTimeout(ctx, *timeoutFlag)
 		defer cancel()
 	}
 
-	module, err := js.CompileModule("js", string(bytes))
+	module, err := entry()
 	if err != nil {
 		return err
 	}
@@ -81,7 +85,145 @@ func run() (err error) {
 	return os.WriteFile(*outputFlag, []byte(ret.String()), 0o600)
 }
 
+// entry returns the module of the script, the script file is loaded by the loader,
+// so it is transformed and watched like the imported modules.
+func entry() (sobek.CyclicModuleRecord, error) {
+	path := flag.Arg(0)
+	if path == "-" {
+		bytes, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("read stdin: %w", err)
+		}
+		return js.CompileModule("js", string(bytes))
+	}
+
+	// the loader resolves the relative path against the working directory
+	if filepath.IsAbs(path) {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		if path, err = filepath.Rel(wd, path); err != nil {
+			return nil, err
+		}
+	}
+	specifier := filepath.ToSlash(filepath.Clean(path))
+	if !strings.HasPrefix(specifier, "../") {
+		specifier = "./" + specifier
+	}
+
+	mod, err := js.Loader().ResolveModule(nil, specifier)
+	if err != nil {
+		return nil, fmt.Errorf("load script file: %w", err)
+	}
+	module, ok := mod.(sobek.CyclicModuleRecord)
+	if !ok {
+		return nil, fmt.Errorf("invalid script file %s", flag.Arg(0))
+	}
+	return module, nil
+}
+
+// watch runs the script, and restarts the run when the modules changed.
+func watch() error {
+	if flag.Arg(0) == "-" {
+		return errors.New("cannot watch the script from stdin")
+	}
+
+	changed := make(chan string, 1)
+	ml, err := loader(modules.WithWatch(context.Background(), watchInterval, func(specifier string) {
+		select {
+		case changed <- specifier:
+		default:
+		}
+	}))
+	if err != nil {
+		return err
+	}
+	js.SetLoader(ml)
+
+	for {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() { done <- run(ctx) }()
+
+		var specifier string
+		select {
+		case specifier = <-changed:
+			cancel()
+			<-done
+		case err := <-done:
+			if err != nil {
+				logger.Error(err.Error())
+			}
+			specifier = <-changed
+			cancel()
+		}
+		logger.Info("restarting", slog.String("changed", specifier))
+	}
+}
+
+// loader returns the modules.Loader with the remote modules cache and the import map.
+func loader(opts ...modules.Option) (modules.Loader, error) {
+	mode := modules.CacheDefault
+	switch {
+	case *offlineFlag && *reloadFlag:
+		return nil, errors.New("the offline and reload flags cannot be used together")
+	case *offlineFlag:
+		mode = modules.CacheOffline
+	case *reloadFlag:
+		mode = modules.CacheReload
+	}
+
+	dir := *cacheDirFlag
+	if dir == "" {
+		cache, err := os.UserCacheDir()
+		if err != nil {
+			return nil, err
+		}
+		dir = filepath.Join(cache, "ski", "remote")
+	}
+	fl, err := modules.CacheFileLoader(modules.DefaultFileLoader(http.DefaultClient.Do), dir, *lockFlag, mode)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, modules.WithFileLoader(fl))
+
+	if *importMapFlag != "" {
+		data, err := os.ReadFile(*importMapFlag) //nolint:gosec
+		if err != nil {
+			return nil, fmt.Errorf("read import map: %w", err)
+		}
+		im, err := modules.ParseImportMap(data, &url.URL{Scheme: "file", Path: filepath.Dir(*importMapFlag)})
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, modules.WithImportMap(im))
+	}
+	return modules.NewLoader(opts...), nil
+}
+
+// types writes the TypeScript declarations of the modules to the directory.
+func types(args []string) error {
+	fs := flag.NewFlagSet("types", flag.ExitOnError)
+	dir := fs.String("o", ".", "output directory")
+	_ = fs.Parse(args)
+
+	if err := os.MkdirAll(*dir, 0o755); err != nil {
+		return err
+	}
+	for name, content := range modules.Describe(js.NewVM().Runtime()) {
+		if err := os.WriteFile(filepath.Join(*dir, name), []byte(content), 0o644); err != nil { //nolint:gosec
+			return err
+		}
+	}
+	return nil
+}
+
 func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage:\n  ski [flags] <script|->\n  ski types [-o dir]\n\nFlags:\n")
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
 	if *versionFlag {
@@ -90,7 +232,30 @@ func main() {
 		return
 	}
 
-	if err := run(); err != nil {
+	if flag.Arg(0) == "types" {
+		if err := types(flag.Args()[1:]); err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *watchFlag {
+		if err := watch(); err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	ml, err := loader()
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+	js.SetLoader(ml)
+
+	if err := run(context.Background()); err != nil {
 		logger.Error(err.Error())
 		os.Exit(1)
 	}